@@ -0,0 +1,50 @@
+// Package audit keeps a log of security-relevant actions such as logins,
+// password changes and admin changes to accounts. Entries are written in the
+// background so a slow database does not hold up the request.
+package audit
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/JongSinister/WTFiber/config"
+	"github.com/JongSinister/WTFiber/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const Collection = "audit_logs"
+
+// Actions
+const (
+	Login          = "auth.login"
+	PasswordChange = "auth.password.change"
+	PasswordReset  = "auth.password.reset"
+	ProfileUpdate  = "auth.profile.update"
+	AccountDelete  = "auth.account.delete"
+	UserRoleChange = "user.role.change"
+	UserDisable    = "user.disable"
+	UserEnable     = "user.enable"
+	UserDelete     = "user.delete"
+	ExportRequest  = "export.request"
+	ExportDownload = "export.download"
+
+	ImpersonationStart   = "impersonation.start"
+	ImpersonationRequest = "impersonation.request"
+)
+
+// Record stores the entry in the background and logs failures
+func Record(entry models.AuditEntry) {
+	if entry.CreatedAt == 0 {
+		entry.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if _, err := config.DB.Collection(Collection).InsertOne(ctx, entry); err != nil {
+			log.Printf("Error recording audit entry %s: %v", entry.Action, err)
+		}
+	}()
+}
@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JongSinister/WTFiber/config"
+	"github.com/JongSinister/WTFiber/models"
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// Issuer is the "iss" claim of every token (JWT_ISSUER)
+func Issuer() string {
+	return config.GetEnv("JWT_ISSUER", "wtfiber")
+}
+
+// Audience is the "aud" claim of access tokens (JWT_AUDIENCE)
+func Audience() string {
+	return config.GetEnv("JWT_AUDIENCE", "wtfiber-api")
+}
+
+// Sign adds iss/iat/exp to the claims and signs them with the active key.
+// The audience must be set by the caller so tokens cannot be used for a
+// purpose they were not issued for.
+func Sign(claims jwt.MapClaims, ttl time.Duration) (string, error) {
+	key, err := signingKey()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims["iss"] = Issuer()
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(ttl).Unix()
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Alg), claims)
+	token.Header["kid"] = key.Kid
+	return token.SignedString(key.private)
+}
+
+// Parse verifies a token signed by Sign and returns its claims. The
+// algorithm must match the key named by the kid header, and iss, aud, exp
+// and sub are all required.
+func Parse(tokenString, audience string) (jwt.MapClaims, error) {
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{AlgRS256, AlgEdDSA}))
+	token, err := parser.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := verificationKey(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown key %q", kid)
+		}
+		if token.Method.Alg() != key.Alg {
+			return nil, fmt.Errorf("algorithm %s does not match key %s", token.Method.Alg(), kid)
+		}
+		return key.public, nil
+	})
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	now := time.Now().Unix()
+	if !claims.VerifyExpiresAt(now, true) ||
+		!claims.VerifyIssuer(Issuer(), true) ||
+		!claims.VerifyAudience(audience, true) {
+		return nil, ErrInvalidToken
+	}
+	if sub, _ := claims["sub"].(string); sub == "" {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+// IssueAccessToken signs a short-lived access token for a session of the user
+func IssueAccessToken(user *models.User, session *models.Session) (string, error) {
+	return Sign(withOrganization(jwt.MapClaims{
+		"sub":   user.ID.Hex(),
+		"aud":   Audience(),
+		"jti":   primitive.NewObjectID().Hex(),
+		"email": user.Email,
+		"role":  user.Role,
+		"sid":   session.ID.Hex(),
+		"amr":   session.AMR,
+	}, user), config.AccessTokenTTL())
+}
+
+// IssueImpersonationToken issues an access token that acts as the user on
+// behalf of an admin. The "act" claim names the admin and the session they
+// are logged in with; the token has no session or refresh token of its own.
+func IssueImpersonationToken(user *models.User, actorID, actorSession string, amr interface{}) (string, error) {
+	return Sign(withOrganization(jwt.MapClaims{
+		"sub":   user.ID.Hex(),
+		"aud":   Audience(),
+		"jti":   primitive.NewObjectID().Hex(),
+		"email": user.Email,
+		"role":  user.Role,
+		"amr":   amr,
+		"act":   map[string]interface{}{"sub": actorID, "sid": actorSession},
+	}, user), config.ImpersonationTTL())
+}
+
+// withOrganization adds the "org" claim for users who belong to an organization
+func withOrganization(claims jwt.MapClaims, user *models.User) jwt.MapClaims {
+	if !user.Organization.IsZero() {
+		claims["org"] = user.Organization.Hex()
+	}
+	return claims
+}
+
+// Actor returns the admin and session behind an impersonation token, or
+// ok false for an ordinary token
+func Actor(claims jwt.MapClaims) (actorID, actorSession string, ok bool) {
+	act, ok := claims["act"].(map[string]interface{})
+	if !ok {
+		return "", "", false
+	}
+	actorID, _ = act["sub"].(string)
+	actorSession, _ = act["sid"].(string)
+	return actorID, actorSession, actorID != ""
+}
+
+// ParseAccessToken verifies an access token
+func ParseAccessToken(tokenString string) (jwt.MapClaims, error) {
+	return Parse(tokenString, Audience())
+}
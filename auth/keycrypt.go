@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+
+	"github.com/JongSinister/WTFiber/config"
+	"golang.org/x/crypto/hkdf"
+)
+
+var errNoEncryptionKey = errors.New("JWT_KEY_ENCRYPTION_KEY is not set")
+
+// keyCipher returns the AES-256-GCM cipher that protects the private keys
+// at rest. Its key is derived from JWT_KEY_ENCRYPTION_KEY, which should be a
+// long random secret kept outside the database.
+func keyCipher() (cipher.AEAD, error) {
+	secret := config.GetEnv("JWT_KEY_ENCRYPTION_KEY", "")
+	if secret == "" {
+		return nil, errNoEncryptionKey
+	}
+
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, []byte(secret), nil, []byte("wtfiber signing keys")), key); err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealKey encrypts a private key PEM. The kid is authenticated with it, so
+// a ciphertext cannot be moved to another key's record.
+func sealKey(kid string, plain []byte) ([]byte, error) {
+	aead, err := keyCipher()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plain, []byte(kid)), nil
+}
+
+// openKey decrypts a private key sealed by sealKey
+func openKey(kid string, sealed []byte) ([]byte, error) {
+	aead, err := keyCipher()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("encrypted key is too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, []byte(kid))
+}
@@ -0,0 +1,357 @@
+// Package auth signs and verifies the JWTs issued by the API. Tokens are
+// signed with asymmetric keys (RS256 or EdDSA) that are stored in Mongo,
+// encrypted with JWT_KEY_ENCRYPTION_KEY, rotated on a schedule and published
+// as a JWKS so other services can verify them without sharing a secret.
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/JongSinister/WTFiber/config"
+	"github.com/JongSinister/WTFiber/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const keyCollection = "signing_keys"
+
+// Supported signing algorithms
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// loadedKey is a signing key with its PEM decoded
+type loadedKey struct {
+	models.SigningKey
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+var (
+	mu   sync.RWMutex
+	keys []*loadedKey // sorted by ActivatesAt, newest first
+)
+
+// keyReloadInterval is how often a token with an unknown kid may make the
+// keys reload, so a key created by another instance is picked up without
+// letting forged kids hammer the database
+const keyReloadInterval = 10 * time.Second
+
+var (
+	reloadMu   sync.Mutex
+	lastReload time.Time
+	reloadKeys = func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return load(ctx)
+	}
+)
+
+var plaintextWarning sync.Once
+
+// Algorithm is the algorithm new keys are generated for (JWT_ALG)
+func Algorithm() string {
+	return config.GetEnv("JWT_ALG", AlgRS256)
+}
+
+// rotationPeriod is how long a key signs new tokens (JWT_KEY_ROTATION)
+func rotationPeriod() time.Duration {
+	return config.GetDuration("JWT_KEY_ROTATION", 30*24*time.Hour)
+}
+
+// overlapPeriod is how long a retired key keeps verifying (JWT_KEY_OVERLAP).
+// It must be longer than the lifetime of any token the API signs.
+func overlapPeriod() time.Duration {
+	return config.GetDuration("JWT_KEY_OVERLAP", 48*time.Hour)
+}
+
+// prepublishPeriod is how long before activation a new key appears in the
+// JWKS, giving verifiers time to pick it up (JWT_KEY_PREPUBLISH)
+func prepublishPeriod() time.Duration {
+	return config.GetDuration("JWT_KEY_PREPUBLISH", time.Hour)
+}
+
+// StartRotation makes sure a signing key exists and keeps rotating keys in
+// the background, reloading keys created by other instances
+func StartRotation(interval time.Duration) {
+	if err := rotate(); err != nil {
+		log.Fatalf("Error preparing signing keys: %v", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := rotate(); err != nil {
+				log.Printf("Error rotating signing keys: %v", err)
+			}
+		}
+	}()
+}
+
+// rotate loads the keys and creates the next one when the current key is
+// about to retire, the algorithm changed, or there is no key at all
+func rotate() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := load(ctx); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	current, err := signingKey()
+	switch {
+	case err != nil || current.Alg != Algorithm():
+		if _, err := createKey(ctx, now); err != nil {
+			return err
+		}
+	case current.RetiresAt.Time().Sub(now) < prepublishPeriod() && !hasKeyAfter(current):
+		if _, err := createKey(ctx, current.RetiresAt.Time()); err != nil {
+			return err
+		}
+	default:
+		return nil
+	}
+
+	return load(ctx)
+}
+
+// load replaces the in-memory keys with the unexpired keys from the database
+func load(ctx context.Context) error {
+	now := primitive.NewDateTimeFromTime(time.Now())
+	opts := options.Find().SetSort(bson.M{"activatesAt": -1})
+	cursor, err := config.DB.Collection(keyCollection).Find(ctx, bson.M{"expiresAt": bson.M{"$gt": now}}, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var stored []models.SigningKey
+	if err := cursor.All(ctx, &stored); err != nil {
+		return err
+	}
+
+	SetKeys(stored)
+	for _, key := range stored {
+		if key.PrivateKey != "" {
+			encryptStoredKey(ctx, key)
+		}
+	}
+	return nil
+}
+
+// SetKeys replaces the in-memory keys with the stored keys that decode,
+// newest first
+func SetKeys(stored []models.SigningKey) {
+	loaded := make([]*loadedKey, 0, len(stored))
+	for _, key := range stored {
+		k, err := decodeKey(key)
+		if err != nil {
+			log.Printf("Skipping signing key %s: %v", key.Kid, err)
+			continue
+		}
+		loaded = append(loaded, k)
+	}
+	sort.SliceStable(loaded, func(i, j int) bool {
+		return loaded[i].ActivatesAt > loaded[j].ActivatesAt
+	})
+
+	mu.Lock()
+	keys = loaded
+	mu.Unlock()
+}
+
+// createKey generates a key that starts signing at activatesAt
+func createKey(ctx context.Context, activatesAt time.Time) (*models.SigningKey, error) {
+	alg := Algorithm()
+
+	var private crypto.Signer
+	var err error
+	switch alg {
+	case AlgRS256:
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case AlgEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported JWT_ALG %q", alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+
+	kid := make([]byte, 8)
+	if _, err := rand.Read(kid); err != nil {
+		return nil, err
+	}
+
+	retiresAt := activatesAt.Add(rotationPeriod())
+	key := &models.SigningKey{
+		Kid:         hex.EncodeToString(kid),
+		Alg:         alg,
+		CreatedAt:   primitive.NewDateTimeFromTime(time.Now()),
+		ActivatesAt: primitive.NewDateTimeFromTime(activatesAt),
+		RetiresAt:   primitive.NewDateTimeFromTime(retiresAt),
+		ExpiresAt:   primitive.NewDateTimeFromTime(retiresAt.Add(overlapPeriod())),
+	}
+
+	// Private keys are encrypted at rest; without an encryption key they are
+	// stored as plain PEM, which is only meant for local development
+	plain := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	key.EncryptedKey, err = sealKey(key.Kid, plain)
+	if errors.Is(err, errNoEncryptionKey) {
+		plaintextWarning.Do(func() {
+			log.Println("JWT_KEY_ENCRYPTION_KEY not set, signing keys are stored unencrypted")
+		})
+		key.PrivateKey = string(plain)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if _, err := config.DB.Collection(keyCollection).InsertOne(ctx, key); err != nil {
+		return nil, err
+	}
+	log.Printf("Created %s signing key %s, active from %s", alg, key.Kid, activatesAt.Format(time.RFC3339))
+	return key, nil
+}
+
+// encryptStoredKey replaces the plain PEM of a key stored before an
+// encryption key was configured with its encrypted form
+func encryptStoredKey(ctx context.Context, key models.SigningKey) {
+	sealed, err := sealKey(key.Kid, []byte(key.PrivateKey))
+	if errors.Is(err, errNoEncryptionKey) {
+		return
+	}
+	if err == nil {
+		_, err = config.DB.Collection(keyCollection).UpdateOne(ctx,
+			bson.M{"_id": key.ID},
+			bson.M{"$set": bson.M{"encryptedKey": sealed}, "$unset": bson.M{"privateKey": ""}},
+		)
+	}
+	if err != nil {
+		log.Printf("Error encrypting signing key %s: %v", key.Kid, err)
+	}
+}
+
+// decodeKey decrypts and parses the PEM of a stored key
+func decodeKey(key models.SigningKey) (*loadedKey, error) {
+	plain := []byte(key.PrivateKey)
+	if len(key.EncryptedKey) > 0 {
+		var err error
+		if plain, err = openKey(key.Kid, key.EncryptedKey); err != nil {
+			return nil, fmt.Errorf("decrypting: %w", err)
+		}
+	}
+
+	block, _ := pem.Decode(plain)
+	if block == nil {
+		return nil, fmt.Errorf("invalid PEM")
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch private := parsed.(type) {
+	case *rsa.PrivateKey:
+		if key.Alg != AlgRS256 {
+			return nil, fmt.Errorf("RSA key stored as %s", key.Alg)
+		}
+		return &loadedKey{SigningKey: key, private: private, public: &private.PublicKey}, nil
+	case ed25519.PrivateKey:
+		if key.Alg != AlgEdDSA {
+			return nil, fmt.Errorf("Ed25519 key stored as %s", key.Alg)
+		}
+		return &loadedKey{SigningKey: key, private: private, public: private.Public()}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %T", parsed)
+}
+
+// signingKey returns the newest key that is active now
+func signingKey() (*loadedKey, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	now := time.Now()
+	for _, key := range keys {
+		if !now.Before(key.ActivatesAt.Time()) && now.Before(key.RetiresAt.Time()) {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("no active signing key")
+}
+
+// verificationKey returns the unexpired key with the given kid. An unknown
+// kid may belong to a key another instance just created, so the keys are
+// reloaded once, at most every keyReloadInterval.
+func verificationKey(kid string) (*loadedKey, bool) {
+	if key, ok := findKey(kid); ok || kid == "" {
+		return key, ok
+	}
+
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	// Another request may have reloaded the keys while this one waited
+	if key, ok := findKey(kid); ok {
+		return key, true
+	}
+	if time.Since(lastReload) < keyReloadInterval {
+		return nil, false
+	}
+	lastReload = time.Now()
+
+	if err := reloadKeys(); err != nil {
+		log.Printf("Error reloading signing keys: %v", err)
+		return nil, false
+	}
+	return findKey(kid)
+}
+
+// findKey returns the loaded, unexpired key with the given kid
+func findKey(kid string) (*loadedKey, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	now := time.Now()
+	for _, key := range keys {
+		if key.Kid == kid && now.Before(key.ExpiresAt.Time()) {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// hasKeyAfter reports whether a successor of current is already scheduled
+func hasKeyAfter(current *loadedKey) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, key := range keys {
+		if key.ActivatesAt > current.ActivatesAt && key.Alg == current.Alg {
+			return true
+		}
+	}
+	return false
+}
@@ -4,8 +4,17 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/JongSinister/WTFiber/auth"
 	"github.com/JongSinister/WTFiber/config"
+	"github.com/JongSinister/WTFiber/controllers"
+	"github.com/JongSinister/WTFiber/mailer"
+	"github.com/JongSinister/WTFiber/oidc"
+	"github.com/JongSinister/WTFiber/payment"
+	"github.com/JongSinister/WTFiber/pdf"
+	"github.com/JongSinister/WTFiber/rbac"
+	"github.com/JongSinister/WTFiber/revocation"
 	"github.com/JongSinister/WTFiber/routes"
 	"github.com/gofiber/fiber/v2"
 	"github.com/joho/godotenv"
@@ -24,7 +33,14 @@ func main() {
 		log.Fatalf("Error loading .env file: %v", err)
 	}
 
-	app := fiber.New()
+	// Behind a reverse proxy, take the client IP from its header, but only
+	// on connections from the proxy itself
+	app := fiber.New(fiber.Config{
+		ProxyHeader:             config.ProxyHeader(),
+		EnableTrustedProxyCheck: len(config.TrustedProxies()) > 0,
+		TrustedProxies:          config.TrustedProxies(),
+		EnableIPValidation:      true,
+	})
 
 	app.Get("/hello", func(c *fiber.Ctx) error {
 		return c.SendString("Hello World")
@@ -37,6 +53,28 @@ func main() {
 	// Initialize the database
 	config.InitDB()
 
+	// Prepare the JWT signing keys and rotate them on schedule
+	auth.StartRotation(5 * time.Minute)
+
+	// Keep the revoked token cache in sync with the database
+	revocation.StartSync(30 * time.Second)
+	rbac.StartSync(30 * time.Second)
+
+	// Set up outgoing email
+	mailer.Setup()
+
+	// Load the fonts invoices are set in
+	pdf.Setup()
+
+	// Configure external identity providers
+	oidc.Setup()
+
+	// Delete the files of expired data exports
+	controllers.StartDataExportCleanup(time.Hour)
+
+	// Register payment providers
+	payment.Register(payment.NewMockProvider(os.Getenv("PAYMENT_WEBHOOK_SECRET")))
+
 	// set up routes
 	routes.Setup(app)
 
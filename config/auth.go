@@ -0,0 +1,116 @@
+package config
+
+import (
+	"strings"
+	"time"
+)
+
+// AccessTokenTTL is the lifetime of the JWT access token (JWT_ACCESS_TTL)
+func AccessTokenTTL() time.Duration {
+	return GetDuration("JWT_ACCESS_TTL", 15*time.Minute)
+}
+
+// RefreshTokenTTL is the lifetime of an opaque refresh token (JWT_REFRESH_TTL)
+func RefreshTokenTTL() time.Duration {
+	return GetDuration("JWT_REFRESH_TTL", 30*24*time.Hour)
+}
+
+// PasswordResetTTL is how long a password reset link stays valid (PASSWORD_RESET_TTL)
+func PasswordResetTTL() time.Duration {
+	return GetDuration("PASSWORD_RESET_TTL", time.Hour)
+}
+
+// PasswordResetMaxRequests is the number of reset emails one address can ask
+// for within LOGIN_LOCKOUT before further requests are refused
+// (PASSWORD_RESET_MAX_REQUESTS)
+func PasswordResetMaxRequests() int {
+	return GetInt("PASSWORD_RESET_MAX_REQUESTS", 3)
+}
+
+// PasswordResetIPMaxRequests is the number of reset emails one client IP can
+// ask for within LOGIN_LOCKOUT (PASSWORD_RESET_IP_MAX_REQUESTS)
+func PasswordResetIPMaxRequests() int {
+	return GetInt("PASSWORD_RESET_IP_MAX_REQUESTS", 20)
+}
+
+// EmailVerificationTTL is how long an email verification link stays valid
+// (EMAIL_VERIFICATION_TTL). Keep it below JWT_KEY_OVERLAP.
+func EmailVerificationTTL() time.Duration {
+	return GetDuration("EMAIL_VERIFICATION_TTL", 24*time.Hour)
+}
+
+// RequireVerifiedEmail blocks booking until the user's email is verified
+// (REQUIRE_VERIFIED_EMAIL=true)
+func RequireVerifiedEmail() bool {
+	return GetEnv("REQUIRE_VERIFIED_EMAIL", "false") == "true"
+}
+
+// MFARequiredRoles lists the roles that may only use admin routes after
+// passing a second factor (MFA_REQUIRED_ROLES, comma separated)
+func MFARequiredRoles() []string {
+	return strings.Split(GetEnv("MFA_REQUIRED_ROLES", "admin"), ",")
+}
+
+// ImpersonationTTL is the lifetime of a token an admin uses to act as a user
+// (IMPERSONATION_TTL)
+func ImpersonationTTL() time.Duration {
+	return GetDuration("IMPERSONATION_TTL", 15*time.Minute)
+}
+
+// LoginMaxAttempts is the number of failed logins to one account from one
+// client IP after which that client is locked out of it (LOGIN_MAX_ATTEMPTS)
+func LoginMaxAttempts() int {
+	return GetInt("LOGIN_MAX_ATTEMPTS", 5)
+}
+
+// LoginAccountMaxAttempts is the number of failed logins to an account, from
+// any IP, after which the account is locked (LOGIN_ACCOUNT_MAX_ATTEMPTS). It
+// stops guessing spread over many IPs; keep it well above LOGIN_MAX_ATTEMPTS
+// so one client cannot lock everybody else out.
+func LoginAccountMaxAttempts() int {
+	return GetInt("LOGIN_ACCOUNT_MAX_ATTEMPTS", 50)
+}
+
+// LoginIPMaxAttempts is the number of failed logins after which a client IP
+// is locked (LOGIN_IP_MAX_ATTEMPTS)
+func LoginIPMaxAttempts() int {
+	return GetInt("LOGIN_IP_MAX_ATTEMPTS", 50)
+}
+
+// LoginBackoff is the delay after the first failed login; it doubles with
+// every further failure (LOGIN_BACKOFF)
+func LoginBackoff() time.Duration {
+	return GetDuration("LOGIN_BACKOFF", time.Second)
+}
+
+// LoginLockout is how long a locked account or IP stays locked, and caps the
+// backoff (LOGIN_LOCKOUT)
+func LoginLockout() time.Duration {
+	return GetDuration("LOGIN_LOCKOUT", 15*time.Minute)
+}
+
+// ReauthWindow is how recently a user without a password must have signed
+// in to make changes that otherwise need the password (REAUTH_WINDOW)
+func ReauthWindow() time.Duration {
+	return GetDuration("REAUTH_WINDOW", 10*time.Minute)
+}
+
+// InviteTTL is how long an invitation stays valid unless the admin picks
+// another expiry (INVITE_TTL)
+func InviteTTL() time.Duration {
+	return GetDuration("INVITE_TTL", 7*24*time.Hour)
+}
+
+// DataExportTTL is how long a personal data export can be downloaded before
+// it is deleted (DATA_EXPORT_TTL)
+func DataExportTTL() time.Duration {
+	return GetDuration("DATA_EXPORT_TTL", 48*time.Hour)
+}
+
+// TokenSources lists where Protect looks for the access token, in order
+// (TOKEN_SOURCES, comma separated: header, cookie, query). The query string
+// is only read on GET requests to download routes, for links opened where
+// headers cannot be sent.
+func TokenSources() []string {
+	return strings.Split(GetEnv("TOKEN_SOURCES", "header,cookie"), ",")
+}
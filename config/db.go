@@ -50,4 +50,5 @@ func DisconnectDB() {
 
 func InitDB() {
 	DB = clt.Database("test")
+	EnsureIndexes()
 }
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// GetEnv returns the environment variable or the fallback when it is unset
+func GetEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// GetInt reads an integer from the environment
+func GetInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// GetDuration reads a duration such as "15m" or "720h" from the environment
+func GetDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// AppURL is the public base URL used in links sent to users (APP_URL)
+func AppURL() string {
+	return GetEnv("APP_URL", "http://localhost:"+GetEnv("PORT", "3000"))
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// indexes lists the indexes each collection needs
+var indexes = map[string][]mongo.IndexModel{
+	"refresh_tokens": {
+		{Keys: bson.D{{Key: "hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "family", Value: 1}}},
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	},
+	"password_reset_tokens": {
+		{Keys: bson.D{{Key: "hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	},
+	"verification_emails": {
+		{Keys: bson.D{{Key: "user", Value: 1}, {Key: "sentAt", Value: -1}}},
+		{Keys: bson.D{{Key: "sentAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(24 * 60 * 60)},
+	},
+	"login_attempts": {
+		{Keys: bson.D{{Key: "key", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "organization", Value: 1}, {Key: "lastFailureAt", Value: -1}}},
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	},
+	"invites": {
+		{Keys: bson.D{{Key: "hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	},
+	"data_exports": {
+		{Keys: bson.D{{Key: "user", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	},
+	"audit_logs": {
+		{Keys: bson.D{{Key: "subject", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{Keys: bson.D{{Key: "actor", Value: 1}, {Key: "createdAt", Value: -1}}},
+	},
+	"organizations": {
+		{Keys: bson.D{{Key: "slug", Value: 1}}, Options: options.Index().SetUnique(true)},
+	},
+	"hotels": {
+		{Keys: bson.D{{Key: "organization", Value: 1}}},
+		{Keys: bson.D{{Key: "chain", Value: 1}}},
+	},
+	"chains": {
+		{Keys: bson.D{{Key: "organization", Value: 1}, {Key: "name", Value: 1}}, Options: options.Index().SetUnique(true)},
+	},
+	"appointments": {
+		{Keys: bson.D{{Key: "organization", Value: 1}, {Key: "hotel", Value: 1}}},
+	},
+	"payments": {
+		// At most one open deposit per appointment; the check in CreatePayment
+		// alone races with a concurrent request
+		{
+			Keys: bson.D{{Key: "appointment", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+				"status": bson.M{"$in": bson.A{"pending", "paid"}},
+			}),
+		},
+	},
+	"users": {
+		{Keys: bson.D{{Key: "organization", Value: 1}}},
+	},
+	"roles": {
+		{Keys: bson.D{{Key: "name", Value: 1}}, Options: options.Index().SetUnique(true)},
+	},
+	"grants": {
+		{Keys: bson.D{{Key: "user", Value: 1}, {Key: "resource", Value: 1}}},
+	},
+	"api_keys": {
+		{Keys: bson.D{{Key: "prefix", Value: 1}}, Options: options.Index().SetUnique(true)},
+	},
+	"identities": {
+		{Keys: bson.D{{Key: "provider", Value: 1}, {Key: "subject", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user", Value: 1}}},
+	},
+	"oidc_states": {
+		{Keys: bson.D{{Key: "state", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	},
+	"sessions": {
+		{Keys: bson.D{{Key: "user", Value: 1}}},
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	},
+	"signing_keys": {
+		{Keys: bson.D{{Key: "kid", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	},
+	"revoked_tokens": {
+		{Keys: bson.D{{Key: "kind", Value: 1}, {Key: "key", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	},
+}
+
+// EnsureIndexes creates missing indexes; existing ones are left untouched
+func EnsureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for collection, models := range indexes {
+		if _, err := DB.Collection(collection).Indexes().CreateMany(ctx, models); err != nil {
+			log.Printf("Error creating indexes for %s: %v", collection, err)
+		}
+	}
+}
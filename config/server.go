@@ -0,0 +1,26 @@
+package config
+
+import "strings"
+
+// TrustedProxies lists the proxy addresses or CIDR ranges whose client IP
+// header is believed (TRUSTED_PROXIES, comma separated). Requests from
+// anywhere else are identified by their own address.
+func TrustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(GetEnv("TRUSTED_PROXIES", ""), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+// ProxyHeader is the header a trusted proxy puts the client IP in
+// (PROXY_HEADER). The proxy must overwrite it rather than append to it, or
+// clients could pick their own IP; it is ignored unless TRUSTED_PROXIES is set.
+func ProxyHeader() string {
+	if len(TrustedProxies()) == 0 {
+		return ""
+	}
+	return GetEnv("PROXY_HEADER", "X-Real-IP")
+}
@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/JongSinister/WTFiber/config"
+	"github.com/JongSinister/WTFiber/dto"
+	"github.com/JongSinister/WTFiber/models"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const apiKeyCollection = "api_keys"
+
+// @desc    Issue an API key for a service integration
+// @route   POST /api/v1/admin/api-keys
+// @access  Private (admin)
+func CreateAPIKey(c *fiber.Ctx) error {
+	// 1) Parse the request body
+	body := struct {
+		Name      string    `json:"name"`
+		Role      string    `json:"role"`
+		Scopes    []string  `json:"scopes"`
+		ExpiresAt time.Time `json:"expiresAt"`
+	}{}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request format"})
+	}
+
+	// 2) Validate the key settings
+	if body.Name == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Name is required"})
+	}
+	if body.Role == "" {
+		body.Role = "user"
+	}
+	if status, message := checkAssignableRole(c, body.Role); status != 0 {
+		return c.Status(status).JSON(fiber.Map{"error": message})
+	}
+	if len(body.Scopes) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "At least one scope is required"})
+	}
+	for _, scope := range body.Scopes {
+		if !models.ValidAPIKeyScope(scope) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unknown scope " + scope})
+		}
+	}
+	if !body.ExpiresAt.IsZero() && body.ExpiresAt.Before(time.Now()) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Expiry must be in the future"})
+	}
+
+	// 3) Generate and store the key
+	adminID, ok := currentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error parsing claims"})
+	}
+
+	key, plain, err := models.NewAPIKey(body.Name, body.Role, body.Scopes, adminID, body.ExpiresAt)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error generating API key"})
+	}
+	if scope := tenantOf(c); !scope.All {
+		key.Organization = scope.Organization
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := config.DB.Collection(apiKeyCollection).InsertOne(ctx, key)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error creating API key"})
+	}
+	key.ID = result.InsertedID.(primitive.ObjectID)
+
+	// 4) Return the plaintext key; it cannot be retrieved again
+	return c.Status(fiber.StatusCreated).JSON(dto.CreatedAPIKeyResponse{
+		APIKeyResponse: dto.NewAPIKey(key),
+		Key:            plain,
+	})
+}
+
+// @desc    List API keys
+// @route   GET /api/v1/admin/api-keys
+// @access  Private (admin)
+func GetAPIKeys(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.M{"createdAt": -1})
+	cursor, err := config.DB.Collection(apiKeyCollection).Find(ctx, scoped(c, bson.M{}), opts)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching API keys"})
+	}
+	defer cursor.Close(ctx)
+
+	var keys []models.APIKey
+	if err := cursor.All(ctx, &keys); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching API keys"})
+	}
+
+	return c.JSON(dto.List(keys, dto.NewAPIKey))
+}
+
+// @desc    Revoke an API key
+// @route   DELETE /api/v1/admin/api-keys/:id
+// @access  Private (admin)
+func RevokeAPIKey(c *fiber.Ctx) error {
+	// 1) Get the ID from the URL and convert it to an ObjectID
+	objectID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID Format"})
+	}
+
+	// 2) Mark the key revoked; Protect rejects it from the next request
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := config.DB.Collection(apiKeyCollection).UpdateOne(ctx,
+		scoped(c, bson.M{"_id": objectID, "revokedAt": bson.M{"$exists": false}}),
+		bson.M{"$set": bson.M{"revokedAt": primitive.NewDateTimeFromTime(time.Now())}},
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error revoking API key"})
+	}
+	if result.MatchedCount == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "API key not found"})
+	}
+
+	return c.JSON(fiber.Map{"message": "API key revoked successfully"})
+}
@@ -2,14 +2,17 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"math/rand"
 	"time"
 
 	"github.com/JongSinister/WTFiber/config"
+	"github.com/JongSinister/WTFiber/dto"
 	"github.com/JongSinister/WTFiber/models"
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
@@ -19,18 +22,39 @@ const appointmentCollection = "appointments"
 // @route GET /api/v1/appointments
 // @access Private
 func GetAppointments(c *fiber.Ctx) error {
-	// 1) Fetch appointments from the database
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	// 1) Without appointments:read users only see their own bookings and
+	// those at the hotels they manage
+	filter := scoped(c, bson.M{})
+	if !can(c, "appointments:read") {
+		userID, ok := currentUserID(c)
+		if !ok {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+		}
+
+		visible := bson.A{bson.M{"user": userID}}
+		if can(c, "appointments:read:managed") {
+			hotelIDs, err := managedHotelIDs(ctx, userID)
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching appointments"})
+			}
+			visible = append(visible, bson.M{"hotel": bson.M{"$in": hotelIDs}})
+		}
+		filter["$or"] = visible
+	}
+
+	// 2) Fetch appointments from the database
+
 	opts := options.Find()
-	cursor, err := config.DB.Collection(appointmentCollection).Find(ctx, bson.M{}, opts)
+	cursor, err := config.DB.Collection(appointmentCollection).Find(ctx, filter, opts)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Error fetching appointments"})
 	}
 	defer cursor.Close(ctx)
 
-	// 2) Decode results
+	// 3) Decode results
 	var appointments []models.Appointment
 	if err := cursor.All(ctx, &appointments); err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -43,8 +67,8 @@ func GetAppointments(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "No appointments found"})
 	}
 
-	// 3) Return appointments
-	return c.JSON(appointments)
+	// 4) Return appointments
+	return c.JSON(dto.List(appointments, dto.NewAppointment))
 }
 
 // @desc Get a single appointment
@@ -65,13 +89,22 @@ func GetAppointment(c *fiber.Ctx) error {
 
 	// 3) fetch appointment from database
 	appointment := models.Appointment{}
-	err = config.DB.Collection(appointmentCollection).FindOne(ctx, bson.M{"_id": objectID}).Decode(&appointment)
+	err = config.DB.Collection(appointmentCollection).FindOne(ctx, scoped(c, bson.M{"_id": objectID})).Decode(&appointment)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Appointment not found"})
 	}
+	if !canAccess(c, "appointments:read", appointment.User) {
+		allowed, err := canManageAppointment(ctx, c, "appointments:read", &appointment)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error checking permissions"})
+		}
+		if !allowed {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+		}
+	}
 
 	// 4) Return appointment
-	return c.JSON(appointment)
+	return c.JSON(dto.NewAppointment(&appointment))
 }
 
 // @desc Add appointment
@@ -92,25 +125,56 @@ func AddAppointment(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request format"})
 	}
 
-	// 3) Set the hotel ID and CreatedAt fields
+	// 3) Set the hotel, owner and CreatedAt fields
+	userID, ok := currentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Error parsing claims"})
+	}
+
 	appointment.Hotel = objectHotelID
+	appointment.User = userID
 	appointment.CreatedAt = primitive.DateTime(time.Now().UnixNano() / int64(time.Millisecond))
 	appointment.WifiPassword = generateRandomPassword()
+	appointment.Status = models.AppointmentPending
+	appointment.ConfirmedAt = 0
+	appointment.ConfirmedBy = primitive.NilObjectID
 
-	// 4) Insert the appointment into the database
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	// The hotel must exist in the caller's organization and be open on the day
+	hotel := new(models.Hotel)
+	if err := config.DB.Collection(hotelCollection).FindOne(ctx, scoped(c, bson.M{"_id": objectHotelID})).Decode(hotel); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Hotel not found"})
+	}
+	appointment.Organization = hotel.Organization
+	if hotel.IsClosedOn(appointment.ApptDate) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "The hotel is closed on that date"})
+	}
+
+	// 4) Block unverified users when the deployment requires it
+	if config.RequireVerifiedEmail() {
+		count, err := config.DB.Collection(userCollection).CountDocuments(ctx, bson.M{"_id": userID, "emailVerified": true})
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error checking email verification"})
+		}
+		if count == 0 {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Please verify your email address before booking"})
+		}
+	}
+
+	// 5) Insert the appointment into the database
 	res, err := config.DB.Collection(appointmentCollection).InsertOne(ctx, appointment)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create appointment"})
 	}
+	appointment.ID = res.InsertedID.(primitive.ObjectID)
 
-	// 5) Return the response
+	// 6) Return the response
 	return c.Status(fiber.StatusCreated).JSON(
 		fiber.Map{
 			"message":     "Appointment created successfully",
-			"appointment": res,
+			"appointment": dto.NewAppointment(appointment),
 		},
 	)
 }
@@ -132,11 +196,14 @@ func UpdateAppointment(c *fiber.Ctx) error {
 	defer cancel()
 
 	existAppointment := new(models.Appointment)
-	err = config.DB.Collection(appointmentCollection).FindOne(ctx, bson.M{"_id": objectID}).Decode(existAppointment)
+	err = config.DB.Collection(appointmentCollection).FindOne(ctx, scoped(c, bson.M{"_id": objectID})).Decode(existAppointment)
 
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Appointment not found"})
 	}
+	if !canAccess(c, "appointments:update", existAppointment.User) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+	}
 
 	// 3) Parse the request body into a map for partial updates
 	update := make(map[string]interface{})
@@ -144,13 +211,22 @@ func UpdateAppointment(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request format"})
 	}
 
+	// Owners may not hand the appointment to someone else, and nobody moves
+	// it to another hotel or organization; that is a new booking
+	if !can(c, "appointments:update") {
+		delete(update, "user")
+	}
+	for _, field := range []string{"_id", "hotel", "organization"} {
+		delete(update, field)
+	}
+
 	// 4) Prepare the update document
 	updateDoc := bson.M{
 		"$set": update,
 	}
 
 	// 5) Update the appointment document with specified fields
-	_, err = config.DB.Collection(appointmentCollection).UpdateOne(ctx, bson.M{"_id": objectID}, updateDoc)
+	_, err = config.DB.Collection(appointmentCollection).UpdateOne(ctx, scoped(c, bson.M{"_id": objectID}), updateDoc)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update appointment"})
 	}
@@ -175,7 +251,16 @@ func DeleteAppointment(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	_, err = config.DB.Collection(appointmentCollection).DeleteOne(ctx, bson.M{"_id": objectID})
+	existAppointment := new(models.Appointment)
+	err = config.DB.Collection(appointmentCollection).FindOne(ctx, scoped(c, bson.M{"_id": objectID})).Decode(existAppointment)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Appointment not found"})
+	}
+	if !canAccess(c, "appointments:delete", existAppointment.User) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	_, err = config.DB.Collection(appointmentCollection).DeleteOne(ctx, scoped(c, bson.M{"_id": objectID}))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete appointment"})
 	}
@@ -184,6 +269,69 @@ func DeleteAppointment(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"message": "Appointment deleted successfully"})
 }
 
+// @desc Confirm a pending appointment
+// @route PUT /api/v1/appointments/:id/confirm
+// @access Private (appointments:confirm, or a manager of the hotel)
+func ConfirmAppointment(c *fiber.Ctx) error {
+	// 1) Get the ID from the URL and convert it to an ObjectID
+	objectID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID Format"})
+	}
+
+	// 2) Fetch the appointment and check the caller runs its hotel
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	existAppointment := new(models.Appointment)
+	err = config.DB.Collection(appointmentCollection).FindOne(ctx, scoped(c, bson.M{"_id": objectID})).Decode(existAppointment)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Appointment not found"})
+	}
+
+	allowed, err := canManageAppointment(ctx, c, "appointments:confirm", existAppointment)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error checking permissions"})
+	}
+	if !allowed {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	// 3) Confirm it unless it already is
+	userID, _ := currentUserID(c)
+	res, err := config.DB.Collection(appointmentCollection).UpdateOne(ctx,
+		scoped(c, bson.M{"_id": objectID, "status": bson.M{"$ne": models.AppointmentConfirmed}}),
+		bson.M{"$set": bson.M{
+			"status":      models.AppointmentConfirmed,
+			"confirmedAt": primitive.NewDateTimeFromTime(time.Now()),
+			"confirmedBy": userID,
+		}},
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to confirm appointment"})
+	}
+	if res.ModifiedCount == 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Appointment is already confirmed"})
+	}
+
+	// 4) Return the response
+	return c.JSON(fiber.Map{"message": "Appointment confirmed successfully"})
+}
+
+// canManageAppointment reports whether the principal may apply perm to the
+// appointment through the hotel it is at
+func canManageAppointment(ctx context.Context, c *fiber.Ctx, perm string, appointment *models.Appointment) (bool, error) {
+	hotel := new(models.Hotel)
+	err := config.DB.Collection(hotelCollection).FindOne(ctx, scoped(c, bson.M{"_id": appointment.Hotel})).Decode(hotel)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return can(c, perm), nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return canManageHotel(ctx, c, perm, hotel)
+}
+
 // Create Random Wifi Password
 func generateRandomPassword() string {
 	// Random length from 6 to 8
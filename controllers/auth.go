@@ -2,11 +2,19 @@ package controllers
 
 import (
 	"context"
-	"os"
+	"log"
+	"math"
+	"strconv"
 	"time"
 
+	"github.com/JongSinister/WTFiber/audit"
+	"github.com/JongSinister/WTFiber/auth"
 	"github.com/JongSinister/WTFiber/config"
+	"github.com/JongSinister/WTFiber/dto"
+	"github.com/JongSinister/WTFiber/middleware"
 	"github.com/JongSinister/WTFiber/models"
+	"github.com/JongSinister/WTFiber/password"
+	"github.com/JongSinister/WTFiber/revocation"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v4"
 	"go.mongodb.org/mongo-driver/bson"
@@ -14,22 +22,30 @@ import (
 )
 
 const userCollection = "users"
+const refreshTokenCollection = "refresh_tokens"
+
+// The refresh cookie is only sent to the auth routes
+const refreshCookiePath = "/api/v1/auth"
 
 // @desc	Register a new user
 // @route	POST /api/v1/auth/register
 // @access	Public
 func Register(c *fiber.Ctx) error {
 
-	// 1) Parse the request body into the User struct
-	user := new(models.User)
-	if err := c.BodyParser(user); err != nil {
+	// 1) Parse the request body; the role is never taken from it
+	body := new(dto.RegisterRequest)
+	if err := c.BodyParser(body); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request format"})
 	}
+	user := body.User()
 
 	// 2) Validate the user input
 	if !user.ValidateEmail() {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid email address"})
 	}
+	if problems := password.Validate(user.Password, user.Email, user.Name); len(problems) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Password does not meet the requirements", "details": problems})
+	}
 
 	// 3) check if the email is already registered
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -50,65 +66,211 @@ func Register(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error hashing password"})
 	}
 
+	user.ID = primitive.NewObjectID()
 	user.CreatedAt = primitive.DateTime(time.Now().UnixNano() / int64(time.Millisecond))
+	user.EmailVerified = false
+	user.EmailVerifiedAt = 0
+
+	// Self sign-ups join the organization whose site they registered on
+	if organization := tenantOf(c).Organization; !organization.IsZero() {
+		count, err := config.DB.Collection(organizationCollection).CountDocuments(ctx, bson.M{"_id": organization})
+		if err != nil || count == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Organization not found"})
+		}
+		user.Organization = organization
+	}
 
-	// 5) Insert the user into the database
-	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	// 5) An invite grants its role and organization; claiming it first makes it single-use
+	var invite *models.Invite
+	if body.InviteToken != "" {
+		invite, err = claimInvite(ctx, body.InviteToken, user)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired invite"})
+		}
+		user.Role = invite.Role
+		user.Organization = invite.Organization
+	}
 
-	res, err := config.DB.Collection(userCollection).InsertOne(ctx, user)
+	// 6) Insert the user into the database
+	_, err = config.DB.Collection(userCollection).InsertOne(ctx, user)
 	if err != nil {
+		if invite != nil {
+			releaseInvite(ctx, invite.ID)
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error creating user"})
 	}
 
-	user.ID = res.InsertedID.(primitive.ObjectID)
-
-	// 6) Generate a JWT token for the user
-	token, err := user.GenerateToken(os.Getenv("JWT_SECRET"))
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Error generating token"})
+	// 7) Ask the user to verify the email address
+	if err := sendVerificationEmail(ctx, user); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error sending verification email"})
 	}
 
-	// 7) Return the response
-	return SendCookie(c, fiber.StatusOK, token, user.ID)
+	// 8) Generate the tokens and return the response
+	return sendTokens(c, fiber.StatusOK, user, []string{"pwd"})
 }
 
 // @desc	Login a user
 // @route	POST /api/v1/auth/login
 // @access	Public
 func Login(c *fiber.Ctx) error {
-	// 1) Parse the request body into the User struct
-	loggedUser := new(models.User)
+	// 1) Parse the request body
+	loggedUser := new(dto.LoginRequest)
 
-	if err := c.BodyParser(&loggedUser); err != nil {
+	if err := c.BodyParser(loggedUser); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request format"})
 	}
 
 	// 2) Validate the email format
-	if !loggedUser.ValidateEmail() {
+	if !models.ValidEmail(loggedUser.Email) {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid email address"})
 	}
 
-	// 3) Find the user by email
+	// 3) Refuse the attempt while the account or the client IP is backing off
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	wait, err := loginWait(ctx, loggedUser.Email, c.IP())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error checking login attempts"})
+	}
+	if wait > 0 {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "Too many failed login attempts, try again later"})
+	}
+
+	// 4) Find the user by email and check the password. Both failures get the
+	// same response so the endpoint does not reveal which emails exist.
 	targetUser := new(models.User)
-	err := config.DB.Collection(userCollection).FindOne(context.Background(), bson.M{"email": loggedUser.Email}).Decode(targetUser)
+	err = config.DB.Collection(userCollection).FindOne(ctx, bson.M{"email": loggedUser.Email, "deletedAt": bson.M{"$exists": false}}).Decode(targetUser)
+	if err != nil {
+		models.CheckDummyPassword(loggedUser.Password)
+	}
+	if err != nil || !targetUser.CheckPassword(loggedUser.Password) {
+		var owner *models.User
+		if err == nil {
+			owner = targetUser
+		}
+		if err := recordLoginFailure(ctx, owner, loggedUser.Email, c.IP()); err != nil {
+			log.Printf("Error recording failed login: %v", err)
+		}
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid email or password"})
+	}
+
+	// 5) Reset the failure counts of the account and of this client
+	if err := clearLoginFailures(ctx, models.AccountAttemptKey(targetUser.Email), models.ClientAttemptKey(targetUser.Email, c.IP())); err != nil {
+		log.Printf("Error clearing failed logins: %v", err)
+	}
+
+	// 6) Upgrade a legacy or under-cost hash while the plaintext is at hand
+	if targetUser.PasswordNeedsRehash() {
+		rehashPassword(ctx, targetUser, loggedUser.Password)
+	}
+
+	// 7) Generate the tokens, or an MFA challenge, and return the response
+	return loginResponse(c, targetUser, "pwd")
+}
+
+// @desc	Exchange a refresh token for a new token pair
+// @route	POST /api/v1/auth/refresh
+// @access	Public (refresh token)
+func Refresh(c *fiber.Ctx) error {
+	// 1) Read the refresh token from the body or the cookie
+	body := struct {
+		RefreshToken string `json:"refreshToken"`
+	}{}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request format"})
+		}
+	}
+	if body.RefreshToken == "" {
+		body.RefreshToken = c.Cookies("refresh_token")
+		if body.RefreshToken != "" && !middleware.ValidCSRF(c) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Invalid CSRF token"})
+		}
+	}
+	if body.RefreshToken == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Missing refresh token"})
+	}
+
+	// 2) Find the stored token by its hash
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	existToken := new(models.RefreshToken)
+	err := config.DB.Collection(refreshTokenCollection).FindOne(ctx, bson.M{"hash": models.HashToken(body.RefreshToken)}).Decode(existToken)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid refresh token"})
+	}
+
+	if existToken.RevokedAt != 0 || existToken.IsExpired() {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid refresh token"})
+	}
+
+	// 3) Mark the token as used; if it already was, someone is replaying it
+	// and the whole session is revoked
+	now := primitive.NewDateTimeFromTime(time.Now())
+	res, err := config.DB.Collection(refreshTokenCollection).UpdateOne(ctx,
+		bson.M{"_id": existToken.ID, "usedAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"usedAt": now}},
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error rotating refresh token"})
+	}
+
+	if res.ModifiedCount == 0 {
+		if err := revokeSession(ctx, existToken.Family); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error revoking refresh tokens"})
+		}
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Refresh token reuse detected"})
+	}
+
+	// 4) Load the session and the user so the new access token carries current claims
+	session := new(models.Session)
+	err = config.DB.Collection(sessionCollection).FindOne(ctx, bson.M{"_id": existToken.Family}).Decode(session)
+	if err != nil || session.RevokedAt != 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Session has ended"})
+	}
+
+	user := new(models.User)
+	err = config.DB.Collection(userCollection).FindOne(ctx, bson.M{"_id": existToken.User}).Decode(user)
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not found"})
 	}
+	if !user.IsActive() {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Account is disabled"})
+	}
 
-	// 4) Check the password
-	if !targetUser.CheckPassword(loggedUser.Password) {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid password"})
+	// 5) Issue the replacement and link it to the used token
+	token, err := auth.IssueAccessToken(user, session)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error generating token"})
 	}
 
-	// 5) Generate a JWT token for the user
-	token, err := targetUser.GenerateToken(os.Getenv("JWT_SECRET"))
+	newToken, refreshToken, err := createRefreshToken(ctx, user.ID, existToken.Family)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error generating token"})
 	}
 
-	// 6) Return the response
-	return SendCookie(c, fiber.StatusOK, token, targetUser.ID)
+	_, err = config.DB.Collection(refreshTokenCollection).UpdateOne(ctx,
+		bson.M{"_id": existToken.ID},
+		bson.M{"$set": bson.M{"replacedBy": newToken.ID}},
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error rotating refresh token"})
+	}
+
+	// 6) Keep the session alive for as long as its newest refresh token
+	_, err = config.DB.Collection(sessionCollection).UpdateOne(ctx,
+		bson.M{"_id": existToken.Family},
+		bson.M{"$set": bson.M{"lastSeenAt": now, "expiresAt": newToken.ExpiresAt}},
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error updating session"})
+	}
+
+	// 7) Return the response
+	return SendCookie(c, fiber.StatusOK, token, refreshToken, user.ID)
 }
 
 // @desc	Get the current user
@@ -132,44 +294,252 @@ func Me(c *fiber.Ctx) error {
 	}
 
 	// 3) Return the response
-	return c.Status(fiber.StatusOK).JSON(user)
+	return c.Status(fiber.StatusOK).JSON(dto.NewUser(user))
 }
 
-// @desc    Log user out / clear cookie
-// @route   GET /api/v1/auth/logout
+// @desc    Log user out of the current session / clear cookie
+// @route   POST /api/v1/auth/logout
 // @access  Private
 func Logout(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	// 1) Create a cookie object with an expired date to clear it
-	cookie := fiber.Cookie{
-		Name:     "token",
-		Value:    "",
-		Expires:  time.Now().Add(-1 * time.Hour),
-		HTTPOnly: true, // Ensure the cookie is HttpOnly
+	// 1) Revoke the access token used for this request
+	claims, ok := c.Locals("user").(jwt.MapClaims)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error parsing claims"})
+	}
+
+	if jti, ok := claims["jti"].(string); ok {
+		exp, _ := claims["exp"].(float64)
+		if err := revocation.Revoke(ctx, models.RevokeToken, jti, time.Unix(int64(exp), 0)); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error revoking token"})
+		}
+	}
+
+	// 2) Revoke the session together with its refresh tokens
+	if sid, ok := claims["sid"].(string); ok {
+		if sessionID, err := primitive.ObjectIDFromHex(sid); err == nil {
+			if err := revokeSession(ctx, sessionID); err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error revoking session"})
+			}
+		}
+	}
+
+	// 3) Clear the cookies; they belong to the admin when impersonating
+	if !middleware.Impersonating(c) {
+		clearAuthCookies(c)
+	}
+
+	// 4) Respond with a success message
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"date":    time.Now().Format(time.RFC3339),
+	})
+}
+
+// @desc    Log user out of every session
+// @route   POST /api/v1/auth/logout-all
+// @access  Private
+func LogoutAll(c *fiber.Ctx) error {
+	// 1) Get the user ID from the JWT claims
+	userID, ok := currentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error parsing claims"})
 	}
 
-	// 2) Send the cookie to the client
-	c.Cookie(&cookie)
+	// 2) Revoke every access and refresh token of the user
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := revokeAllUserTokens(ctx, userID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error revoking tokens"})
+	}
+
+	// 3) Clear the cookies; they belong to the admin when impersonating
+	if !middleware.Impersonating(c) {
+		clearAuthCookies(c)
+	}
 
-	// 3) Respond with a success message
+	// 4) Respond with a success message
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"success": true,
 		"date":    time.Now().Format(time.RFC3339),
 	})
 }
 
-// Send Cookie function
-func SendCookie(c *fiber.Ctx, statusCode int, token string, userID primitive.ObjectID) error {
+// revokeAllUserTokens ends every session of the user: the access tokens of
+// each session, all refresh tokens and all session records are revoked. A
+// user-wide cutoff also catches access tokens that belong to no session,
+// such as those an admin impersonating the user holds.
+func revokeAllUserTokens(ctx context.Context, userID primitive.ObjectID) error {
+	if err := revokeUserTokensExcept(ctx, userID, primitive.NilObjectID); err != nil {
+		return err
+	}
+	return revocation.RevokeUser(ctx, userID.Hex())
+}
+
+// revokeUserTokensExcept is revokeAllUserTokens but leaves the session keep
+// (if not nil) logged in
+func revokeUserTokensExcept(ctx context.Context, userID, keep primitive.ObjectID) error {
+	filter := bson.M{"user": userID, "revokedAt": bson.M{"$exists": false}}
+	sessionFilter := bson.M{"user": userID, "revokedAt": bson.M{"$exists": false}}
+	if !keep.IsZero() {
+		filter["family"] = bson.M{"$ne": keep}
+		sessionFilter["_id"] = bson.M{"$ne": keep}
+	}
+
+	cursor, err := config.DB.Collection(sessionCollection).Find(ctx, sessionFilter)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var sessions []models.Session
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		if err := revocation.RevokeSession(ctx, session.ID.Hex()); err != nil {
+			return err
+		}
+	}
+
+	update := bson.M{"$set": bson.M{"revokedAt": primitive.NewDateTimeFromTime(time.Now())}}
+	if _, err := config.DB.Collection(refreshTokenCollection).UpdateMany(ctx, filter, update); err != nil {
+		return err
+	}
+	_, err = config.DB.Collection(sessionCollection).UpdateMany(ctx, sessionFilter, update)
+	return err
+}
+
+// clearAuthCookies expires the access token, refresh token and CSRF cookies
+func clearAuthCookies(c *fiber.Ctx) {
+	c.Cookie(&fiber.Cookie{
+		Name:     middleware.TokenCookie,
+		Value:    "",
+		Expires:  time.Now().Add(-1 * time.Hour),
+		HTTPOnly: true, // Ensure the cookie is HttpOnly
+	})
+	c.Cookie(&fiber.Cookie{
+		Name:     "refresh_token",
+		Value:    "",
+		Path:     refreshCookiePath,
+		Expires:  time.Now().Add(-1 * time.Hour),
+		HTTPOnly: true,
+	})
+	c.Cookie(&fiber.Cookie{
+		Name:    middleware.CSRFCookie,
+		Value:   "",
+		Expires: time.Now().Add(-1 * time.Hour),
+	})
+}
+
+// sendTokens starts a new session for the user authenticated with the given
+// methods, issues its access and refresh tokens and sends them to the client
+func sendTokens(c *fiber.Ctx, statusCode int, user *models.User, amr []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	session, err := createSession(ctx, c, user.ID, amr)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error creating session"})
+	}
+
+	token, err := auth.IssueAccessToken(user, session)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error generating token"})
+	}
+
+	_, refreshToken, err := createRefreshToken(ctx, user.ID, session.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error generating token"})
+	}
+	recordAudit(c, audit.Login, user.ID, map[string]interface{}{"session": session.ID, "amr": amr})
+
+	return SendCookie(c, statusCode, token, refreshToken, user.ID)
+}
+
+// createRefreshToken stores a new refresh token and returns it with its plaintext value
+func createRefreshToken(ctx context.Context, userID, family primitive.ObjectID) (*models.RefreshToken, string, error) {
+	token, plain, err := models.NewRefreshToken(userID, family, config.RefreshTokenTTL())
+	if err != nil {
+		return nil, "", err
+	}
+
+	res, err := config.DB.Collection(refreshTokenCollection).InsertOne(ctx, token)
+	if err != nil {
+		return nil, "", err
+	}
+	token.ID = res.InsertedID.(primitive.ObjectID)
+
+	return token, plain, nil
+}
+
+// revokeRefreshFamily revokes every refresh token descending from the same login
+func revokeRefreshFamily(ctx context.Context, family primitive.ObjectID) error {
+	_, err := config.DB.Collection(refreshTokenCollection).UpdateMany(ctx,
+		bson.M{"family": family, "revokedAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revokedAt": primitive.NewDateTimeFromTime(time.Now())}},
+	)
+	return err
+}
+
+// Send Cookie function. The CSRF cookie is readable by scripts so the
+// client can echo it in the X-CSRF-Token header.
+func SendCookie(c *fiber.Ctx, statusCode int, token, refreshToken string, userID primitive.ObjectID) error {
+	csrfToken, err := models.RandomToken(32)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error generating token"})
+	}
+
 	c.Cookie(&fiber.Cookie{
-		Name:     "token",
+		Name:     middleware.TokenCookie,
 		Value:    token,
-		Expires:  time.Now().Add(time.Hour * 24),
+		Expires:  time.Now().Add(config.AccessTokenTTL()),
 		HTTPOnly: true,
 	})
+	c.Cookie(&fiber.Cookie{
+		Name:     "refresh_token",
+		Value:    refreshToken,
+		Path:     refreshCookiePath,
+		Expires:  time.Now().Add(config.RefreshTokenTTL()),
+		HTTPOnly: true,
+	})
+	c.Cookie(&fiber.Cookie{
+		Name:     middleware.CSRFCookie,
+		Value:    csrfToken,
+		Expires:  time.Now().Add(config.RefreshTokenTTL()),
+		SameSite: fiber.CookieSameSiteStrictMode,
+	})
 
 	return c.Status(statusCode).JSON(fiber.Map{
-		"success": true,
-		"token":   token,
-		"userid":  userID,
+		"success":      true,
+		"token":        token,
+		"refreshToken": refreshToken,
+		"userid":       userID,
 	})
 }
+
+// rehashPassword stores a fresh hash of the user's password. The update only
+// applies if the stored hash is unchanged, so a concurrent password change
+// is not overwritten.
+func rehashPassword(ctx context.Context, user *models.User, plain string) {
+	oldHash := user.Password
+	hash, err := password.Hash(plain)
+	if err != nil {
+		log.Printf("Error rehashing password for %s: %v", user.ID.Hex(), err)
+		return
+	}
+
+	_, err = config.DB.Collection(userCollection).UpdateOne(ctx,
+		bson.M{"_id": user.ID, "password": oldHash},
+		bson.M{"$set": bson.M{"password": hash}},
+	)
+	if err != nil {
+		log.Printf("Error rehashing password for %s: %v", user.ID.Hex(), err)
+		return
+	}
+	user.Password = hash
+}
@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JongSinister/WTFiber/auth"
+	"github.com/JongSinister/WTFiber/models"
+	"github.com/JongSinister/WTFiber/revocation"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// withSigningKey loads a fresh Ed25519 signing key for the test
+func withSigningKey(t *testing.T) {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	auth.SetKeys([]models.SigningKey{{
+		Kid:         "test",
+		Alg:         auth.AlgEdDSA,
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		ActivatesAt: primitive.NewDateTimeFromTime(now.Add(-time.Minute)),
+		RetiresAt:   primitive.NewDateTimeFromTime(now.Add(time.Hour)),
+		ExpiresAt:   primitive.NewDateTimeFromTime(now.Add(2 * time.Hour)),
+	}})
+	t.Cleanup(func() { auth.SetKeys(nil) })
+}
+
+// refresh posts a refresh token to Refresh
+func refresh(t *testing.T, plain string) (int, map[string]interface{}) {
+	t.Helper()
+	app := fiber.New()
+	app.Post("/auth/refresh", Refresh)
+
+	req := httptest.NewRequest("POST", "/auth/refresh", strings.NewReader(`{"refreshToken":"`+plain+`"}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, body
+}
+
+func TestRefresh(t *testing.T) {
+	withSigningKey(t)
+
+	user := models.User{ID: primitive.NewObjectID(), Email: "somchai@example.com", Role: "user"}
+	session := models.Session{ID: primitive.NewObjectID(), User: user.ID, AMR: []string{"pwd"}}
+	stored, plain, err := models.NewRefreshToken(user.ID, session.ID, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored.ID = primitive.NewObjectID()
+
+	runWithMockDB(t, "rotation then replay", func(mt *mtest.T) {
+		// The first exchange rotates the token
+		mt.AddMockResponses(
+			mockFind(mt, refreshTokenCollection, stored),
+			mockWrite(1),
+			mockFind(mt, sessionCollection, session),
+			mockFind(mt, userCollection, user),
+			mockWrite(1),
+			mockWrite(1),
+			mockWrite(1),
+		)
+		status, body := refresh(mt.T, plain)
+		if status != fiber.StatusOK {
+			mt.Fatalf("got %d: %v", status, body)
+		}
+		if next, _ := body["refreshToken"].(string); next == "" || next == plain {
+			mt.Errorf("refresh token was not replaced: %q", next)
+		}
+		claims, err := auth.Parse(body["token"].(string), auth.Audience())
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if claims["sub"] != user.ID.Hex() || claims["sid"] != session.ID.Hex() {
+			mt.Errorf("access token claims %v", claims)
+		}
+		want := []string{"find", "update", "find", "find", "insert", "update", "update"}
+		if got := startedCommands(mt); !reflect.DeepEqual(got, want) {
+			mt.Fatalf("sent %v, want %v", got, want)
+		}
+		used := mt.GetAllStartedEvents()[1].Command.Lookup("updates").Array().Index(0).Value().Document()
+		if _, err := used.LookupErr("q", "usedAt"); err != nil {
+			mt.Error("the token is not marked used only if it was unused")
+		}
+		mt.ClearEvents()
+
+		// Presenting the same token again finds it already used and ends
+		// the whole session
+		replayed := *stored
+		replayed.UsedAt = primitive.NewDateTimeFromTime(time.Now())
+		mt.AddMockResponses(
+			mockFind(mt, refreshTokenCollection, replayed),
+			mockWrite(0),
+			mockWrite(1),
+			mockWrite(2),
+			mockWrite(1),
+		)
+		status, body = refresh(mt.T, plain)
+		if status != fiber.StatusUnauthorized || body["error"] != "Refresh token reuse detected" {
+			mt.Fatalf("got %d: %v", status, body)
+		}
+		want = []string{"find", "update", "update", "update", "update"}
+		if got := startedCommands(mt); !reflect.DeepEqual(got, want) {
+			mt.Fatalf("sent %v, want %v", got, want)
+		}
+
+		events := mt.GetAllStartedEvents()
+		revoked := events[3].Command.Lookup("updates").Array().Index(0).Value().Document()
+		if got := revoked.Lookup("q", "family").ObjectID(); got != session.ID {
+			mt.Errorf("revoked family %s, want %s", got.Hex(), session.ID.Hex())
+		}
+		if multi, _ := revoked.Lookup("multi").BooleanOK(); !multi {
+			mt.Error("only one token of the family was revoked")
+		}
+		ended := events[4].Command.Lookup("updates").Array().Index(0).Value().Document()
+		if got := ended.Lookup("q", "_id").ObjectID(); got != session.ID {
+			mt.Errorf("ended session %s, want %s", got.Hex(), session.ID.Hex())
+		}
+		if !revocation.IsRevoked("", session.ID.Hex(), "", time.Now()) {
+			mt.Error("access tokens of the session are still accepted")
+		}
+	})
+
+	runWithMockDB(t, "expired token", func(mt *mtest.T) {
+		expired := *stored
+		expired.ExpiresAt = primitive.NewDateTimeFromTime(time.Now().Add(-time.Minute))
+		mt.AddMockResponses(mockFind(mt, refreshTokenCollection, expired))
+
+		status, body := refresh(mt.T, plain)
+		if status != fiber.StatusUnauthorized || body["error"] != "Invalid refresh token" {
+			mt.Errorf("got %d: %v", status, body)
+		}
+		if got := startedCommands(mt); !reflect.DeepEqual(got, []string{"find"}) {
+			mt.Errorf("sent %v, want only the lookup", got)
+		}
+	})
+
+	runWithMockDB(t, "unknown token", func(mt *mtest.T) {
+		mt.AddMockResponses(mockFind(mt, refreshTokenCollection))
+
+		if status, _ := refresh(mt.T, "not-a-token"); status != fiber.StatusUnauthorized {
+			mt.Errorf("got %d, want 401", status)
+		}
+	})
+}
@@ -0,0 +1,436 @@
+package controllers
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JongSinister/WTFiber/config"
+	"github.com/JongSinister/WTFiber/dto"
+	"github.com/JongSinister/WTFiber/models"
+	"github.com/JongSinister/WTFiber/tenant"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const chainCollection = "chains"
+
+// @desc    List hotel chains
+// @route   GET /api/v1/chains
+// @access  Public
+func GetChains(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.M{"name": 1})
+	cursor, err := config.DB.Collection(chainCollection).Find(ctx, scoped(c, bson.M{}), opts)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching chains"})
+	}
+	defer cursor.Close(ctx)
+
+	var chains []models.Chain
+	if err := cursor.All(ctx, &chains); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching chains"})
+	}
+
+	return c.JSON(dto.List(chains, dto.NewChain))
+}
+
+// @desc    Get a hotel chain
+// @route   GET /api/v1/chains/:id
+// @access  Public
+func GetChain(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	chain, status, msg := findChain(ctx, c)
+	if chain == nil {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+
+	return c.JSON(dto.NewChain(chain))
+}
+
+// @desc    Create a hotel chain
+// @route   POST /api/v1/chains
+// @access  Private (chains:manage)
+func CreateChain(c *fiber.Ctx) error {
+	// 1) Parse and validate the request body
+	body := struct {
+		Name         string `json:"name"`
+		Description  string `json:"description"`
+		Organization string `json:"organization"`
+	}{}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request format"})
+	}
+	if body.Name = strings.TrimSpace(body.Name); body.Name == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Name is required"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// 2) The chain joins the caller's organization; only super-admins
+	// working across tenants choose one
+	chain := &models.Chain{
+		ID:          primitive.NewObjectID(),
+		Name:        body.Name,
+		Description: strings.TrimSpace(body.Description),
+		CreatedAt:   primitive.NewDateTimeFromTime(time.Now()),
+	}
+	if scope := tenantOf(c); !scope.All {
+		chain.Organization = scope.Organization
+	} else if body.Organization != "" {
+		organization, err := primitive.ObjectIDFromHex(body.Organization)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid organization"})
+		}
+		count, err := config.DB.Collection(organizationCollection).CountDocuments(ctx, bson.M{"_id": organization})
+		if err != nil || count == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Organization not found"})
+		}
+		chain.Organization = organization
+	}
+
+	// 3) Store the chain; names are unique within an organization
+	if _, err := config.DB.Collection(chainCollection).InsertOne(ctx, chain); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "A chain with that name already exists"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error creating chain"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(dto.NewChain(chain))
+}
+
+// @desc    Update a hotel chain
+// @route   PUT /api/v1/chains/:id
+// @access  Private (chains:manage)
+func UpdateChain(c *fiber.Ctx) error {
+	// 1) Get the ID from the URL and convert it to an ObjectID
+	objectID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID Format"})
+	}
+
+	// 2) Parse the fields to change
+	body := struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+	}{}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request format"})
+	}
+
+	set := bson.M{}
+	if body.Name != nil {
+		if *body.Name = strings.TrimSpace(*body.Name); *body.Name == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Name is required"})
+		}
+		set["name"] = *body.Name
+	}
+	if body.Description != nil {
+		set["description"] = strings.TrimSpace(*body.Description)
+	}
+	if len(set) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "No fields to update"})
+	}
+
+	// 3) Update the chain
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	chain := new(models.Chain)
+	err = config.DB.Collection(chainCollection).FindOneAndUpdate(ctx,
+		scoped(c, bson.M{"_id": objectID}),
+		bson.M{"$set": set},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(chain)
+	if mongo.IsDuplicateKeyError(err) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "A chain with that name already exists"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Chain not found"})
+	}
+
+	return c.JSON(dto.NewChain(chain))
+}
+
+// @desc    Delete a hotel chain; its hotels stay but lose the brand
+// @route   DELETE /api/v1/chains/:id
+// @access  Private (chains:manage)
+func DeleteChain(c *fiber.Ctx) error {
+	// 1) Get the ID from the URL and convert it to an ObjectID
+	objectID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID Format"})
+	}
+
+	// 2) Delete the chain and detach its hotels
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := config.DB.Collection(chainCollection).DeleteOne(ctx, scoped(c, bson.M{"_id": objectID}))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error deleting chain"})
+	}
+	if result.DeletedCount == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Chain not found"})
+	}
+
+	_, err = config.DB.Collection(hotelCollection).UpdateMany(ctx, bson.M{"chain": objectID}, bson.M{"$unset": bson.M{"chain": ""}})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error detaching hotels"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Chain deleted successfully"})
+}
+
+// @desc    Count a chain's hotels and bookings
+// @route   GET /api/v1/chains/:id/stats
+// @access  Private (chains:reports)
+func GetChainStats(c *fiber.Ctx) error {
+	// 1) Fetch the chain and its hotels
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	chain, status, msg := findChain(ctx, c)
+	if chain == nil {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+
+	hotelIDs, err := chainHotelIDs(ctx, chain)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching hotels"})
+	}
+
+	// 2) Count the appointments at them by status, cancelled ones included
+	cursor, err := config.DB.Collection(appointmentCollection).Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: chainAppointmentsFilter(chain, hotelIDs)}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"$ifNull": bson.A{"$status", models.AppointmentPending}},
+			"count": bson.M{"$sum": 1},
+		}}},
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error computing stats"})
+	}
+	defer cursor.Close(ctx)
+
+	var groups []struct {
+		Status string `bson:"_id"`
+		Count  int64  `bson:"count"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error computing stats"})
+	}
+
+	// 3) Bookings counts what was not cancelled, like the other reports
+	byStatus := fiber.Map{}
+	var total int64
+	for _, group := range groups {
+		byStatus[group.Status] = group.Count
+		if group.Status != models.AppointmentCancelled {
+			total += group.Count
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"chain":            dto.NewChain(chain),
+		"hotels":           len(hotelIDs),
+		"bookings":         total,
+		"bookingsByStatus": byStatus,
+	})
+}
+
+// @desc    Count a chain's bookings per month
+// @route   GET /api/v1/chains/:id/bookings?from=&to=
+// @access  Private (chains:reports)
+func GetChainBookingsPerMonth(c *fiber.Ctx) error {
+	// 1) Parse the date range; the last twelve months by default
+	to := time.Now()
+	from := to.AddDate(-1, 0, 0)
+	if value := c.Query("from"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "from must be YYYY-MM-DD"})
+		}
+		from = parsed
+	}
+	if value := c.Query("to"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "to must be YYYY-MM-DD"})
+		}
+		to = parsed.AddDate(0, 0, 1)
+	}
+	if !from.Before(to) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "from must be before to"})
+	}
+
+	// 2) Fetch the chain and its hotels
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	chain, status, msg := findChain(ctx, c)
+	if chain == nil {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+
+	hotelIDs, err := chainHotelIDs(ctx, chain)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching hotels"})
+	}
+
+	// 3) Group the bookings in the range by the month of their date
+	cursor, err := config.DB.Collection(appointmentCollection).Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: chainBookingsFilter(chain, hotelIDs, bson.M{"$gte": from, "$lt": to})}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"$dateToString": bson.M{"format": "%Y-%m", "date": "$apptDate"}},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error computing bookings"})
+	}
+	defer cursor.Close(ctx)
+
+	var months []struct {
+		Month string `bson:"_id" json:"month"`
+		Count int64  `bson:"count" json:"bookings"`
+	}
+	if err := cursor.All(ctx, &months); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error computing bookings"})
+	}
+
+	return c.JSON(fiber.Map{
+		"chain":  chain.ID,
+		"from":   from.Format("2006-01-02"),
+		"to":     to.AddDate(0, 0, -1).Format("2006-01-02"),
+		"months": months,
+	})
+}
+
+// @desc    List a chain's most booked hotels
+// @route   GET /api/v1/chains/:id/top-hotels?limit=
+// @access  Private (chains:reports)
+func GetChainTopHotels(c *fiber.Ctx) error {
+	// 1) Parse the limit
+	limit, err := strconv.Atoi(c.Query("limit", "10"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	// 2) Fetch the chain and its hotels
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	chain, status, msg := findChain(ctx, c)
+	if chain == nil {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+
+	hotelIDs, err := chainHotelIDs(ctx, chain)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching hotels"})
+	}
+
+	// 3) Rank the hotels by bookings and add their names
+	cursor, err := config.DB.Collection(appointmentCollection).Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: chainBookingsFilter(chain, hotelIDs, nil)}},
+		{{Key: "$group", Value: bson.M{"_id": "$hotel", "count": bson.M{"$sum": 1}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$lookup", Value: bson.M{"from": hotelCollection, "localField": "_id", "foreignField": "_id", "as": "hotel"}}},
+		{{Key: "$unwind", Value: "$hotel"}},
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error computing top hotels"})
+	}
+	defer cursor.Close(ctx)
+
+	var ranked []struct {
+		Count int64        `bson:"count"`
+		Hotel models.Hotel `bson:"hotel"`
+	}
+	if err := cursor.All(ctx, &ranked); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error computing top hotels"})
+	}
+
+	result := make([]fiber.Map, 0, len(ranked))
+	for _, entry := range ranked {
+		result = append(result, fiber.Map{
+			"hotel":    entry.Hotel.ID,
+			"name":     entry.Hotel.Name,
+			"bookings": entry.Count,
+		})
+	}
+	return c.JSON(result)
+}
+
+// findChain loads the chain in the :id param within the request's
+// organization. It returns the HTTP status and message on failure.
+func findChain(ctx context.Context, c *fiber.Ctx) (*models.Chain, int, string) {
+	objectID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return nil, fiber.StatusBadRequest, "Invalid ID Format"
+	}
+
+	chain := new(models.Chain)
+	if err := config.DB.Collection(chainCollection).FindOne(ctx, scoped(c, bson.M{"_id": objectID})).Decode(chain); err != nil {
+		return nil, fiber.StatusNotFound, "Chain not found"
+	}
+	return chain, 0, ""
+}
+
+// chainHotelIDs returns the IDs of the chain's hotels
+func chainHotelIDs(ctx context.Context, chain *models.Chain) ([]primitive.ObjectID, error) {
+	filter := tenant.Of(chain.Organization).Filter(bson.M{"chain": chain.ID})
+	cursor, err := config.DB.Collection(hotelCollection).Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var hotels []models.Hotel
+	if err := cursor.All(ctx, &hotels); err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, len(hotels))
+	for i, hotel := range hotels {
+		ids[i] = hotel.ID
+	}
+	return ids, nil
+}
+
+// chainAppointmentsFilter matches every appointment at the chain's hotels,
+// cancelled ones included
+func chainAppointmentsFilter(chain *models.Chain, hotelIDs []primitive.ObjectID) bson.M {
+	return tenant.Of(chain.Organization).Filter(bson.M{"hotel": bson.M{"$in": hotelIDs}})
+}
+
+// chainBookingsFilter matches the bookings at the chain's hotels that were
+// not cancelled, optionally within a date range
+func chainBookingsFilter(chain *models.Chain, hotelIDs []primitive.ObjectID, dates bson.M) bson.M {
+	filter := chainAppointmentsFilter(chain, hotelIDs)
+	filter["status"] = bson.M{"$ne": models.AppointmentCancelled}
+	if dates != nil {
+		filter["apptDate"] = dates
+	}
+	return filter
+}
+
+// validChain reports whether the chain exists in the organization
+func validChain(ctx context.Context, chainID, organization primitive.ObjectID) (bool, error) {
+	count, err := config.DB.Collection(chainCollection).CountDocuments(ctx,
+		tenant.Of(organization).Filter(bson.M{"_id": chainID}))
+	return count > 0, err
+}
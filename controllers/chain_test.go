@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/JongSinister/WTFiber/models"
+	"github.com/JongSinister/WTFiber/tenant"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// getChainStats calls GetChainStats for the chain as a member of org
+func getChainStats(t *testing.T, org, chainID primitive.ObjectID) (int, map[string]interface{}) {
+	t.Helper()
+	app := fiber.New()
+	app.Get("/chains/:id/stats", func(c *fiber.Ctx) error {
+		c.Locals("tenant", tenant.Of(org))
+		return c.Next()
+	}, GetChainStats)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/chains/"+chainID.Hex()+"/stats", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, body
+}
+
+func TestGetChainStats(t *testing.T) {
+	org := primitive.NewObjectID()
+	chain := models.Chain{ID: primitive.NewObjectID(), Name: "Riverside", Organization: org}
+	hotels := []interface{}{models.Hotel{ID: primitive.NewObjectID()}, models.Hotel{ID: primitive.NewObjectID()}}
+
+	runWithMockDB(t, "stats", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mockFind(mt, chainCollection, chain),
+			mockFind(mt, hotelCollection, hotels...),
+			mockFind(mt, appointmentCollection,
+				bson.M{"_id": models.AppointmentPending, "count": 3},
+				bson.M{"_id": models.AppointmentConfirmed, "count": 5},
+				bson.M{"_id": models.AppointmentCancelled, "count": 2},
+			),
+		)
+
+		status, body := getChainStats(mt.T, org, chain.ID)
+		if status != fiber.StatusOK {
+			mt.Fatalf("got %d: %v", status, body)
+		}
+		if body["hotels"] != float64(2) {
+			mt.Errorf("hotels: got %v, want 2", body["hotels"])
+		}
+		if body["bookings"] != float64(8) {
+			mt.Errorf("bookings: got %v, want 8 (cancelled not counted)", body["bookings"])
+		}
+		want := map[string]interface{}{"pending": float64(3), "confirmed": float64(5), "cancelled": float64(2)}
+		if !reflect.DeepEqual(body["bookingsByStatus"], want) {
+			mt.Errorf("bookingsByStatus: got %v, want %v", body["bookingsByStatus"], want)
+		}
+
+		// Every query stays in the caller's organization, and the status
+		// breakdown does not drop cancellations
+		events := mt.GetAllStartedEvents()
+		if len(events) != 3 {
+			mt.Fatalf("sent %v", startedCommands(mt))
+		}
+		for _, event := range events[:2] {
+			filter := event.Command.Lookup("filter").Document()
+			if got := filter.Lookup("organization").ObjectID(); got != org {
+				mt.Errorf("%s: organization %s, want %s", event.CommandName, got.Hex(), org.Hex())
+			}
+		}
+		match := events[2].Command.Lookup("pipeline").Array().Index(0).Value().Document().Lookup("$match").Document()
+		if got := match.Lookup("organization").ObjectID(); got != org {
+			mt.Errorf("aggregate: organization %s, want %s", got.Hex(), org.Hex())
+		}
+		if _, err := match.LookupErr("status"); err == nil {
+			mt.Error("aggregate filters on status")
+		}
+	})
+
+	// A chain of another organization is not found by the scoped lookup
+	runWithMockDB(t, "other organization", func(mt *mtest.T) {
+		mt.AddMockResponses(mockFind(mt, chainCollection))
+
+		other := primitive.NewObjectID()
+		if status, _ := getChainStats(mt.T, other, chain.ID); status != fiber.StatusNotFound {
+			mt.Errorf("got %d, want 404", status)
+		}
+		if got := startedCommands(mt); !reflect.DeepEqual(got, []string{"find"}) {
+			mt.Fatalf("sent %v, want only the chain lookup", got)
+		}
+		filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+		if got := filter.Lookup("organization").ObjectID(); got != other {
+			mt.Errorf("organization %s, want %s", got.Hex(), other.Hex())
+		}
+	})
+}
@@ -0,0 +1,490 @@
+package controllers
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/JongSinister/WTFiber/audit"
+	"github.com/JongSinister/WTFiber/config"
+	"github.com/JongSinister/WTFiber/dto"
+	"github.com/JongSinister/WTFiber/mailer"
+	"github.com/JongSinister/WTFiber/models"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const dataExportCollection = "data_exports"
+
+// The ZIPs are kept in GridFS, in the export_files.files and
+// export_files.chunks collections, under the ID of their export
+const dataExportBucket = "export_files"
+
+// How long building an export may take before it is marked as failed
+const dataExportTimeout = 2 * time.Minute
+
+// exportSection is one JSON file in the export: the documents of a
+// collection that match the filter, without the fields in omit
+type exportSection struct {
+	file       string
+	collection string
+	filter     bson.M
+	omit       []string
+}
+
+// exportSections lists what is stored about a user. Secrets such as password
+// hashes, MFA secrets and token hashes are left out.
+func exportSections(userID primitive.ObjectID) []exportSection {
+	return []exportSection{
+		{"user.json", userCollection, bson.M{"_id": userID}, []string{"password", "mfaSecret", "mfaPendingSecret", "mfaRecoveryCodes", "mfaLastStep"}},
+		{"appointments.json", appointmentCollection, bson.M{"user": userID}, nil},
+		{"payments.json", paymentCollection, bson.M{"user": userID}, nil},
+		{"sessions.json", sessionCollection, bson.M{"user": userID}, nil},
+		{"identities.json", identityCollection, bson.M{"user": userID}, nil},
+		{"audit.json", audit.Collection, bson.M{"$or": bson.A{bson.M{"subject": userID}, bson.M{"actor": userID}}}, nil},
+	}
+}
+
+// @desc    Request an export of the current user's data
+// @route   POST /api/v1/auth/me/export
+// @access  Private
+func RequestMyDataExport(c *fiber.Ctx) error {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error parsing claims"})
+	}
+	return startDataExport(c, userID)
+}
+
+// @desc    Request an export of a user's data on their behalf
+// @route   POST /api/v1/admin/users/:id/export
+// @access  Private (admin)
+func RequestUserDataExport(c *fiber.Ctx) error {
+	// 1) Get the ID from the URL and convert it to an ObjectID
+	objectID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID Format"})
+	}
+
+	// 2) Make sure the user exists in the admin's organization
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ok, err := inScope(ctx, c, userCollection, objectID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching user"})
+	}
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	return startDataExport(c, objectID)
+}
+
+// @desc    List the current user's data exports
+// @route   GET /api/v1/auth/me/exports
+// @access  Private
+func GetMyDataExports(c *fiber.Ctx) error {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error parsing claims"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := failStaleExports(ctx, userID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching exports"})
+	}
+
+	opts := options.Find().SetSort(bson.M{"createdAt": -1})
+	cursor, err := config.DB.Collection(dataExportCollection).Find(ctx, bson.M{"user": userID}, opts)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching exports"})
+	}
+	defer cursor.Close(ctx)
+
+	var exports []models.DataExport
+	if err := cursor.All(ctx, &exports); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching exports"})
+	}
+
+	return c.JSON(dto.List(exports, dto.NewDataExport))
+}
+
+// @desc    Download a data export
+// @route   GET /api/v1/exports/:id/download?token=
+// @access  Public (export token)
+func DownloadDataExport(c *fiber.Ctx) error {
+	// 1) Get the ID from the URL and convert it to an ObjectID
+	objectID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID Format"})
+	}
+
+	// 2) Find the export the token belongs to
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	export := new(models.DataExport)
+	err = config.DB.Collection(dataExportCollection).FindOne(ctx, bson.M{
+		"_id":       objectID,
+		"hash":      models.HashToken(c.Query("token")),
+		"expiresAt": bson.M{"$gt": primitive.NewDateTimeFromTime(time.Now())},
+	}).Decode(export)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Invalid or expired download link"})
+	}
+
+	// 3) Send the ZIP once it is built
+	switch {
+	case export.Status == models.DataExportPending && !exportStale(export, time.Now()):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Export is not ready yet"})
+	case export.Status != models.DataExportReady:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Export failed, please request a new one"})
+	}
+
+	bucket, err := exportBucket()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching export"})
+	}
+	stream, err := bucket.OpenDownloadStream(export.ID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Invalid or expired download link"})
+	}
+	if err := stream.SetReadDeadline(time.Now().Add(dataExportTimeout)); err != nil {
+		stream.Close()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching export"})
+	}
+
+	recordAudit(c, audit.ExportDownload, export.User, map[string]interface{}{"export": export.ID})
+
+	filename := fmt.Sprintf("wtfiber-export-%s.zip", export.CreatedAt.Time().Format("2006-01-02"))
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+	return c.SendStream(stream, int(stream.GetFile().Length))
+}
+
+// startDataExport stores a pending export for the user, builds it in the
+// background and returns its download link, which cannot be retrieved again
+func startDataExport(c *fiber.Ctx, userID primitive.ObjectID) error {
+	requestedBy, ok := currentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error parsing claims"})
+	}
+
+	// 1) Only one export is built at a time per user. One that has been
+	// pending for longer than a build may take will never finish.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := failStaleExports(ctx, userID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error creating export"})
+	}
+
+	pending, err := config.DB.Collection(dataExportCollection).CountDocuments(ctx, bson.M{
+		"user":   userID,
+		"status": models.DataExportPending,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error creating export"})
+	}
+	if pending > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "An export is already being prepared"})
+	}
+
+	// 2) Store the pending export
+	plain, err := models.RandomToken(32)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error creating export"})
+	}
+
+	now := time.Now()
+	export := &models.DataExport{
+		ID:          primitive.NewObjectID(),
+		User:        userID,
+		RequestedBy: requestedBy,
+		Hash:        models.HashToken(plain),
+		Status:      models.DataExportPending,
+		CreatedAt:   primitive.NewDateTimeFromTime(now),
+		ExpiresAt:   primitive.NewDateTimeFromTime(now.Add(config.DataExportTTL())),
+	}
+
+	if _, err := config.DB.Collection(dataExportCollection).InsertOne(ctx, export); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error creating export"})
+	}
+
+	// 3) Build it in the background
+	link := fmt.Sprintf("%s/api/v1/exports/%s/download?token=%s", config.AppURL(), export.ID.Hex(), plain)
+	go buildDataExport(export.ID, userID, link, export.ExpiresAt.Time())
+	recordAudit(c, audit.ExportRequest, userID, map[string]interface{}{"export": export.ID})
+
+	// 4) Return the link; it works once the export is ready
+	return c.Status(fiber.StatusAccepted).JSON(dto.CreatedDataExportResponse{
+		DataExportResponse: dto.NewDataExport(export),
+		Link:               link,
+	})
+}
+
+// buildDataExport collects the user's data into a ZIP, stores it in GridFS
+// and emails the user the download link
+func buildDataExport(exportID, userID primitive.ObjectID, link string, expiresAt time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), dataExportTimeout)
+	defer cancel()
+
+	set := bson.M{"completedAt": primitive.NewDateTimeFromTime(time.Now())}
+	size, err := storeUserData(ctx, exportID, userID, expiresAt)
+	if err != nil {
+		log.Printf("Error building data export %s: %v", exportID.Hex(), err)
+		set["status"] = models.DataExportFailed
+		set["error"] = "Error collecting data"
+	} else {
+		set["status"] = models.DataExportReady
+		set["size"] = size
+	}
+
+	// An export that took too long was already marked as failed; its file
+	// goes too
+	result, err := config.DB.Collection(dataExportCollection).UpdateOne(ctx,
+		bson.M{"_id": exportID, "status": models.DataExportPending},
+		bson.M{"$set": set},
+	)
+	if err != nil || result.MatchedCount == 0 {
+		if err != nil {
+			log.Printf("Error saving data export %s: %v", exportID.Hex(), err)
+		}
+		deleteExportFile(ctx, exportID)
+		return
+	}
+	if set["status"] != models.DataExportReady {
+		return
+	}
+
+	user := new(models.User)
+	if err := config.DB.Collection(userCollection).FindOne(ctx, bson.M{"_id": userID}).Decode(user); err != nil || !user.IsActive() {
+		return
+	}
+	mailer.SendAsync(mailer.Message{
+		To:      user.Email,
+		Subject: "Your data export is ready",
+		Body: fmt.Sprintf("Hi %s,\n\nThe copy of your data you asked for is ready. "+
+			"Download it within %s using the link below:\n\n%s\n", user.Name, config.DataExportTTL(), link),
+	})
+}
+
+// storeUserData writes the user's data to the export's file in GridFS and
+// returns its size. The file is tagged with the export's expiry so
+// StartDataExportCleanup can remove it.
+func storeUserData(ctx context.Context, exportID, userID primitive.ObjectID, expiresAt time.Time) (int64, error) {
+	bucket, err := exportBucket()
+	if err != nil {
+		return 0, err
+	}
+
+	opts := options.GridFSUpload().SetMetadata(bson.M{"user": userID, "expiresAt": primitive.NewDateTimeFromTime(expiresAt)})
+	stream, err := bucket.OpenUploadStreamWithID(exportID, exportID.Hex()+".zip", opts)
+	if err != nil {
+		return 0, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := stream.SetWriteDeadline(deadline); err != nil {
+			stream.Abort()
+			return 0, err
+		}
+	}
+
+	counter := &countingWriter{w: stream}
+	if err := collectUserData(ctx, counter, userID); err != nil {
+		stream.Abort()
+		return 0, err
+	}
+	if err := stream.Close(); err != nil {
+		return 0, err
+	}
+	return counter.n, nil
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// collectUserData writes a ZIP with one JSON file per export section
+func collectUserData(ctx context.Context, w io.Writer, userID primitive.ObjectID) error {
+	archive := zip.NewWriter(w)
+
+	for _, section := range exportSections(userID) {
+		opts := options.Find()
+		if len(section.omit) > 0 {
+			projection := bson.M{}
+			for _, field := range section.omit {
+				projection[field] = 0
+			}
+			opts.SetProjection(projection)
+		}
+
+		documents, err := findAll(ctx, config.DB.Collection(section.collection), section.filter, opts)
+		if err != nil {
+			return fmt.Errorf("%s: %w", section.collection, err)
+		}
+
+		content, err := json.MarshalIndent(documents, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		w, err := archive.Create(section.file)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(content); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// findAll returns every matching document as a generic map
+func findAll(ctx context.Context, collection *mongo.Collection, filter bson.M, opts *options.FindOptions) ([]bson.M, error) {
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	documents := []bson.M{}
+	if err := cursor.All(ctx, &documents); err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
+
+// exportBucket is the GridFS bucket export ZIPs are stored in
+func exportBucket() (*gridfs.Bucket, error) {
+	return gridfs.NewBucket(config.DB, options.GridFSBucket().SetName(dataExportBucket))
+}
+
+// exportStale reports whether a pending export has been building for longer
+// than a build may take, so it will never finish
+func exportStale(export *models.DataExport, now time.Time) bool {
+	return export.Status == models.DataExportPending && now.Sub(export.CreatedAt.Time()) > dataExportTimeout
+}
+
+// failStaleExports marks the user's exports that will never finish as failed
+func failStaleExports(ctx context.Context, userID primitive.ObjectID) error {
+	now := time.Now()
+	_, err := config.DB.Collection(dataExportCollection).UpdateMany(ctx,
+		bson.M{
+			"user":      userID,
+			"status":    models.DataExportPending,
+			"createdAt": bson.M{"$lt": primitive.NewDateTimeFromTime(now.Add(-dataExportTimeout))},
+		},
+		bson.M{"$set": bson.M{
+			"status":      models.DataExportFailed,
+			"error":       "Export timed out",
+			"completedAt": primitive.NewDateTimeFromTime(now),
+		}},
+	)
+	return err
+}
+
+// deleteUserDataExports removes the user's export records and every stored
+// ZIP of their data, including those whose record has already expired
+func deleteUserDataExports(ctx context.Context, userID primitive.ObjectID) error {
+	bucket, err := exportBucket()
+	if err != nil {
+		return err
+	}
+	cursor, err := bucket.FindContext(ctx, bson.M{"metadata.user": userID})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var file struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.Decode(&file); err != nil {
+			return err
+		}
+		if err := bucket.DeleteContext(ctx, file.ID); err != nil && err != gridfs.ErrFileNotFound {
+			return err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	_, err = config.DB.Collection(dataExportCollection).DeleteMany(ctx, bson.M{"user": userID})
+	return err
+}
+
+// deleteExportFile removes an export's ZIP, if it was stored
+func deleteExportFile(ctx context.Context, exportID primitive.ObjectID) {
+	bucket, err := exportBucket()
+	if err != nil {
+		log.Printf("Error deleting data export file %s: %v", exportID.Hex(), err)
+		return
+	}
+	if err := bucket.DeleteContext(ctx, exportID); err != nil && err != gridfs.ErrFileNotFound {
+		log.Printf("Error deleting data export file %s: %v", exportID.Hex(), err)
+	}
+}
+
+// StartDataExportCleanup deletes the files of expired exports on the given
+// interval; the TTL index only removes the export records
+func StartDataExportCleanup(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			deleteExpiredExportFiles()
+		}
+	}()
+}
+
+// deleteExpiredExportFiles removes every export file past its expiry
+func deleteExpiredExportFiles() {
+	ctx, cancel := context.WithTimeout(context.Background(), dataExportTimeout)
+	defer cancel()
+
+	bucket, err := exportBucket()
+	if err != nil {
+		log.Printf("Error cleaning up data exports: %v", err)
+		return
+	}
+	cursor, err := bucket.FindContext(ctx, bson.M{"metadata.expiresAt": bson.M{"$lte": primitive.NewDateTimeFromTime(time.Now())}})
+	if err != nil {
+		log.Printf("Error cleaning up data exports: %v", err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var file struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.Decode(&file); err != nil {
+			log.Printf("Error cleaning up data exports: %v", err)
+			continue
+		}
+		deleteExportFile(ctx, file.ID)
+	}
+}
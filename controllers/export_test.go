@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/JongSinister/WTFiber/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestExportStale(t *testing.T) {
+	now := time.Now()
+	at := func(d time.Duration) primitive.DateTime { return primitive.NewDateTimeFromTime(now.Add(d)) }
+
+	tests := []struct {
+		name   string
+		export models.DataExport
+		want   bool
+	}{
+		{"pending within the build timeout", models.DataExport{Status: models.DataExportPending, CreatedAt: at(-time.Minute)}, false},
+		{"pending past the build timeout", models.DataExport{Status: models.DataExportPending, CreatedAt: at(-dataExportTimeout - time.Second)}, true},
+		{"ready exports never go stale", models.DataExport{Status: models.DataExportReady, CreatedAt: at(-time.Hour)}, false},
+	}
+
+	for _, tt := range tests {
+		if got := exportStale(&tt.export, now); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDeleteUserDataExports(t *testing.T) {
+	userID := primitive.NewObjectID()
+	fileID := primitive.NewObjectID()
+
+	runWithMockDB(t, "files and records", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mockFind(mt, dataExportBucket+".files", bson.M{"_id": fileID, "metadata": bson.M{"user": userID}}),
+			mockWrite(1), // files
+			mockWrite(3), // chunks
+			mockWrite(1), // export records
+		)
+
+		if err := deleteUserDataExports(context.Background(), userID); err != nil {
+			mt.Fatal(err)
+		}
+
+		events := mt.GetAllStartedEvents()
+		want := []string{"find", "delete", "delete", "delete"}
+		if got := startedCommands(mt); !reflect.DeepEqual(got, want) {
+			mt.Fatalf("sent %v, want %v", got, want)
+		}
+		if got := events[0].Command.Lookup("filter", "metadata.user"); got.ObjectID() != userID {
+			mt.Errorf("files looked up by %v, want the user's", got)
+		}
+		if got := events[3].Command.Lookup("delete").StringValue(); got != dataExportCollection {
+			mt.Errorf("records deleted from %q, want %q", got, dataExportCollection)
+		}
+		if got := events[3].Command.Lookup("deletes").Array().Index(0).Value().Document().Lookup("q", "user"); got.ObjectID() != userID {
+			mt.Errorf("records deleted by %v, want the user's", got)
+		}
+	})
+}
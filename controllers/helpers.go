@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/JongSinister/WTFiber/audit"
+	"github.com/JongSinister/WTFiber/config"
+	"github.com/JongSinister/WTFiber/middleware"
+	"github.com/JongSinister/WTFiber/models"
+	"github.com/JongSinister/WTFiber/tenant"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// currentUserID returns the ID of the authenticated user from the JWT claims.
+// API keys act for no user: their subject is the key's own ID.
+func currentUserID(c *fiber.Ctx) (primitive.ObjectID, bool) {
+	claims, ok := c.Locals("user").(jwt.MapClaims)
+	if !ok {
+		return primitive.NilObjectID, false
+	}
+	if _, isKey := claims["apiKey"]; isKey {
+		return primitive.NilObjectID, false
+	}
+
+	id, ok := claims["sub"].(string)
+	if !ok {
+		return primitive.NilObjectID, false
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return primitive.NilObjectID, false
+	}
+	return objectID, true
+}
+
+// recordAudit logs an action on the subject user. The actor is the
+// authenticated user, the subject itself before anyone is logged in, or none
+// for an API key, which is named in the details instead. Request values are
+// copied since the entry outlives the request.
+func recordAudit(c *fiber.Ctx, action string, subject primitive.ObjectID, details map[string]interface{}) {
+	actor, ok := currentUserID(c)
+	if claims, isClaims := c.Locals("user").(jwt.MapClaims); isClaims && claims["apiKey"] != nil {
+		if details == nil {
+			details = map[string]interface{}{}
+		}
+		details["apiKey"] = claims["apiKey"]
+	} else if !ok {
+		actor = subject
+	}
+
+	audit.Record(models.AuditEntry{
+		Action:    action,
+		Actor:     actor,
+		Subject:   subject,
+		IP:        strings.Clone(c.IP()),
+		UserAgent: strings.Clone(c.Get("User-Agent")),
+		Details:   details,
+	})
+}
+
+// tenantOf returns the organization the request acts for, as resolved by
+// middleware.Protect or middleware.Tenant
+func tenantOf(c *fiber.Ctx) tenant.Scope {
+	if scope, ok := c.Locals("tenant").(tenant.Scope); ok {
+		return scope
+	}
+	return tenant.Default
+}
+
+// scoped limits a query filter to the request's organization
+func scoped(c *fiber.Ctx, filter bson.M) bson.M {
+	return tenantOf(c).Filter(filter)
+}
+
+// inScope reports whether the record with the ID is in the collection and
+// belongs to the request's organization. Records kept without an
+// organization of their own, such as payments, sessions and grants, are
+// checked through the user or appointment they belong to.
+func inScope(ctx context.Context, c *fiber.Ctx, collection string, id primitive.ObjectID) (bool, error) {
+	count, err := config.DB.Collection(collection).CountDocuments(ctx, scoped(c, bson.M{"_id": id}))
+	return count > 0, err
+}
+
+// checkUserInScope makes sure the user exists in the request's organization.
+// It returns the HTTP status and message on failure, or zero.
+func checkUserInScope(c *fiber.Ctx, userID primitive.ObjectID) (int, string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ok, err := inScope(ctx, c, userCollection, userID)
+	if err != nil {
+		return fiber.StatusInternalServerError, "Error fetching user"
+	}
+	if !ok {
+		return fiber.StatusNotFound, "User not found"
+	}
+	return 0, ""
+}
+
+// can reports whether the authenticated principal holds the permission and
+// meets the MFA policy
+func can(c *fiber.Ctx, perm string) bool {
+	principal, status, _ := middleware.Principal(c)
+	return status == 0 && principal.Can(perm)
+}
+
+// canAccess reports whether the principal may apply perm to a record owned
+// by ownerID: either it holds perm outright or it holds the ":own" variant
+// and owns the record
+func canAccess(c *fiber.Ctx, perm string, ownerID primitive.ObjectID) bool {
+	if can(c, perm) {
+		return true
+	}
+	userID, ok := currentUserID(c)
+	return ok && userID == ownerID && can(c, perm+":own")
+}
+
+// canManageHotel reports whether the principal may apply perm to the hotel:
+// through its role, through the ":managed" variant when it manages the
+// hotel, or through a grant on the hotel
+func canManageHotel(ctx context.Context, c *fiber.Ctx, perm string, hotel *models.Hotel) (bool, error) {
+	principal, status, _ := middleware.Principal(c)
+	if status != 0 {
+		return false, nil
+	}
+
+	if userID, ok := currentUserID(c); ok && hotel.HasManager(userID) && principal.Can(perm+":managed") {
+		return true, nil
+	}
+	return principal.CanOn(ctx, perm, hotel.ID)
+}
+
+// managedHotelIDs returns the hotels the user manages
+func managedHotelIDs(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	cursor, err := config.DB.Collection(hotelCollection).Find(ctx, bson.M{"managers": userID},
+		options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var hotels []models.Hotel
+	if err := cursor.All(ctx, &hotels); err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, len(hotels))
+	for i, hotel := range hotels {
+		ids[i] = hotel.ID
+	}
+	return ids, nil
+}
@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/JongSinister/WTFiber/models"
+	"github.com/JongSinister/WTFiber/rbac"
+	"github.com/JongSinister/WTFiber/tenant"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/valyala/fasthttp"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCurrentUserID(t *testing.T) {
+	userID := primitive.NewObjectID()
+	keyID := primitive.NewObjectID()
+
+	tests := []struct {
+		name   string
+		claims interface{}
+		want   primitive.ObjectID
+		ok     bool
+	}{
+		{"user token", jwt.MapClaims{"sub": userID.Hex()}, userID, true},
+		{"api key", jwt.MapClaims{"sub": keyID.Hex(), "apiKey": keyID.Hex()}, primitive.NilObjectID, false},
+		{"malformed subject", jwt.MapClaims{"sub": "nope"}, primitive.NilObjectID, false},
+		{"no claims", nil, primitive.NilObjectID, false},
+	}
+
+	app := fiber.New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := app.AcquireCtx(&fasthttp.RequestCtx{})
+			defer app.ReleaseCtx(c)
+			if tt.claims != nil {
+				c.Locals("user", tt.claims)
+			}
+
+			got, ok := currentUserID(c)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("got (%s, %v), want (%s, %v)", got.Hex(), ok, tt.want.Hex(), tt.ok)
+			}
+		})
+	}
+}
+
+func TestScoped(t *testing.T) {
+	org := primitive.NewObjectID()
+
+	tests := []struct {
+		name  string
+		scope interface{}
+		want  interface{}
+	}{
+		{"organization member", tenant.Of(org), org},
+		{"no scope resolved", nil, bson.M{"$exists": false}},
+		{"super-admin across tenants", tenant.Scope{All: true, SuperAdmin: true}, nil},
+	}
+
+	app := fiber.New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := app.AcquireCtx(&fasthttp.RequestCtx{})
+			defer app.ReleaseCtx(c)
+			if tt.scope != nil {
+				c.Locals("tenant", tt.scope)
+			}
+
+			got, ok := scoped(c, bson.M{"_id": org})["organization"]
+			if tt.want == nil {
+				if ok {
+					t.Errorf("got organization %v, want none", got)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got organization %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// withRoles swaps in the role definitions for the test
+func withRoles(t *testing.T, defs map[string][]string) {
+	t.Helper()
+	saved := rbac.Roles()
+	entries := make([]models.Role, 0, len(defs))
+	for name, perms := range defs {
+		entries = append(entries, models.Role{Name: name, Permissions: perms})
+	}
+	rbac.SetRoles(entries)
+	t.Cleanup(func() { rbac.SetRoles(saved) })
+}
@@ -5,7 +5,9 @@ import (
 	"time"
 
 	"github.com/JongSinister/WTFiber/config"
+	"github.com/JongSinister/WTFiber/dto"
 	"github.com/JongSinister/WTFiber/models"
+	"github.com/JongSinister/WTFiber/tenant"
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -22,9 +24,19 @@ func GetHotels(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// 2) Fetch all hotels from the database
+	// 2) Narrow the list to one chain when asked
+	filter := bson.M{}
+	if chain := c.Query("chain"); chain != "" {
+		chainID, err := primitive.ObjectIDFromHex(chain)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid chain"})
+		}
+		filter["chain"] = chainID
+	}
+
+	// 3) Fetch all hotels from the database
 	opts := options.Find()
-	cursor, err := config.DB.Collection(hotelCollection).Find(ctx, bson.M{}, opts)
+	cursor, err := config.DB.Collection(hotelCollection).Find(ctx, scoped(c, filter), opts)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Error fetching hotels"})
 	}
@@ -42,7 +54,7 @@ func GetHotels(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "No hotels found"})
 	}
 
-	return c.JSON(hotels)
+	return c.JSON(dto.List(hotels, hotelResponse(c)))
 
 }
 
@@ -66,47 +78,81 @@ func GetHotel(c *fiber.Ctx) error {
 	// 3) Fetch the hotel by ID from the database
 	hotel := models.Hotel{}
 
-	err = config.DB.Collection(hotelCollection).FindOne(ctx, bson.M{"_id": objectID}).Decode(&hotel)
+	err = config.DB.Collection(hotelCollection).FindOne(ctx, scoped(c, bson.M{"_id": objectID})).Decode(&hotel)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Hotel not found"})
 	}
 
-	return c.JSON(hotel)
+	return c.JSON(hotelResponse(c)(&hotel))
 }
 
 // @desc    Create a new hotel
 // @route   POST /api/v1/hotels/
-// @access  Public
+// @access  Private (hotels:create, within the caller's organization)
 func CreateHotel(c *fiber.Ctx) error {
-	// 1) Get user and check permission(do later)
-
-	// 2) Parse the request body into a Hotel struct
-	hotel := new(models.Hotel)
-	if err := c.BodyParser(hotel); err != nil {
+	// 1) Parse and validate the request body. Managers, Wi-Fi and schedule
+	// are set through their own endpoints.
+	body := new(dto.CreateHotelRequest)
+	if err := c.BodyParser(body); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request format"})
 	}
+	if problem := body.Problem(false); problem != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": problem})
+	}
+	hotel := body.Hotel()
 
-	// 3) Insert the hotel into the database
+	// 2) The hotel joins the caller's organization; only super-admins
+	// working across tenants choose one
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	if scope := tenantOf(c); !scope.All {
+		hotel.Organization = scope.Organization
+	} else if body.Organization != "" {
+		organization, err := primitive.ObjectIDFromHex(body.Organization)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid organization"})
+		}
+		count, err := config.DB.Collection(organizationCollection).CountDocuments(ctx, bson.M{"_id": organization})
+		if err != nil || count == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Organization not found"})
+		}
+		hotel.Organization = organization
+	}
+
+	// 3) A chain must belong to the same organization as the hotel
+	if body.Chain != nil && *body.Chain != "" {
+		chainID, err := primitive.ObjectIDFromHex(*body.Chain)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid chain"})
+		}
+		found, err := validChain(ctx, chainID, hotel.Organization)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error checking chain"})
+		}
+		if !found {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Chain not found"})
+		}
+		hotel.Chain = chainID
+	}
+
+	// 4) Insert the hotel into the database
 	res, err := config.DB.Collection(hotelCollection).InsertOne(ctx, hotel)
 
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create hotel"})
 	}
+	hotel.ID = res.InsertedID.(primitive.ObjectID)
 
-	// 4) Return the response
-	return c.Status(fiber.StatusCreated).JSON(res)
+	// 5) Return the response
+	return c.Status(fiber.StatusCreated).JSON(hotelResponse(c)(hotel))
 }
 
 // @desc    Update a hotel by ID
 // @route   PUT /api/v1/hotels/:id
-// @access  Public
+// @access  Private (hotels:update, or a manager of the hotel)
 func UpdateHotel(c *fiber.Ctx) error {
-	// 1) Get user and check permission(do later)
-
-	// 2) Get the ID from the URL and convert it to an ObjectID
+	// 1) Get the ID from the URL and convert it to an ObjectID
 	id := c.Params("id")
 
 	objectID, err := primitive.ObjectIDFromHex(id)
@@ -114,46 +160,80 @@ func UpdateHotel(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID Format"})
 	}
 
-	// 3) Fetch the existing hotel document
+	// 2) Fetch the existing hotel document and check the caller may change it
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	existingHotel := new(models.Hotel)
-	err = config.DB.Collection(hotelCollection).FindOne(ctx, bson.M{"_id": objectID}).Decode(existingHotel)
+	err = config.DB.Collection(hotelCollection).FindOne(ctx, scoped(c, bson.M{"_id": objectID})).Decode(existingHotel)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Hotel not found"})
 	}
 
-	// 4) Parse the request body into a map for partial updates
-	var updates map[string]interface{}
-	if err := c.BodyParser(&updates); err != nil {
+	allowed, err := canManageHotel(ctx, c, "hotels:update", existingHotel)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error checking permissions"})
+	}
+	if !allowed {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	// 3) Parse and validate the fields to change. Managers, Wi-Fi and
+	// schedule have their own endpoints; hotels never change organization.
+	body := new(dto.UpdateHotelRequest)
+	if err := c.BodyParser(body); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request format"})
 	}
+	if problem := body.Problem(true); problem != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": problem})
+	}
+	updates := body.Set()
+	if len(updates) == 0 && body.Chain == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "No fields to update"})
+	}
 
-	// 5) Prepare the update document
-	update := bson.M{
-		"$set": updates,
+	// 4) Prepare the update document. A chain must belong to the hotel's
+	// organization; an empty chain removes the hotel from its chain.
+	update := bson.M{}
+	if body.Chain != nil {
+		if *body.Chain == "" {
+			update["$unset"] = bson.M{"chain": ""}
+		} else {
+			chainID, err := primitive.ObjectIDFromHex(*body.Chain)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid chain"})
+			}
+			found, err := validChain(ctx, chainID, existingHotel.Organization)
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error checking chain"})
+			}
+			if !found {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Chain not found"})
+			}
+			updates["chain"] = chainID
+		}
+	}
+	if len(updates) > 0 {
+		update["$set"] = updates
 	}
 
-	// 6) Update the hotel document with specified fields
+	// 5) Update the hotel document with specified fields
 	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
 	updatedHotel := new(models.Hotel)
-	err = config.DB.Collection(hotelCollection).FindOneAndUpdate(ctx, bson.M{"_id": objectID}, update, opts).Decode(updatedHotel)
+	err = config.DB.Collection(hotelCollection).FindOneAndUpdate(ctx, scoped(c, bson.M{"_id": objectID}), update, opts).Decode(updatedHotel)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update hotel"})
 	}
 
-	// 7) Return the updated hotel document
-	return c.JSON(updatedHotel)
+	// 6) Return the updated hotel document
+	return c.JSON(hotelResponse(c)(updatedHotel))
 }
 
 // @desc    Delete a hotel by ID
 // @route   DELETE /api/v1/hotels/:id
-// @access  Public
+// @access  Private (hotels:delete or a grant on the hotel, within the caller's organization)
 func DeleteHotel(c *fiber.Ctx) error {
-	// 1) Get user and check permission(do later)
-
-	// 2) Get the ID from the URL and convert it to an ObjectID
+	// 1) Get the ID from the URL and convert it to an ObjectID
 	id := c.Params("id")
 
 	objectID, err := primitive.ObjectIDFromHex(id)
@@ -161,11 +241,11 @@ func DeleteHotel(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID Format"})
 	}
 
-	// 3) Delete the hotel from database
+	// 2) Delete the hotel from the database; only hotels in the caller's organization match
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	res, err := config.DB.Collection(hotelCollection).DeleteOne(ctx, bson.M{"_id": objectID})
+	res, err := config.DB.Collection(hotelCollection).DeleteOne(ctx, scoped(c, bson.M{"_id": objectID}))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete hotel"})
 	}
@@ -174,6 +254,231 @@ func DeleteHotel(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "error"})
 	}
 
-	// 4) Return the response
+	// 3) Return the response
 	return c.JSON(fiber.Map{"message": "Hotel deleted successfully"})
 }
+
+// @desc    Set the managers of a hotel
+// @route   PUT /api/v1/hotels/:id/managers
+// @access  Private (hotels:managers)
+func SetHotelManagers(c *fiber.Ctx) error {
+	// 1) Get the ID from the URL and convert it to an ObjectID
+	objectID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID Format"})
+	}
+
+	// 2) Parse the manager IDs
+	body := struct {
+		Managers []string `json:"managers"`
+	}{}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request format"})
+	}
+
+	managers := make([]primitive.ObjectID, 0, len(body.Managers))
+	for _, id := range body.Managers {
+		managerID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid manager ID " + id})
+		}
+		managers = append(managers, managerID)
+	}
+
+	// 3) Every manager must be an existing user with the manager role in
+	// the hotel's organization
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	hotel := new(models.Hotel)
+	if err := config.DB.Collection(hotelCollection).FindOne(ctx, scoped(c, bson.M{"_id": objectID})).Decode(hotel); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Hotel not found"})
+	}
+
+	count, err := config.DB.Collection(userCollection).CountDocuments(ctx,
+		tenant.Of(hotel.Organization).Filter(bson.M{"_id": bson.M{"$in": managers}, "role": "manager"}))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error checking managers"})
+	}
+	if int(count) != len(managers) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Managers must be users with the manager role"})
+	}
+
+	// 4) Save the list
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	updatedHotel := new(models.Hotel)
+	err = config.DB.Collection(hotelCollection).FindOneAndUpdate(ctx,
+		bson.M{"_id": hotel.ID},
+		bson.M{"$set": bson.M{"managers": managers}},
+		opts,
+	).Decode(updatedHotel)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Hotel not found"})
+	}
+
+	return c.JSON(hotelResponse(c)(updatedHotel))
+}
+
+// @desc    Set the guest Wi-Fi of a hotel, optionally issuing new passwords
+// @route   PUT /api/v1/hotels/:id/wifi
+// @access  Private (hotels:wifi, or a manager of the hotel)
+func UpdateHotelWifi(c *fiber.Ctx) error {
+	// 1) Fetch the hotel and check the caller may change it
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	hotel, status, msg := findManagedHotel(ctx, c, "hotels:wifi")
+	if hotel == nil {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+
+	// 2) Parse the request body
+	body := struct {
+		SSID   string `json:"ssid"`
+		Rotate bool   `json:"rotate"`
+	}{}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request format"})
+	}
+	if body.SSID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "SSID is required"})
+	}
+
+	// 3) Save the network
+	wifi := models.HotelWifi{SSID: body.SSID, UpdatedAt: primitive.NewDateTimeFromTime(time.Now())}
+	_, err := config.DB.Collection(hotelCollection).UpdateOne(ctx, bson.M{"_id": hotel.ID}, bson.M{"$set": bson.M{"wifi": wifi}})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update Wi-Fi"})
+	}
+
+	// 4) Give upcoming appointments new passwords when asked to
+	rotated := 0
+	if body.Rotate {
+		cursor, err := config.DB.Collection(appointmentCollection).Find(ctx, scoped(c, bson.M{
+			"hotel":    hotel.ID,
+			"apptDate": bson.M{"$gte": time.Now()},
+		}))
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to rotate Wi-Fi passwords"})
+		}
+		defer cursor.Close(ctx)
+
+		var appointments []models.Appointment
+		if err := cursor.All(ctx, &appointments); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to rotate Wi-Fi passwords"})
+		}
+
+		for _, appointment := range appointments {
+			_, err := config.DB.Collection(appointmentCollection).UpdateOne(ctx,
+				bson.M{"_id": appointment.ID},
+				bson.M{"$set": bson.M{"wifiPassword": generateRandomPassword()}},
+			)
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to rotate Wi-Fi passwords"})
+			}
+			rotated++
+		}
+	}
+
+	return c.JSON(fiber.Map{"message": "Wi-Fi updated successfully", "wifi": dto.NewHotelWifi(&wifi), "rotated": rotated})
+}
+
+// @desc    Set the check-in/check-out times and closed dates of a hotel
+// @route   PUT /api/v1/hotels/:id/schedule
+// @access  Private (hotels:schedule, or a manager of the hotel)
+func UpdateHotelSchedule(c *fiber.Ctx) error {
+	// 1) Fetch the hotel and check the caller may change it
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	hotel, status, msg := findManagedHotel(ctx, c, "hotels:schedule")
+	if hotel == nil {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+
+	// 2) Parse and validate the schedule
+	schedule := new(models.HotelSchedule)
+	if err := c.BodyParser(schedule); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request format"})
+	}
+	for _, t := range []string{schedule.CheckIn, schedule.CheckOut} {
+		if _, err := time.Parse("15:04", t); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Check-in and check-out must be HH:MM"})
+		}
+	}
+	for _, d := range schedule.ClosedDates {
+		if _, err := time.Parse("2006-01-02", d); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Closed dates must be YYYY-MM-DD"})
+		}
+	}
+
+	// 3) Save the schedule
+	_, err := config.DB.Collection(hotelCollection).UpdateOne(ctx, bson.M{"_id": hotel.ID}, bson.M{"$set": bson.M{"schedule": schedule}})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update schedule"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Schedule updated successfully", "schedule": dto.NewHotelSchedule(schedule)})
+}
+
+// @desc    Get the appointments at a hotel
+// @route   GET /api/v1/hotels/:id/appointments
+// @access  Private (appointments:read, or a manager of the hotel)
+func GetHotelAppointments(c *fiber.Ctx) error {
+	// 1) Fetch the hotel and check the caller may see its bookings
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	hotel, status, msg := findManagedHotel(ctx, c, "appointments:read")
+	if hotel == nil {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+
+	// 2) Fetch the appointments, soonest first
+	opts := options.Find().SetSort(bson.M{"apptDate": 1})
+	cursor, err := config.DB.Collection(appointmentCollection).Find(ctx, scoped(c, bson.M{"hotel": hotel.ID}), opts)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching appointments"})
+	}
+	defer cursor.Close(ctx)
+
+	appointments := []models.Appointment{}
+	if err := cursor.All(ctx, &appointments); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching appointments"})
+	}
+
+	return c.JSON(dto.List(appointments, dto.NewAppointment))
+}
+
+// findManagedHotel loads the hotel in the :id param and checks that the
+// principal may apply perm to it. It returns the HTTP status and message on
+// failure.
+func findManagedHotel(ctx context.Context, c *fiber.Ctx, perm string) (*models.Hotel, int, string) {
+	objectID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return nil, fiber.StatusBadRequest, "Invalid ID Format"
+	}
+
+	hotel := new(models.Hotel)
+	if err := config.DB.Collection(hotelCollection).FindOne(ctx, scoped(c, bson.M{"_id": objectID})).Decode(hotel); err != nil {
+		return nil, fiber.StatusNotFound, "Hotel not found"
+	}
+
+	allowed, err := canManageHotel(ctx, c, perm, hotel)
+	if err != nil {
+		return nil, fiber.StatusInternalServerError, "Error checking permissions"
+	}
+	if !allowed {
+		return nil, fiber.StatusForbidden, "Access denied"
+	}
+	return hotel, 0, ""
+}
+
+// hotelResponse picks the hotel response for the caller: managers are only
+// listed for those who may assign them
+func hotelResponse(c *fiber.Ctx) func(*models.Hotel) dto.HotelResponse {
+	if can(c, "hotels:managers") {
+		return dto.NewManagedHotel
+	}
+	return dto.NewHotel
+}
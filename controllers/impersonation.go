@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/JongSinister/WTFiber/audit"
+	"github.com/JongSinister/WTFiber/auth"
+	"github.com/JongSinister/WTFiber/config"
+	"github.com/JongSinister/WTFiber/dto"
+	"github.com/JongSinister/WTFiber/models"
+	"github.com/JongSinister/WTFiber/rbac"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// @desc    Get a short-lived token to act as a user
+// @route   POST /api/v1/admin/impersonate/:userId
+// @access  Private (users:impersonate)
+func Impersonate(c *fiber.Ctx) error {
+	// 1) Only a logged-in admin can impersonate, never an API key
+	claims, ok := c.Locals("user").(jwt.MapClaims)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error parsing claims"})
+	}
+	if rbac.FromClaims(claims).APIKey {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "API keys cannot impersonate users"})
+	}
+	adminID, _ := claims["sub"].(string)
+	adminSession, _ := claims["sid"].(string)
+
+	// 2) Get the user from the URL
+	objectID, err := primitive.ObjectIDFromHex(c.Params("userId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID Format"})
+	}
+	if isCurrentUser(c, objectID) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "You cannot impersonate yourself"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user := new(models.User)
+	if err := config.DB.Collection(userCollection).FindOne(ctx, scoped(c, bson.M{"_id": objectID})).Decode(user); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	if !user.IsActive() {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Account is disabled"})
+	}
+
+	// 3) Staff who manage accounts cannot be impersonated, so nobody gains
+	// permissions through it
+	target := rbac.Principal{ID: user.ID.Hex(), Role: user.Role}
+	if target.Can("users:impersonate") || target.Can("users:manage") {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "This user cannot be impersonated"})
+	}
+
+	// 4) Issue the token and log it
+	token, err := auth.IssueImpersonationToken(user, adminID, adminSession, claims["amr"])
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error generating token"})
+	}
+
+	ttl := config.ImpersonationTTL()
+	recordAudit(c, audit.ImpersonationStart, user.ID, map[string]interface{}{"ttl": ttl.String()})
+
+	// The token is only returned in the body so the admin's own cookies are
+	// left alone; POST /auth/logout with it ends the impersonation
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success":       true,
+		"token":         token,
+		"impersonating": dto.NewUser(user),
+		"impersonator":  adminID,
+		"expiresAt":     time.Now().Add(ttl).UTC(),
+	})
+}
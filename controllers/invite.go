@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/JongSinister/WTFiber/config"
+	"github.com/JongSinister/WTFiber/dto"
+	"github.com/JongSinister/WTFiber/mailer"
+	"github.com/JongSinister/WTFiber/models"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const inviteCollection = "invites"
+
+// @desc    Invite someone to register with a given role
+// @route   POST /api/v1/admin/invites
+// @access  Private (admin)
+func CreateInvite(c *fiber.Ctx) error {
+	// 1) Parse and validate the request body
+	body := new(dto.InviteRequest)
+	if err := c.BodyParser(body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request format"})
+	}
+	if status, message := checkAssignableRole(c, body.Role); status != 0 {
+		return c.Status(status).JSON(fiber.Map{"error": message})
+	}
+	if body.Email != "" && !models.ValidEmail(body.Email) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid email address"})
+	}
+
+	ttl := config.InviteTTL()
+	if body.ExpiresIn != "" {
+		d, err := time.ParseDuration(body.ExpiresIn)
+		if err != nil || d <= 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid expiresIn duration"})
+		}
+		ttl = d
+	}
+
+	adminID, ok := currentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error parsing claims"})
+	}
+
+	// 2) Store the invite
+	plain, err := models.RandomToken(32)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error creating invite"})
+	}
+
+	now := time.Now()
+	invite := &models.Invite{
+		ID:        primitive.NewObjectID(),
+		Hash:      models.HashToken(plain),
+		Email:     strings.ToLower(body.Email),
+		Role:      body.Role,
+		CreatedBy: adminID,
+		CreatedAt: primitive.NewDateTimeFromTime(now),
+		ExpiresAt: primitive.NewDateTimeFromTime(now.Add(ttl)),
+	}
+	if scope := tenantOf(c); !scope.All {
+		invite.Organization = scope.Organization
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := config.DB.Collection(inviteCollection).InsertOne(ctx, invite); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error creating invite"})
+	}
+
+	// 3) Email the link when the invite is for a known address
+	link := config.GetEnv("INVITE_URL", config.AppURL()+"/register?invite=") + plain
+	if invite.Email != "" {
+		mailer.SendAsync(mailer.Message{
+			To:      invite.Email,
+			Subject: "You have been invited",
+			Body: fmt.Sprintf("Hi,\n\nYou have been invited to create an account with the %s role. "+
+				"Use the link below within %s to sign up:\n\n%s\n", invite.Role, ttl, link),
+		})
+	}
+
+	// 4) Return the token; it cannot be retrieved again
+	return c.Status(fiber.StatusCreated).JSON(dto.CreatedInviteResponse{
+		InviteResponse: dto.NewInvite(invite),
+		Token:          plain,
+		Link:           link,
+	})
+}
+
+// @desc    List pending and used invites
+// @route   GET /api/v1/admin/invites
+// @access  Private (admin)
+func GetInvites(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.M{"createdAt": -1})
+	cursor, err := config.DB.Collection(inviteCollection).Find(ctx, scoped(c, bson.M{}), opts)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching invites"})
+	}
+	defer cursor.Close(ctx)
+
+	var invites []models.Invite
+	if err := cursor.All(ctx, &invites); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching invites"})
+	}
+
+	return c.JSON(dto.List(invites, dto.NewInvite))
+}
+
+// @desc    Withdraw an unused invite
+// @route   DELETE /api/v1/admin/invites/:id
+// @access  Private (admin)
+func DeleteInvite(c *fiber.Ctx) error {
+	// 1) Get the ID from the URL and convert it to an ObjectID
+	objectID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID Format"})
+	}
+
+	// 2) Delete the invite if nobody has used it yet
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := config.DB.Collection(inviteCollection).DeleteOne(ctx, scoped(c, bson.M{"_id": objectID, "usedAt": bson.M{"$exists": false}}))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error deleting invite"})
+	}
+	if result.DeletedCount == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Invite not found or already used"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Invite deleted successfully"})
+}
+
+// claimInvite marks an invite used by the user about to be created. Invites
+// tied to an email only match that address.
+func claimInvite(ctx context.Context, token string, user *models.User) (*models.Invite, error) {
+	now := primitive.NewDateTimeFromTime(time.Now())
+	filter := bson.M{
+		"hash":      models.HashToken(token),
+		"usedAt":    bson.M{"$exists": false},
+		"expiresAt": bson.M{"$gt": now},
+		"$or": bson.A{
+			bson.M{"email": bson.M{"$exists": false}},
+			bson.M{"email": strings.ToLower(user.Email)},
+		},
+	}
+
+	invite := new(models.Invite)
+	err := config.DB.Collection(inviteCollection).FindOneAndUpdate(ctx, filter,
+		bson.M{"$set": bson.M{"usedAt": now, "usedBy": user.ID}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(invite)
+	if err != nil {
+		return nil, err
+	}
+	return invite, nil
+}
+
+// releaseInvite makes a claimed invite usable again after sign-up failed
+func releaseInvite(ctx context.Context, inviteID primitive.ObjectID) {
+	_, err := config.DB.Collection(inviteCollection).UpdateOne(ctx,
+		bson.M{"_id": inviteID},
+		bson.M{"$unset": bson.M{"usedAt": "", "usedBy": ""}},
+	)
+	if err != nil {
+		log.Printf("Error releasing invite %s: %v", inviteID.Hex(), err)
+	}
+}
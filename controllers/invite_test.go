@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"context"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JongSinister/WTFiber/middleware"
+	"github.com/JongSinister/WTFiber/models"
+	"github.com/JongSinister/WTFiber/tenant"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestClaimInvite(t *testing.T) {
+	invite := models.Invite{
+		ID:        primitive.NewObjectID(),
+		Email:     "niran@example.com",
+		Role:      "manager",
+		ExpiresAt: primitive.NewDateTimeFromTime(time.Now().Add(time.Hour)),
+	}
+
+	runWithMockDB(t, "single use", func(mt *mtest.T) {
+		// The claim only matches an unused invite, so once the first
+		// sign-up has set usedAt the second finds nothing
+		claimed := invite
+		claimed.UsedAt = primitive.NewDateTimeFromTime(time.Now())
+		mt.AddMockResponses(mockFindAndModify(mt, claimed), mockFindAndModify(mt, nil))
+
+		user := &models.User{ID: primitive.NewObjectID(), Email: "Niran@example.com"}
+		if _, err := claimInvite(context.Background(), "token", user); err != nil {
+			mt.Fatalf("first claim: %v", err)
+		}
+		if _, err := claimInvite(context.Background(), "token", user); err != mongo.ErrNoDocuments {
+			mt.Errorf("second claim: got %v, want no match", err)
+		}
+
+		for _, event := range mt.GetAllStartedEvents() {
+			query := event.Command.Lookup("query").Document()
+			if query.Lookup("hash").StringValue() != models.HashToken("token") {
+				mt.Error("the claim does not match on the token hash")
+			}
+			if _, err := query.LookupErr("usedAt", "$exists"); err != nil {
+				mt.Error("the claim does not require an unused invite")
+			}
+			if _, err := query.LookupErr("expiresAt", "$gt"); err != nil {
+				mt.Error("the claim does not require an unexpired invite")
+			}
+		}
+	})
+
+	runWithMockDB(t, "bound to an email", func(mt *mtest.T) {
+		mt.AddMockResponses(mockFindAndModify(mt, nil))
+
+		user := &models.User{ID: primitive.NewObjectID(), Email: "Somchai@Example.com"}
+		if _, err := claimInvite(context.Background(), "token", user); err != mongo.ErrNoDocuments {
+			mt.Errorf("got %v, want no match", err)
+		}
+
+		// Only invites without an email or for the registering address match
+		alternatives := mt.GetStartedEvent().Command.Lookup("query", "$or").Array()
+		values, err := alternatives.Values()
+		if err != nil || len(values) != 2 {
+			mt.Fatalf("$or: %v", alternatives)
+		}
+		if _, err := values[0].Document().LookupErr("email", "$exists"); err != nil {
+			mt.Errorf("first alternative %v, want an invite without an email", values[0])
+		}
+		if got := values[1].Document().Lookup("email").StringValue(); got != "somchai@example.com" {
+			mt.Errorf("second alternative matches %q, want the lower-cased address", got)
+		}
+	})
+}
+
+func TestCreateInviteRole(t *testing.T) {
+	withRoles(t, map[string][]string{
+		"support": {"users:invite", "hotels:read"},
+		"user":    {"hotels:read"},
+		"manager": {"hotels:read", "hotels:update:managed"},
+	})
+
+	tests := []struct {
+		name   string
+		role   string
+		status int
+	}{
+		{"role beyond the inviter", "manager", fiber.StatusForbidden},
+		{"unknown role", "owner", fiber.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		runWithMockDB(t, tt.name, func(mt *mtest.T) {
+			app := fiber.New()
+			app.Post("/admin/invites", func(c *fiber.Ctx) error {
+				c.Locals("user", jwt.MapClaims{"sub": primitive.NewObjectID().Hex(), "role": "support", "amr": []interface{}{"pwd"}})
+				c.Locals("tenant", tenant.Default)
+				return c.Next()
+			}, CreateInvite)
+
+			req := httptest.NewRequest("POST", "/admin/invites", strings.NewReader(`{"role":"`+tt.role+`"}`))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			resp, err := app.Test(req)
+			if err != nil {
+				mt.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				mt.Errorf("got %d, want %d", resp.StatusCode, tt.status)
+			}
+			if got := startedCommands(mt); len(got) != 0 {
+				mt.Errorf("sent %v, want nothing stored", got)
+			}
+		})
+	}
+}
+
+func TestRegisterInviteOrganization(t *testing.T) {
+	t.Setenv("ARGON2_MEMORY", "1024")
+	t.Setenv("ARGON2_TIME", "1")
+
+	header := primitive.NewObjectID()
+	tests := []struct {
+		name         string
+		organization primitive.ObjectID
+	}{
+		{"invite to another organization", primitive.NewObjectID()},
+		{"invite to the default tenant", primitive.NilObjectID},
+	}
+
+	for _, tt := range tests {
+		runWithMockDB(t, tt.name, func(mt *mtest.T) {
+			invite := models.Invite{ID: primitive.NewObjectID(), Role: "user", Organization: tt.organization}
+			mt.AddMockResponses(
+				mockCount(mt, userCollection, 0),
+				mockCount(mt, organizationCollection, 1),
+				mockFindAndModify(mt, invite),
+				// The insert fails so the test stops before tokens are issued;
+				// the claimed invite is then released
+				mtest.CreateWriteErrorsResponse(mtest.WriteError{Code: 2, Message: "stop"}),
+				mockWrite(1),
+			)
+
+			app := fiber.New()
+			app.Post("/auth/register", middleware.Tenant, Register)
+			req := httptest.NewRequest("POST", "/auth/register", strings.NewReader(
+				`{"name":"Niran","email":"niran@example.com","password":"Tangerine42!x","inviteToken":"token"}`))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			req.Header.Set(tenant.Header, header.Hex())
+			if _, err := app.Test(req); err != nil {
+				mt.Fatal(err)
+			}
+
+			want := []string{"aggregate", "aggregate", "findAndModify", "insert", "update"}
+			if got := startedCommands(mt); !reflect.DeepEqual(got, want) {
+				mt.Fatalf("sent %v, want %v", got, want)
+			}
+
+			// The new user joins the invite's organization, not the header's
+			inserted := mt.GetAllStartedEvents()[3].Command.Lookup("documents").Array().Index(0).Value().Document()
+			value, err := inserted.LookupErr("organization")
+			switch {
+			case tt.organization.IsZero() && err == nil:
+				mt.Errorf("user joined %v, want the default tenant", value)
+			case !tt.organization.IsZero() && (err != nil || value.ObjectID() != tt.organization):
+				mt.Errorf("user joined %v, want %s", value, tt.organization.Hex())
+			}
+
+			released := mt.GetAllStartedEvents()[4].Command.Lookup("updates").Array().Index(0).Value().Document()
+			if got := released.Lookup("q", "_id").ObjectID(); got != invite.ID {
+				mt.Errorf("released %s, want %s", got.Hex(), invite.ID.Hex())
+			}
+		})
+	}
+}
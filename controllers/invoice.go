@@ -0,0 +1,229 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/JongSinister/WTFiber/config"
+	"github.com/JongSinister/WTFiber/models"
+	"github.com/JongSinister/WTFiber/pdf"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// @desc    Download the invoice / booking confirmation of an appointment
+// @route   GET /api/v1/appointments/:id/invoice.pdf
+// @access  Private (owner or admin)
+func GetInvoice(c *fiber.Ctx) error {
+	// 1) Get the ID from the URL and convert it to an ObjectID
+	objectID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID Format"})
+	}
+
+	// 2) Fetch the appointment and check ownership
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	appointment := new(models.Appointment)
+	err = config.DB.Collection(appointmentCollection).FindOne(ctx, scoped(c, bson.M{"_id": objectID})).Decode(appointment)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Appointment not found"})
+	}
+
+	if !canAccess(c, "appointments:read", appointment.User) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	// 3) Fetch the hotel, the guest and the payments
+	hotel := new(models.Hotel)
+	err = config.DB.Collection(hotelCollection).FindOne(ctx, scoped(c, bson.M{"_id": appointment.Hotel})).Decode(hotel)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Hotel not found"})
+	}
+
+	guest := new(models.User)
+	err = config.DB.Collection(userCollection).FindOne(ctx, scoped(c, bson.M{"_id": appointment.User})).Decode(guest)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Guest not found"})
+	}
+
+	opts := options.Find().SetSort(bson.M{"createdAt": 1})
+	cursor, err := config.DB.Collection(paymentCollection).Find(ctx, bson.M{"appointment": appointment.ID}, opts)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching payments"})
+	}
+	defer cursor.Close(ctx)
+
+	var payments []models.Payment
+	if err := cursor.All(ctx, &payments); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching payments"})
+	}
+
+	// 4) The balance is worked out in the hotel's currency; payments in
+	// another one cannot be netted off against it
+	if mixedCurrency(payments, paymentCurrency()) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Appointment has payments in another currency"})
+	}
+
+	// 5) Render and send the PDF
+	doc := renderInvoice(appointment, hotel, guest, payments)
+
+	c.Set(fiber.HeaderContentType, "application/pdf")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`inline; filename="invoice-%s.pdf"`, appointment.ID.Hex()))
+	return c.Send(doc.Bytes())
+}
+
+// renderInvoice lays out a one-page invoice for the appointment
+func renderInvoice(appointment *models.Appointment, hotel *models.Hotel, guest *models.User, payments []models.Payment) *pdf.Document {
+	doc := pdf.New()
+	page := doc.AddPage()
+
+	const left, right = 50.0, pdf.PageWidth - 50
+	y := pdf.PageHeight - 60
+
+	currency := paymentCurrency()
+
+	// Header
+	page.Text(left, y, 20, true, "Invoice / Booking Confirmation")
+	y -= 22
+	page.Text(left, y, 10, false, "Invoice no. INV-"+appointment.ID.Hex())
+	y -= 14
+	page.Text(left, y, 10, false, "Issued "+time.Now().Format("02 Jan 2006"))
+	y -= 16
+	page.Line(left, y, right, y, 1)
+	y -= 24
+
+	// Hotel and guest details side by side
+	page.Text(left, y, 12, true, "Hotel")
+	page.Text(320, y, 12, true, "Guest")
+	y -= 16
+
+	hotelLines := []string{
+		hotel.Name,
+		hotel.Address,
+		hotel.District + ", " + hotel.Province + " " + hotel.PostalCode,
+		hotel.Region,
+	}
+	if hotel.Tel != "" {
+		hotelLines = append(hotelLines, "Tel. "+hotel.Tel)
+	}
+	guestLines := []string{guest.Name, guest.Email}
+	if guest.Tel != "" {
+		guestLines = append(guestLines, "Tel. "+guest.Tel)
+	}
+
+	for i := 0; i < max(len(hotelLines), len(guestLines)); i++ {
+		if i < len(hotelLines) {
+			page.Text(left, y, 10, false, hotelLines[i])
+		}
+		if i < len(guestLines) {
+			page.Text(320, y, 10, false, guestLines[i])
+		}
+		y -= 14
+	}
+	y -= 14
+
+	// Stay details
+	page.Text(left, y, 12, true, "Stay")
+	y -= 16
+	page.Text(left, y, 10, false, "Check-in: "+appointment.ApptDate.Format("Monday, 02 Jan 2006"))
+	y -= 14
+	page.Text(left, y, 10, false, "Booked on: "+appointment.CreatedAt.Time().Format("02 Jan 2006 15:04"))
+	y -= 28
+
+	// Price lines
+	page.Text(left, y, 12, true, "Description")
+	page.Text(420, y, 12, true, "Amount")
+	y -= 6
+	page.Line(left, y, right, y, 0.5)
+	y -= 16
+
+	var paid int64
+	total := hotel.Price
+	if hotel.Price > 0 {
+		page.Text(left, y, 10, false, "Room, 1 night")
+		page.Text(420, y, 10, false, formatAmount(hotel.Price, currency))
+		y -= 14
+	}
+
+	for _, p := range payments {
+		label := fmt.Sprintf("Deposit %s (%s)", p.CreatedAt.Time().Format("02 Jan 2006"), p.Status)
+		amount := formatAmount(p.Amount, p.Currency)
+		switch p.Status {
+		case models.PaymentPaid:
+			paid += p.Amount
+			amount = "-" + amount
+		case models.PaymentRefunded:
+			label += ", refunded " + p.RefundedAt.Time().Format("02 Jan 2006")
+		}
+		page.Text(left, y, 10, false, label)
+		page.Text(420, y, 10, false, amount)
+		y -= 14
+	}
+
+	y -= 2
+	page.Line(left, y, right, y, 0.5)
+	y -= 16
+
+	if total > 0 {
+		page.Text(left, y, 11, true, "Balance due")
+		page.Text(420, y, 11, true, formatAmount(max(total-paid, 0), currency))
+		y -= 24
+	}
+
+	// Payment status
+	page.Text(left, y, 12, true, "Payment status: "+invoiceStatus(payments, total, paid))
+
+	return doc
+}
+
+// mixedCurrency reports whether any paid payment is in another currency
+func mixedCurrency(payments []models.Payment, currency string) bool {
+	for _, p := range payments {
+		if p.Status == models.PaymentPaid && p.Currency != currency {
+			return true
+		}
+	}
+	return false
+}
+
+// invoiceStatus summarises the payments into a single status line
+func invoiceStatus(payments []models.Payment, total, paid int64) string {
+	switch {
+	case total > 0 && paid >= total:
+		return "Paid in full"
+	case paid > 0:
+		return "Deposit paid"
+	}
+
+	for _, p := range payments {
+		if p.Status == models.PaymentPending {
+			return "Awaiting payment"
+		}
+	}
+	for _, p := range payments {
+		if p.Status == models.PaymentRefunded {
+			return "Refunded"
+		}
+	}
+	return "Unpaid"
+}
+
+// formatAmount renders minor units as "1,234.50 THB"
+func formatAmount(amount int64, currency string) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	whole := fmt.Sprintf("%d", amount/100)
+	for i := len(whole) - 3; i > 0; i -= 3 {
+		whole = whole[:i] + "," + whole[i:]
+	}
+	return fmt.Sprintf("%s%s.%02d %s", sign, whole, amount%100, currency)
+}
@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/JongSinister/WTFiber/models"
+)
+
+func TestMixedCurrency(t *testing.T) {
+	tests := []struct {
+		name     string
+		payments []models.Payment
+		want     bool
+	}{
+		{"no payments", nil, false},
+		{"same currency", []models.Payment{{Status: models.PaymentPaid, Currency: "THB"}}, false},
+		{"paid in another currency", []models.Payment{
+			{Status: models.PaymentPaid, Currency: "THB"},
+			{Status: models.PaymentPaid, Currency: "USD"},
+		}, true},
+		{"failed payment in another currency", []models.Payment{{Status: models.PaymentFailed, Currency: "USD"}}, false},
+		{"refunded payment in another currency", []models.Payment{{Status: models.PaymentRefunded, Currency: "USD"}}, false},
+	}
+
+	for _, tt := range tests {
+		if got := mixedCurrency(tt.payments, "THB"); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		amount int64
+		want   string
+	}{
+		{0, "0.00 THB"},
+		{5, "0.05 THB"},
+		{123450, "1,234.50 THB"},
+		{100000000, "1,000,000.00 THB"},
+		{-50000, "-500.00 THB"},
+	}
+
+	for _, tt := range tests {
+		if got := formatAmount(tt.amount, "THB"); got != tt.want {
+			t.Errorf("formatAmount(%d) = %q, want %q", tt.amount, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package controllers
+
+import (
+	"github.com/JongSinister/WTFiber/auth"
+	"github.com/gofiber/fiber/v2"
+)
+
+// @desc    Publish the public keys used to sign tokens
+// @route   GET /.well-known/jwks.json
+// @access  Public
+func JWKS(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(fiber.Map{"keys": auth.JWKS()})
+}
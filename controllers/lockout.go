@@ -0,0 +1,241 @@
+package controllers
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/JongSinister/WTFiber/config"
+	"github.com/JongSinister/WTFiber/dto"
+	"github.com/JongSinister/WTFiber/models"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const loginAttemptCollection = "login_attempts"
+
+// @desc    List accounts and IPs with recent failed logins
+// @route   GET /api/v1/admin/lockouts
+// @access  Private (admin)
+func GetLockouts(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// 1) Admins of an organization only see the counters of its users; IP
+	// counters span tenants and are left to super-admins
+	filter := bson.M{}
+	if !tenantOf(c).SuperAdmin {
+		filter = scoped(c, bson.M{"user": bson.M{"$exists": true}})
+	}
+
+	// 2) Fetch and return the counters
+	opts := options.Find().SetSort(bson.M{"lastFailureAt": -1})
+	cursor, err := config.DB.Collection(loginAttemptCollection).Find(ctx, filter, opts)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching lockouts"})
+	}
+	defer cursor.Close(ctx)
+
+	var attempts []models.LoginAttempt
+	if err := cursor.All(ctx, &attempts); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching lockouts"})
+	}
+
+	now := time.Now()
+	result := make([]dto.LockoutResponse, 0, len(attempts))
+	for i := range attempts {
+		result = append(result, dto.NewLockout(&attempts[i], now))
+	}
+
+	return c.JSON(result)
+}
+
+// @desc    Unlock a user's account after failed logins
+// @route   DELETE /api/v1/admin/users/:id/lockout
+// @access  Private (admin)
+func UnlockUser(c *fiber.Ctx) error {
+	// 1) Get the ID from the URL and convert it to an ObjectID
+	objectID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID Format"})
+	}
+
+	// 2) Find the user's email; only users the admin could have given their
+	// role can be unlocked
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user := new(models.User)
+	if err := config.DB.Collection(userCollection).FindOne(ctx, scoped(c, bson.M{"_id": objectID})).Decode(user); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	if status, message := checkManageableUser(c, user); status != 0 {
+		return c.Status(status).JSON(fiber.Map{"error": message})
+	}
+
+	// 3) Drop the failure counters for passwords and second-factor codes
+	if err := clearAccountFailures(ctx, user); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error unlocking account"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Account unlocked successfully"})
+}
+
+// @desc    Unlock a client IP after failed logins
+// @route   DELETE /api/v1/admin/lockouts/ip/:ip
+// @access  Private (super-admin)
+func UnlockIP(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := clearLoginFailures(ctx, models.IPAttemptKey(c.Params("ip"))); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error unlocking IP"})
+	}
+
+	return c.JSON(fiber.Map{"message": "IP unlocked successfully"})
+}
+
+// loginWait returns how long a client must wait before it may try to log in
+// to the account again, or zero when it may try now
+func loginWait(ctx context.Context, email, ip string) (time.Duration, error) {
+	return attemptWait(ctx, models.ClientAttemptKey(email, ip), models.AccountAttemptKey(email), models.IPAttemptKey(ip))
+}
+
+// attemptWait returns how long until the counters with the given keys let
+// another attempt through, or zero when one may be made now
+func attemptWait(ctx context.Context, keys ...string) (time.Duration, error) {
+	cursor, err := config.DB.Collection(loginAttemptCollection).Find(ctx, bson.M{
+		"key": bson.M{"$in": keys},
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var attempts []models.LoginAttempt
+	if err := cursor.All(ctx, &attempts); err != nil {
+		return 0, err
+	}
+	return waitFor(attempts, time.Now()), nil
+}
+
+// waitFor returns how long from now until every counter lets another
+// attempt through
+func waitFor(attempts []models.LoginAttempt, now time.Time) time.Duration {
+	var wait time.Duration
+	for _, attempt := range attempts {
+		until := attempt.LockedUntil.Time()
+
+		// One client guessing at one account also backs off exponentially
+		// before the lockout kicks in. Accounts and IPs are shared with other
+		// clients, so they are only locked outright, at a higher limit.
+		if backsOff(attempt.Key) && attempt.Failures > 0 {
+			backoff := config.LoginBackoff() << min(attempt.Failures-1, 30)
+			if backoff <= 0 || backoff > config.LoginLockout() {
+				backoff = config.LoginLockout()
+			}
+			if next := attempt.LastFailureAt.Time().Add(backoff); next.After(until) {
+				until = next
+			}
+		}
+
+		if d := until.Sub(now); d > wait {
+			wait = d
+		}
+	}
+	return wait
+}
+
+// backsOff reports whether the counter belongs to a single client guessing a
+// secret, so that slowing it down does not hold up anyone else. Reset
+// requests are not guesses and are only capped.
+func backsOff(key string) bool {
+	kind, _, _ := strings.Cut(key, ":")
+	return kind == "client" || kind == "mfa"
+}
+
+// recordLoginFailure counts a failed login against the account from the
+// client IP, the account and the IP, locking each once it reaches its limit.
+// owner is the account behind the email, or nil when there is none.
+func recordLoginFailure(ctx context.Context, owner *models.User, email, ip string) error {
+	err := recordFailures(ctx, owner, map[string]int{
+		models.ClientAttemptKey(email, ip): config.LoginMaxAttempts(),
+		models.AccountAttemptKey(email):    config.LoginAccountMaxAttempts(),
+	})
+	if err != nil {
+		return err
+	}
+	return recordFailures(ctx, nil, map[string]int{models.IPAttemptKey(ip): config.LoginIPMaxAttempts()})
+}
+
+// recordFailures counts a failure against each key, locking a key once it
+// reaches its limit. The counters are filed under owner, when given, so the
+// admins of its organization can see them.
+func recordFailures(ctx context.Context, owner *models.User, limits map[string]int) error {
+	now := time.Now()
+	lockout := config.LoginLockout()
+	collection := config.DB.Collection(loginAttemptCollection)
+
+	set := bson.M{
+		"lastFailureAt": primitive.NewDateTimeFromTime(now),
+		"expiresAt":     primitive.NewDateTimeFromTime(now.Add(lockout)),
+	}
+	if owner != nil {
+		set["user"] = owner.ID
+		if !owner.Organization.IsZero() {
+			set["organization"] = owner.Organization
+		}
+	}
+
+	for key, limit := range limits {
+		attempt := new(models.LoginAttempt)
+		err := collection.FindOneAndUpdate(ctx,
+			bson.M{"key": key},
+			bson.M{"$inc": bson.M{"failures": 1}, "$set": set},
+			options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+		).Decode(attempt)
+		if err != nil {
+			return err
+		}
+
+		if attempt.Failures < limit {
+			continue
+		}
+
+		// Lock, and start counting afresh once the lock ends
+		lockedUntil := primitive.NewDateTimeFromTime(now.Add(lockout))
+		_, err = collection.UpdateOne(ctx,
+			bson.M{"_id": attempt.ID},
+			bson.M{"$set": bson.M{"failures": 0, "lockedUntil": lockedUntil, "expiresAt": lockedUntil}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clearLoginFailures removes the failure counters and any locks for the keys
+func clearLoginFailures(ctx context.Context, keys ...string) error {
+	_, err := config.DB.Collection(loginAttemptCollection).DeleteMany(ctx, bson.M{"key": bson.M{"$in": keys}})
+	return err
+}
+
+// clearAccountFailures removes every counter and lock on the user's account:
+// its own, those of each client IP that tried it, its second-factor codes and
+// its password reset requests
+func clearAccountFailures(ctx context.Context, user *models.User) error {
+	_, err := config.DB.Collection(loginAttemptCollection).DeleteMany(ctx, bson.M{"$or": accountFailureKeys(user)})
+	return err
+}
+
+// accountFailureKeys matches the counters clearAccountFailures removes
+func accountFailureKeys(user *models.User) bson.A {
+	return bson.A{
+		bson.M{"key": bson.M{"$in": bson.A{models.AccountAttemptKey(user.Email), models.MFAAttemptKey(user.ID), models.ResetAttemptKey(user.Email)}}},
+		bson.M{"key": bson.M{"$regex": "^" + regexp.QuoteMeta(models.ClientAttemptPrefix(user.Email))}},
+	}
+}
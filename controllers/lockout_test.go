@@ -0,0 +1,178 @@
+package controllers
+
+import (
+	"context"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JongSinister/WTFiber/models"
+	"github.com/JongSinister/WTFiber/tenant"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestWaitFor(t *testing.T) {
+	t.Setenv("LOGIN_BACKOFF", "1s")
+	t.Setenv("LOGIN_LOCKOUT", "15m")
+
+	now := time.Now().Truncate(time.Millisecond)
+	at := func(d time.Duration) primitive.DateTime { return primitive.NewDateTimeFromTime(now.Add(d)) }
+	userID := primitive.NewObjectID()
+
+	tests := []struct {
+		name     string
+		attempts []models.LoginAttempt
+		want     time.Duration
+	}{
+		{"no counters", nil, 0},
+		{"first client failure backs off 1s", []models.LoginAttempt{
+			{Key: models.ClientAttemptKey("a@b.c", "10.0.0.1"), Failures: 1, LastFailureAt: at(0)},
+		}, time.Second},
+		{"fourth client failure backs off 8s", []models.LoginAttempt{
+			{Key: models.ClientAttemptKey("a@b.c", "10.0.0.1"), Failures: 4, LastFailureAt: at(0)},
+		}, 8 * time.Second},
+		{"backoff is capped at the lockout", []models.LoginAttempt{
+			{Key: models.ClientAttemptKey("a@b.c", "10.0.0.1"), Failures: 40, LastFailureAt: at(0)},
+		}, 15 * time.Minute},
+		{"account failures from other IPs do not back off", []models.LoginAttempt{
+			{Key: models.AccountAttemptKey("a@b.c"), Failures: 10, LastFailureAt: at(0)},
+		}, 0},
+		{"locked account", []models.LoginAttempt{
+			{Key: models.AccountAttemptKey("a@b.c"), LockedUntil: at(15 * time.Minute)},
+		}, 15 * time.Minute},
+		{"wrong MFA codes back off like passwords", []models.LoginAttempt{
+			{Key: models.MFAAttemptKey(userID), Failures: 3, LastFailureAt: at(0)},
+		}, 4 * time.Second},
+		{"IP failures do not back off", []models.LoginAttempt{
+			{Key: models.IPAttemptKey("10.0.0.1"), Failures: 10, LastFailureAt: at(0)},
+		}, 0},
+		{"locked IP", []models.LoginAttempt{
+			{Key: models.IPAttemptKey("10.0.0.1"), LockedUntil: at(10 * time.Minute)},
+		}, 10 * time.Minute},
+		{"expired lock", []models.LoginAttempt{
+			{Key: models.MFAAttemptKey(userID), LockedUntil: at(-time.Minute)},
+		}, 0},
+		{"longest wait wins", []models.LoginAttempt{
+			{Key: models.ClientAttemptKey("a@b.c", "10.0.0.1"), Failures: 1, LastFailureAt: at(0)},
+			{Key: models.MFAAttemptKey(userID), LockedUntil: at(5 * time.Minute)},
+		}, 5 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := waitFor(tt.attempts, now); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestClientAttemptKey(t *testing.T) {
+	key := models.ClientAttemptKey(" Guest@Example.com ", "10.0.0.1")
+	if key != "client:guest@example.com|10.0.0.1" {
+		t.Errorf("got %q", key)
+	}
+	if !strings.HasPrefix(key, models.ClientAttemptPrefix("guest@example.com")) {
+		t.Errorf("%q does not start with the account's client prefix", key)
+	}
+	if strings.HasPrefix(models.ClientAttemptKey("guest@example.com.evil", "10.0.0.1"), models.ClientAttemptPrefix("guest@example.com")) {
+		t.Error("prefix matches another account's counters")
+	}
+}
+
+func TestGetLockoutsScope(t *testing.T) {
+	org := primitive.NewObjectID()
+
+	tests := []struct {
+		name  string
+		scope tenant.Scope
+		want  bson.D
+	}{
+		{"organization admin", tenant.Of(org), bson.D{
+			{Key: "organization", Value: org},
+			{Key: "user", Value: bson.D{{Key: "$exists", Value: true}}},
+		}},
+		{"default tenant admin", tenant.Default, bson.D{
+			{Key: "organization", Value: bson.D{{Key: "$exists", Value: false}}},
+			{Key: "user", Value: bson.D{{Key: "$exists", Value: true}}},
+		}},
+		{"super-admin", tenant.Scope{All: true, SuperAdmin: true}, bson.D{}},
+	}
+
+	for _, tt := range tests {
+		runWithMockDB(t, tt.name, func(mt *mtest.T) {
+			mt.AddMockResponses(mockFind(mt, loginAttemptCollection))
+
+			app := fiber.New()
+			app.Get("/admin/lockouts", func(c *fiber.Ctx) error {
+				c.Locals("tenant", tt.scope)
+				return c.Next()
+			}, GetLockouts)
+			resp, err := app.Test(httptest.NewRequest("GET", "/admin/lockouts", nil))
+			if err != nil {
+				mt.Fatal(err)
+			}
+			if resp.StatusCode != fiber.StatusOK {
+				mt.Fatalf("got %d, want 200", resp.StatusCode)
+			}
+
+			// A single query on the stored organization, whatever the
+			// number of users
+			if got := startedCommands(mt); !reflect.DeepEqual(got, []string{"find"}) {
+				mt.Fatalf("sent %v, want one find", got)
+			}
+			var filter bson.D
+			if err := bson.Unmarshal(mt.GetStartedEvent().Command.Lookup("filter").Document(), &filter); err != nil {
+				mt.Fatal(err)
+			}
+			sort.Slice(filter, func(i, j int) bool { return filter[i].Key < filter[j].Key })
+			if !reflect.DeepEqual(filter, tt.want) {
+				mt.Errorf("filter %v, want %v", filter, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordLoginFailureOwner(t *testing.T) {
+	owner := &models.User{ID: primitive.NewObjectID(), Email: "guest@example.com", Organization: primitive.NewObjectID()}
+	counted := models.LoginAttempt{ID: primitive.NewObjectID(), Failures: 1}
+
+	tests := []struct {
+		name  string
+		owner *models.User
+	}{
+		{"known account", owner},
+		{"unknown address", nil},
+	}
+
+	for _, tt := range tests {
+		runWithMockDB(t, tt.name, func(mt *mtest.T) {
+			mt.AddMockResponses(mockFindAndModify(mt, counted), mockFindAndModify(mt, counted), mockFindAndModify(mt, counted))
+
+			if err := recordLoginFailure(context.Background(), tt.owner, owner.Email, "10.0.0.1"); err != nil {
+				mt.Fatal(err)
+			}
+
+			for _, event := range mt.GetAllStartedEvents() {
+				key := event.Command.Lookup("query", "key").StringValue()
+				set := event.Command.Lookup("update", "$set").Document()
+				user, hasUser := set.Lookup("user").ObjectIDOK()
+				organization, hasOrganization := set.Lookup("organization").ObjectIDOK()
+
+				// Only the account's own counters are filed under it; the
+				// IP counter is shared by every tenant
+				wantOwner := tt.owner != nil && key != models.IPAttemptKey("10.0.0.1")
+				if hasUser != wantOwner || hasOrganization != wantOwner {
+					mt.Errorf("%s: user %v, organization %v, want them set: %v", key, hasUser, hasOrganization, wantOwner)
+				}
+				if wantOwner && (user != owner.ID || organization != owner.Organization) {
+					mt.Errorf("%s: filed under %s in %s", key, user.Hex(), organization.Hex())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,470 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/JongSinister/WTFiber/auth"
+	"github.com/JongSinister/WTFiber/config"
+	"github.com/JongSinister/WTFiber/models"
+	"github.com/JongSinister/WTFiber/revocation"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/skip2/go-qrcode"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// An MFA challenge is valid for a few minutes. Wrong codes are counted
+// against the account, not the challenge, so logging in again does not buy
+// more guesses.
+const (
+	mfaChallengeTTL   = 5 * time.Minute
+	recoveryCodeCount = 10
+)
+
+// @desc    Complete a login with a TOTP or recovery code
+// @route   POST /api/v1/auth/login/mfa
+// @access  Public (MFA challenge token)
+func LoginMFA(c *fiber.Ctx) error {
+	// 1) Parse the request body
+	body := struct {
+		MFAToken     string `json:"mfaToken"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recoveryCode"`
+	}{}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request format"})
+	}
+
+	// 2) Verify the challenge issued by Login
+	claims, err := auth.Parse(body.MFAToken, mfaAudience())
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired MFA challenge"})
+	}
+
+	jti, _ := claims["jti"].(string)
+	if revocation.IsRevoked(jti, "", "", time.Time{}) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired MFA challenge"})
+	}
+
+	userID, err := primitive.ObjectIDFromHex(claims["sub"].(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired MFA challenge"})
+	}
+
+	// 3) Check the second factor
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user := new(models.User)
+	err = config.DB.Collection(userCollection).FindOne(ctx, bson.M{"_id": userID}).Decode(user)
+	if err != nil || !user.MFAEnabled {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired MFA challenge"})
+	}
+
+	if status, msg := confirmSecondFactor(ctx, c, user, body.Code, body.RecoveryCode); status != 0 {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+
+	// 4) A challenge completes a single login
+	exp, _ := claims["exp"].(float64)
+	if err := revocation.Revoke(ctx, models.RevokeToken, jti, time.Unix(int64(exp), 0)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error revoking challenge"})
+	}
+
+	// 5) Generate the tokens and return the response
+	amr := []string{}
+	if methods, ok := claims["amr"].([]interface{}); ok {
+		for _, method := range methods {
+			if m, ok := method.(string); ok {
+				amr = append(amr, m)
+			}
+		}
+	}
+	return sendTokens(c, fiber.StatusOK, user, append(amr, "otp"))
+}
+
+// @desc    Start TOTP enrollment
+// @route   POST /api/v1/auth/mfa/enroll
+// @access  Private
+func EnrollMFA(c *fiber.Ctx) error {
+	// 1) Fetch the current user
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, status, msg := findCurrentUser(ctx, c)
+	if user == nil {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+
+	if user.MFAEnabled {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "MFA is already enabled"})
+	}
+
+	// 2) Generate a secret and keep it pending until a code confirms it
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error generating secret"})
+	}
+
+	sealed, err := auth.SealSecret(secret)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error generating secret"})
+	}
+
+	_, err = config.DB.Collection(userCollection).UpdateOne(ctx,
+		bson.M{"_id": user.ID},
+		bson.M{"$set": bson.M{"mfaPendingSecret": sealed}},
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error saving secret"})
+	}
+
+	// 3) Return the secret, the otpauth URI and the QR code
+	uri := auth.TOTPURI(user.Email, secret)
+	png, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error generating QR code"})
+	}
+
+	return c.JSON(fiber.Map{
+		"secret":     secret,
+		"otpauthUri": uri,
+		"qrCode":     "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	})
+}
+
+// @desc    Get the QR code of the pending TOTP enrollment
+// @route   GET /api/v1/auth/mfa/qr.png
+// @access  Private
+func MFAQRCode(c *fiber.Ctx) error {
+	// 1) Fetch the current user
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, status, msg := findCurrentUser(ctx, c)
+	if user == nil {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+
+	if user.MFAPendingSecret == "" {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "No MFA enrollment in progress"})
+	}
+
+	// 2) Render the QR code
+	secret, err := auth.OpenSecret(user.MFAPendingSecret)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error reading secret"})
+	}
+
+	png, err := qrcode.Encode(auth.TOTPURI(user.Email, secret), qrcode.Medium, 256)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error generating QR code"})
+	}
+
+	c.Set(fiber.HeaderContentType, "image/png")
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.SendStream(bytes.NewReader(png), len(png))
+}
+
+// @desc    Confirm TOTP enrollment with a first code
+// @route   POST /api/v1/auth/mfa/verify
+// @access  Private
+func VerifyMFA(c *fiber.Ctx) error {
+	// 1) Parse the request body
+	body := struct {
+		Code string `json:"code"`
+	}{}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request format"})
+	}
+
+	// 2) Fetch the current user
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, status, msg := findCurrentUser(ctx, c)
+	if user == nil {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+
+	if user.MFAPendingSecret == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "No MFA enrollment in progress"})
+	}
+
+	// 3) Check the code against the pending secret
+	secret, err := auth.OpenSecret(user.MFAPendingSecret)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error reading secret"})
+	}
+
+	step, ok := auth.ValidateTOTP(secret, body.Code, 0)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid code"})
+	}
+
+	// 4) Enable MFA with a fresh set of recovery codes
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error generating recovery codes"})
+	}
+
+	_, err = config.DB.Collection(userCollection).UpdateOne(ctx,
+		bson.M{"_id": user.ID},
+		bson.M{
+			"$set": bson.M{
+				"mfaEnabled":       true,
+				"mfaSecret":        user.MFAPendingSecret,
+				"mfaRecoveryCodes": hashes,
+				"mfaLastStep":      step,
+			},
+			"$unset": bson.M{"mfaPendingSecret": ""},
+		},
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error enabling MFA"})
+	}
+
+	// 5) Return the recovery codes; they are never shown again
+	return c.JSON(fiber.Map{
+		"success":       true,
+		"message":       "MFA enabled, log in again to start a session with a second factor",
+		"recoveryCodes": codes,
+	})
+}
+
+// @desc    Replace the recovery codes
+// @route   POST /api/v1/auth/mfa/recovery-codes
+// @access  Private
+func RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	// 1) Parse the request body
+	body := struct {
+		Code string `json:"code"`
+	}{}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request format"})
+	}
+
+	// 2) Fetch the current user and check the code
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, status, msg := findCurrentUser(ctx, c)
+	if user == nil {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+
+	if !user.MFAEnabled {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "MFA is not enabled"})
+	}
+
+	if status, msg := confirmSecondFactor(ctx, c, user, body.Code, ""); status != 0 {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+
+	// 3) Store the new codes
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error generating recovery codes"})
+	}
+
+	_, err = config.DB.Collection(userCollection).UpdateOne(ctx,
+		bson.M{"_id": user.ID},
+		bson.M{"$set": bson.M{"mfaRecoveryCodes": hashes}},
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error saving recovery codes"})
+	}
+
+	return c.JSON(fiber.Map{"success": true, "recoveryCodes": codes})
+}
+
+// @desc    Turn off TOTP
+// @route   POST /api/v1/auth/mfa/disable
+// @access  Private
+func DisableMFA(c *fiber.Ctx) error {
+	// 1) Parse the request body
+	body := struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}{}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request format"})
+	}
+
+	// 2) Fetch the current user and check both factors
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, status, msg := findCurrentUser(ctx, c)
+	if user == nil {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+
+	if !user.MFAEnabled {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "MFA is not enabled"})
+	}
+
+	if status, msg := confirmIdentity(ctx, c, user, body.Password, "Invalid password"); status != 0 {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+
+	if status, msg := confirmSecondFactor(ctx, c, user, body.Code, ""); status != 0 {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+
+	// 3) Remove the secret and the recovery codes
+	_, err := config.DB.Collection(userCollection).UpdateOne(ctx,
+		bson.M{"_id": user.ID},
+		bson.M{
+			"$set": bson.M{"mfaEnabled": false},
+			"$unset": bson.M{
+				"mfaSecret":        "",
+				"mfaPendingSecret": "",
+				"mfaRecoveryCodes": "",
+				"mfaLastStep":      "",
+			},
+		},
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error disabling MFA"})
+	}
+
+	return c.JSON(fiber.Map{"success": true, "message": "MFA disabled"})
+}
+
+// loginResponse finishes a first-factor login made with the given method:
+// users with MFA get a challenge token for /auth/login/mfa, everyone else
+// gets a session
+func loginResponse(c *fiber.Ctx, user *models.User, method string) error {
+	if !user.IsActive() {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Account is disabled"})
+	}
+
+	if !user.MFAEnabled {
+		return sendTokens(c, fiber.StatusOK, user, []string{method})
+	}
+
+	mfaToken, err := auth.Sign(jwt.MapClaims{
+		"sub": user.ID.Hex(),
+		"aud": mfaAudience(),
+		"jti": primitive.NewObjectID().Hex(),
+		"amr": []string{method},
+	}, mfaChallengeTTL)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error generating token"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success":     true,
+		"mfaRequired": true,
+		"mfaToken":    mfaToken,
+	})
+}
+
+// confirmSecondFactor checks a TOTP or recovery code for the user. Wrong
+// codes are counted and lock the account's second factor once they reach the
+// login limit, wherever they are typed. It returns the HTTP status and
+// message on failure.
+func confirmSecondFactor(ctx context.Context, c *fiber.Ctx, user *models.User, code, recoveryCode string) (int, string) {
+	attemptKey := models.MFAAttemptKey(user.ID)
+	wait, err := attemptWait(ctx, attemptKey)
+	if err != nil {
+		return fiber.StatusInternalServerError, "Error checking login attempts"
+	}
+	if wait > 0 {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		return fiber.StatusTooManyRequests, "Too many invalid codes, try again later"
+	}
+
+	ok, err := checkSecondFactor(ctx, user, code, recoveryCode)
+	if err != nil {
+		return fiber.StatusInternalServerError, "Error checking code"
+	}
+	if !ok {
+		if err := recordFailures(ctx, user, map[string]int{attemptKey: config.LoginMaxAttempts()}); err != nil {
+			log.Printf("Error recording invalid MFA code: %v", err)
+		}
+		return fiber.StatusUnauthorized, "Invalid code"
+	}
+	if err := clearLoginFailures(ctx, attemptKey); err != nil {
+		log.Printf("Error clearing invalid MFA codes: %v", err)
+	}
+	return 0, ""
+}
+
+// checkSecondFactor accepts either a TOTP code or an unused recovery code.
+// Both are consumed atomically so they cannot be used twice.
+func checkSecondFactor(ctx context.Context, user *models.User, code, recoveryCode string) (bool, error) {
+	if recoveryCode != "" {
+		hash := models.HashToken(recoveryCode)
+		res, err := config.DB.Collection(userCollection).UpdateOne(ctx,
+			bson.M{"_id": user.ID, "mfaRecoveryCodes": hash},
+			bson.M{"$pull": bson.M{"mfaRecoveryCodes": hash}},
+		)
+		if err != nil {
+			return false, err
+		}
+		return res.ModifiedCount == 1, nil
+	}
+
+	secret, err := auth.OpenSecret(user.MFASecret)
+	if err != nil {
+		return false, err
+	}
+
+	step, ok := auth.ValidateTOTP(secret, code, user.MFALastStep)
+	if !ok {
+		return false, nil
+	}
+
+	res, err := config.DB.Collection(userCollection).UpdateOne(ctx,
+		bson.M{"_id": user.ID, "mfaLastStep": bson.M{"$not": bson.M{"$gte": step}}},
+		bson.M{"$set": bson.M{"mfaLastStep": step}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// newRecoveryCodes returns fresh recovery codes and the hashes to store
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := auth.NewRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = models.HashToken(code)
+	}
+	return codes, hashes, nil
+}
+
+// findCurrentUser loads the authenticated user. It returns the HTTP status
+// and message on failure.
+func findCurrentUser(ctx context.Context, c *fiber.Ctx) (*models.User, int, string) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return nil, fiber.StatusInternalServerError, "Error parsing claims"
+	}
+
+	user := new(models.User)
+	err := config.DB.Collection(userCollection).FindOne(ctx, bson.M{"_id": userID}).Decode(user)
+	if err != nil {
+		return nil, fiber.StatusNotFound, "User not found"
+	}
+	return user, 0, ""
+}
+
+// mfaAudience keeps MFA challenges from being used as access tokens
+func mfaAudience() string {
+	return auth.Issuer() + "/mfa"
+}
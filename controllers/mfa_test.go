@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/JongSinister/WTFiber/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestConfirmSecondFactor(t *testing.T) {
+	t.Setenv("LOGIN_LOCKOUT", "15m")
+
+	user := &models.User{ID: primitive.NewObjectID(), MFAEnabled: true}
+	locked := models.LoginAttempt{
+		Key:         models.MFAAttemptKey(user.ID),
+		LockedUntil: primitive.NewDateTimeFromTime(time.Now().Add(time.Minute)),
+	}
+	counted := models.LoginAttempt{ID: primitive.NewObjectID(), Key: models.MFAAttemptKey(user.ID), Failures: 1}
+
+	tests := []struct {
+		name      string
+		responses func(mt *mtest.T) []bson.D
+		status    int
+		commands  []string
+	}{
+		{"valid code clears the counter", func(mt *mtest.T) []bson.D {
+			return []bson.D{mockFind(mt, loginAttemptCollection), mockWrite(1), mockWrite(1)}
+		}, 0, []string{"find", "update", "delete"}},
+		{"invalid code is counted", func(mt *mtest.T) []bson.D {
+			return []bson.D{mockFind(mt, loginAttemptCollection), mockWrite(0), mockFindAndModify(mt, counted)}
+		}, fiber.StatusUnauthorized, []string{"find", "update", "findAndModify"}},
+		{"locked second factor is not checked", func(mt *mtest.T) []bson.D {
+			return []bson.D{mockFind(mt, loginAttemptCollection, locked)}
+		}, fiber.StatusTooManyRequests, []string{"find"}},
+	}
+
+	app := fiber.New()
+	for _, tt := range tests {
+		runWithMockDB(t, tt.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tt.responses(mt)...)
+			c := app.AcquireCtx(&fasthttp.RequestCtx{})
+			defer app.ReleaseCtx(c)
+
+			if status, _ := confirmSecondFactor(context.Background(), c, user, "", "recovery-code"); status != tt.status {
+				mt.Errorf("got %d, want %d", status, tt.status)
+			}
+			if got := startedCommands(mt); !reflect.DeepEqual(got, tt.commands) {
+				mt.Errorf("sent %v, want %v", got, tt.commands)
+			}
+			if tt.status == fiber.StatusTooManyRequests && c.GetRespHeader(fiber.HeaderRetryAfter) == "" {
+				mt.Error("no Retry-After header")
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/JongSinister/WTFiber/config"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// runWithMockDB runs fn in a subtest whose config.DB answers from the
+// responses queued with mt.AddMockResponses, in order
+func runWithMockDB(t *testing.T, name string, fn func(mt *mtest.T)) {
+	t.Helper()
+	mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock)).Run(name, func(mt *mtest.T) {
+		saved := config.DB
+		config.DB = mt.DB
+		mt.Cleanup(func() { config.DB = saved })
+		fn(mt)
+	})
+}
+
+// mockDocs converts records to the documents a mock response carries
+func mockDocs(t testing.TB, records ...interface{}) []bson.D {
+	t.Helper()
+	docs := make([]bson.D, 0, len(records))
+	for _, record := range records {
+		raw, err := bson.Marshal(record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var doc bson.D
+		if err := bson.Unmarshal(raw, &doc); err != nil {
+			t.Fatal(err)
+		}
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+// mockFind answers a find or aggregate with the records
+func mockFind(mt *mtest.T, collection string, records ...interface{}) bson.D {
+	return mtest.CreateCursorResponse(0, mt.DB.Name()+"."+collection, mtest.FirstBatch, mockDocs(mt, records...)...)
+}
+
+// mockCount answers a CountDocuments
+func mockCount(mt *mtest.T, collection string, n int) bson.D {
+	if n == 0 {
+		return mockFind(mt, collection)
+	}
+	return mockFind(mt, collection, bson.M{"_id": 1, "n": n})
+}
+
+// mockFindAndModify answers a FindOneAndUpdate or FindOneAndDelete with the
+// record, or with no match when it is nil
+func mockFindAndModify(mt *mtest.T, record interface{}) bson.D {
+	if record == nil {
+		return mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil})
+	}
+	return mtest.CreateSuccessResponse(bson.E{Key: "value", Value: mockDocs(mt, record)[0]})
+}
+
+// mockWrite answers an insert, update or delete that touched n documents
+func mockWrite(n int) bson.D {
+	return mtest.CreateSuccessResponse(bson.E{Key: "n", Value: n}, bson.E{Key: "nModified", Value: n})
+}
+
+// startedCommands returns the names of the commands sent, in order
+func startedCommands(mt *mtest.T) []string {
+	var names []string
+	for _, event := range mt.GetAllStartedEvents() {
+		names = append(names, event.CommandName)
+	}
+	return names
+}
@@ -0,0 +1,216 @@
+package controllers
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/JongSinister/WTFiber/config"
+	"github.com/JongSinister/WTFiber/models"
+	"github.com/JongSinister/WTFiber/oidc"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const identityCollection = "identities"
+const oidcStateCollection = "oidc_states"
+
+// How long the user has to finish signing in at the provider
+const oidcStateTTL = 10 * time.Minute
+
+// @desc    Redirect to an identity provider to sign in
+// @route   GET /api/v1/auth/oidc/:provider/login
+// @access  Public
+func OIDCLogin(c *fiber.Ctx) error {
+	// 1) Find the provider
+	provider, ok := oidc.Get(c.Params("provider"))
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Unknown identity provider"})
+	}
+
+	// 2) Generate the state, nonce and PKCE pair
+	state, errState := oidc.RandomString(24)
+	nonce, errNonce := oidc.RandomString(24)
+	verifier, challenge, errPKCE := oidc.NewPKCE()
+	if errState != nil || errNonce != nil || errPKCE != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error starting sign in"})
+	}
+
+	// 3) Remember them until the callback
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := config.DB.Collection(oidcStateCollection).InsertOne(ctx, models.OIDCState{
+		State:     state,
+		Provider:  provider.Name,
+		Nonce:     nonce,
+		Verifier:  verifier,
+		ExpiresAt: primitive.NewDateTimeFromTime(time.Now().Add(oidcStateTTL)),
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error starting sign in"})
+	}
+
+	// 4) Redirect to the provider
+	extra := url.Values{}
+	if hint := c.Query("login_hint"); hint != "" {
+		extra.Set("login_hint", hint)
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, challenge, extra)
+	if err != nil {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "Identity provider unavailable"})
+	}
+
+	return c.Redirect(authURL, fiber.StatusFound)
+}
+
+// @desc    Finish signing in with an identity provider
+// @route   GET /api/v1/auth/oidc/:provider/callback
+// @access  Public
+func OIDCCallback(c *fiber.Ctx) error {
+	// 1) Find the provider and report errors it sent back
+	provider, ok := oidc.Get(c.Params("provider"))
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Unknown identity provider"})
+	}
+
+	if providerErr := c.Query("error"); providerErr != "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Sign in failed: " + providerErr})
+	}
+
+	// 2) Consume the state we stored before the redirect
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	state := new(models.OIDCState)
+	err := config.DB.Collection(oidcStateCollection).FindOneAndDelete(ctx, bson.M{
+		"state":     c.Query("state"),
+		"provider":  provider.Name,
+		"expiresAt": bson.M{"$gt": primitive.NewDateTimeFromTime(time.Now())},
+	}).Decode(state)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired sign in attempt"})
+	}
+
+	// 3) Exchange the code and verify the ID token
+	rawIDToken, err := provider.Exchange(ctx, c.Query("code"), state.Verifier)
+	if err != nil {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "Error exchanging authorization code"})
+	}
+
+	claims, err := provider.VerifyIDToken(ctx, rawIDToken, state.Nonce)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid ID token"})
+	}
+
+	// 4) Find or create the linked user
+	user, status, msg := userForIdentity(ctx, provider.Name, claims)
+	if user == nil {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+
+	// 5) Generate the tokens and return the response
+	return loginResponse(c, user, "oidc")
+}
+
+// userForIdentity returns the user linked to the provider account. Accounts
+// are linked to an existing user only by an email both sides verified;
+// otherwise a new user is created. It returns the HTTP status and message on failure.
+func userForIdentity(ctx context.Context, provider string, claims *oidc.Claims) (*models.User, int, string) {
+	// 1) Already linked
+	identity := new(models.Identity)
+	err := config.DB.Collection(identityCollection).FindOne(ctx, bson.M{"provider": provider, "subject": claims.Subject}).Decode(identity)
+	if err == nil {
+		user := new(models.User)
+		if err := config.DB.Collection(userCollection).FindOne(ctx, bson.M{"_id": identity.User}).Decode(user); err != nil {
+			return nil, fiber.StatusUnauthorized, "Linked user not found"
+		}
+		return user, 0, ""
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, fiber.StatusInternalServerError, "Error fetching identity"
+	}
+
+	if claims.Email == "" {
+		return nil, fiber.StatusBadRequest, "The identity provider did not share an email address"
+	}
+
+	// 2) Link to an existing user with the same email, when both the provider
+	// and this API verified it
+	user := new(models.User)
+	err = config.DB.Collection(userCollection).FindOne(ctx, bson.M{"email": claims.Email}).Decode(user)
+	switch {
+	case err == nil && !canAutoLink(user, claims):
+		return nil, fiber.StatusConflict, "Email already registered, log in with your password"
+	case err == mongo.ErrNoDocuments:
+		// 3) Otherwise create a new user with an unusable password
+		user, err = createIdentityUser(ctx, claims)
+		if err != nil {
+			return nil, fiber.StatusInternalServerError, "Error creating user"
+		}
+	case err != nil:
+		return nil, fiber.StatusInternalServerError, "Error fetching user"
+	}
+
+	_, err = config.DB.Collection(identityCollection).InsertOne(ctx, models.Identity{
+		Provider:  provider,
+		Subject:   claims.Subject,
+		User:      user.ID,
+		Email:     claims.Email,
+		CreatedAt: primitive.NewDateTimeFromTime(time.Now()),
+	})
+	if err != nil {
+		return nil, fiber.StatusInternalServerError, "Error linking identity"
+	}
+
+	return user, 0, ""
+}
+
+// canAutoLink reports whether a provider account may be linked to the
+// existing user with its email. Both sides must have verified the address:
+// otherwise whoever registered the email first, without owning it, would
+// keep a password login to the account the owner signs in to.
+func canAutoLink(user *models.User, claims *oidc.Claims) bool {
+	return claims.EmailVerified && user.EmailVerified
+}
+
+// createIdentityUser registers a user from the provider's claims
+func createIdentityUser(ctx context.Context, claims *oidc.Claims) (*models.User, error) {
+	password, err := models.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	name := claims.Name
+	if name == "" {
+		name = claims.Email
+	}
+
+	now := time.Now()
+	user := &models.User{
+		Name:          name,
+		Email:         claims.Email,
+		Role:          "user",
+		Password:      password,
+		NoPassword:    true,
+		CreatedAt:     primitive.NewDateTimeFromTime(now),
+		EmailVerified: claims.EmailVerified,
+	}
+	if claims.EmailVerified {
+		user.EmailVerifiedAt = primitive.NewDateTimeFromTime(now)
+	}
+
+	if err := user.HashPassword(); err != nil {
+		return nil, err
+	}
+
+	res, err := config.DB.Collection(userCollection).InsertOne(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	user.ID = res.InsertedID.(primitive.ObjectID)
+	return user, nil
+}
@@ -0,0 +1,29 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/JongSinister/WTFiber/models"
+	"github.com/JongSinister/WTFiber/oidc"
+)
+
+func TestCanAutoLink(t *testing.T) {
+	tests := []struct {
+		name          string
+		local, remote bool
+		want          bool
+	}{
+		{"both verified", true, true, true},
+		{"local account unverified", false, true, false},
+		{"provider email unverified", true, false, false},
+		{"neither verified", false, false, false},
+	}
+
+	for _, tt := range tests {
+		user := &models.User{EmailVerified: tt.local}
+		claims := &oidc.Claims{Email: "guest@example.com", EmailVerified: tt.remote}
+		if got := canAutoLink(user, claims); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/JongSinister/WTFiber/config"
+	"github.com/JongSinister/WTFiber/dto"
+	"github.com/JongSinister/WTFiber/middleware"
+	"github.com/JongSinister/WTFiber/models"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const organizationCollection = "organizations"
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// @desc    List organizations
+// @route   GET /api/v1/organizations
+// @access  Private (super-admin)
+func GetOrganizations(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.M{"name": 1})
+	cursor, err := config.DB.Collection(organizationCollection).Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching organizations"})
+	}
+	defer cursor.Close(ctx)
+
+	var organizations []models.Organization
+	if err := cursor.All(ctx, &organizations); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching organizations"})
+	}
+
+	return c.JSON(dto.List(organizations, dto.NewOrganization))
+}
+
+// @desc    Get an organization
+// @route   GET /api/v1/organizations/:id
+// @access  Private (super-admin)
+func GetOrganization(c *fiber.Ctx) error {
+	// 1) Get the ID from the URL and convert it to an ObjectID
+	objectID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID Format"})
+	}
+
+	// 2) Fetch the organization
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	organization := new(models.Organization)
+	if err := config.DB.Collection(organizationCollection).FindOne(ctx, bson.M{"_id": objectID}).Decode(organization); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Organization not found"})
+	}
+
+	return c.JSON(dto.NewOrganization(organization))
+}
+
+// @desc    Create an organization
+// @route   POST /api/v1/organizations
+// @access  Private (super-admin)
+func CreateOrganization(c *fiber.Ctx) error {
+	// 1) Parse and validate the request body
+	body := struct {
+		Name string `json:"name"`
+		Slug string `json:"slug"`
+	}{}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request format"})
+	}
+
+	body.Name = strings.TrimSpace(body.Name)
+	body.Slug = strings.ToLower(strings.TrimSpace(body.Slug))
+	if body.Name == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Name is required"})
+	}
+	if !slugPattern.MatchString(body.Slug) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Slug must be lowercase letters, digits and dashes"})
+	}
+
+	// 2) Store the organization; the slug is unique
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	organization := &models.Organization{
+		ID:        primitive.NewObjectID(),
+		Name:      body.Name,
+		Slug:      body.Slug,
+		CreatedAt: primitive.NewDateTimeFromTime(time.Now()),
+	}
+	if _, err := config.DB.Collection(organizationCollection).InsertOne(ctx, organization); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Slug already taken"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error creating organization"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(dto.NewOrganization(organization))
+}
+
+// @desc    Rename an organization
+// @route   PUT /api/v1/organizations/:id
+// @access  Private (super-admin)
+func UpdateOrganization(c *fiber.Ctx) error {
+	// 1) Get the ID from the URL and convert it to an ObjectID
+	objectID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID Format"})
+	}
+
+	// 2) Parse the new name
+	body := struct {
+		Name string `json:"name"`
+	}{}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request format"})
+	}
+	if body.Name = strings.TrimSpace(body.Name); body.Name == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Name is required"})
+	}
+
+	// 3) Update the organization
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	organization := new(models.Organization)
+	err = config.DB.Collection(organizationCollection).FindOneAndUpdate(ctx,
+		bson.M{"_id": objectID},
+		bson.M{"$set": bson.M{"name": body.Name}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(organization)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Organization not found"})
+	}
+
+	return c.JSON(dto.NewOrganization(organization))
+}
+
+// @desc    Move a user to an organization, or to the default tenant
+// @route   PUT /api/v1/users/:id/organization
+// @access  Private (super-admin)
+func SetUserOrganization(c *fiber.Ctx) error {
+	// 1) Get the user ID from the URL and the organization from the body
+	objectID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID Format"})
+	}
+	if isCurrentUser(c, objectID) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "You cannot change your own account here"})
+	}
+
+	body := struct {
+		Organization string `json:"organization"`
+	}{}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request format"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	update := bson.M{"$unset": bson.M{"organization": ""}}
+	if body.Organization != "" {
+		organizationID, err := primitive.ObjectIDFromHex(body.Organization)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid organization"})
+		}
+		count, err := config.DB.Collection(organizationCollection).CountDocuments(ctx, bson.M{"_id": organizationID})
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching organization"})
+		}
+		if count == 0 {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Organization not found"})
+		}
+		update = bson.M{"$set": bson.M{"organization": organizationID}}
+	}
+
+	// 2) Move the user; their tokens carry the old organization, so their
+	// sessions end
+	result, err := config.DB.Collection(userCollection).UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error updating user"})
+	}
+	if result.MatchedCount == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	if err := revokeAllUserTokens(ctx, objectID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error revoking sessions"})
+	}
+	middleware.ForgetAccount(objectID.Hex())
+
+	return c.JSON(fiber.Map{"message": "Organization updated successfully"})
+}
@@ -0,0 +1,205 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JongSinister/WTFiber/audit"
+	"github.com/JongSinister/WTFiber/config"
+	"github.com/JongSinister/WTFiber/mailer"
+	"github.com/JongSinister/WTFiber/models"
+	"github.com/JongSinister/WTFiber/password"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const passwordResetCollection = "password_reset_tokens"
+
+// @desc    Email a password reset link
+// @route   POST /api/v1/auth/forgot-password
+// @access  Public
+func ForgotPassword(c *fiber.Ctx) error {
+	// 1) Parse the request body
+	body := struct {
+		Email string `json:"email"`
+	}{}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request format"})
+	}
+
+	// The response is the same whether or not the email is registered
+	response := fiber.Map{
+		"success": true,
+		"message": "If the email is registered, a reset link has been sent",
+	}
+
+	// 2) Cap the emails one address or client can ask for. Every request
+	// counts, so the limit says nothing about which emails exist.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	email := strings.Clone(body.Email)
+	emailKey, ipKey := models.ResetAttemptKey(email), models.ResetIPAttemptKey(c.IP())
+	wait, err := attemptWait(ctx, emailKey, ipKey)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error checking reset requests"})
+	}
+	if wait > 0 {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "Too many reset requests, try again later"})
+	}
+	err = recordFailures(ctx, nil, map[string]int{
+		emailKey: config.PasswordResetMaxRequests(),
+		ipKey:    config.PasswordResetIPMaxRequests(),
+	})
+	if err != nil {
+		log.Printf("Error recording password reset request: %v", err)
+	}
+
+	// 3) Issue the link in the background, so the response takes as long
+	// whether or not the email is registered
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := issuePasswordReset(ctx, email); err != nil {
+			log.Printf("Error issuing password reset: %v", err)
+		}
+	}()
+
+	return c.JSON(response)
+}
+
+// issuePasswordReset emails a reset link to the user with the email, if
+// there is one, after invalidating their earlier links
+func issuePasswordReset(ctx context.Context, email string) error {
+	// 1) Find the user by email
+	user := new(models.User)
+	err := config.DB.Collection(userCollection).FindOne(ctx, bson.M{"email": email}).Decode(user)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// 2) Invalidate earlier links and store a new token
+	now := time.Now()
+	_, err = config.DB.Collection(passwordResetCollection).UpdateMany(ctx,
+		bson.M{"user": user.ID, "usedAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"usedAt": primitive.NewDateTimeFromTime(now)}},
+	)
+	if err != nil {
+		return err
+	}
+
+	plain, err := models.RandomToken(32)
+	if err != nil {
+		return err
+	}
+
+	ttl := config.PasswordResetTTL()
+	_, err = config.DB.Collection(passwordResetCollection).InsertOne(ctx, models.PasswordResetToken{
+		User:      user.ID,
+		Hash:      models.HashToken(plain),
+		ExpiresAt: primitive.NewDateTimeFromTime(now.Add(ttl)),
+		CreatedAt: primitive.NewDateTimeFromTime(now),
+	})
+	if err != nil {
+		return err
+	}
+
+	// 3) Email the link
+	link := config.GetEnv("PASSWORD_RESET_URL", config.AppURL()+"/reset-password/") + plain
+	return mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your account. "+
+			"Use the link below within %s to choose a new one:\n\n%s\n\n"+
+			"If this was not you, you can ignore this email.\n", user.Name, ttl, link),
+	})
+}
+
+// @desc    Set a new password with a reset token
+// @route   PUT /api/v1/auth/reset-password/:token
+// @access  Public
+func ResetPassword(c *fiber.Ctx) error {
+	// 1) Parse the request body
+	body := struct {
+		Password string `json:"password"`
+	}{}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request format"})
+	}
+	if body.Password == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Password is required"})
+	}
+
+	// 2) Find the token and its user
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	filter := bson.M{
+		"hash":      models.HashToken(c.Params("token")),
+		"usedAt":    bson.M{"$exists": false},
+		"expiresAt": bson.M{"$gt": now},
+	}
+
+	resetToken := new(models.PasswordResetToken)
+	if err := config.DB.Collection(passwordResetCollection).FindOne(ctx, filter).Decode(resetToken); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired reset token"})
+	}
+
+	user := new(models.User)
+	err := config.DB.Collection(userCollection).FindOne(ctx, bson.M{"_id": resetToken.User}).Decode(user)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	// 3) Check the new password before the token is spent
+	if problems := password.Validate(body.Password, user.Email, user.Name); len(problems) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Password does not meet the requirements", "details": problems})
+	}
+
+	// 4) Consume the token; the match on usedAt makes it single-use
+	result, err := config.DB.Collection(passwordResetCollection).UpdateOne(ctx, filter, bson.M{"$set": bson.M{"usedAt": now}})
+	if err != nil || result.ModifiedCount == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired reset token"})
+	}
+
+	// 5) Hash and store the new password
+	user.Password = body.Password
+	if err := user.HashPassword(); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error hashing password"})
+	}
+
+	_, err = config.DB.Collection(userCollection).UpdateOne(ctx,
+		bson.M{"_id": user.ID},
+		bson.M{"$set": bson.M{"password": user.Password}, "$unset": bson.M{"noPassword": ""}},
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error updating password"})
+	}
+
+	// 6) Log out every existing session
+	if err := revokeAllUserTokens(ctx, user.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error revoking sessions"})
+	}
+
+	// 7) The user proved control of the email, so lift any login lockout
+	if err := clearAccountFailures(ctx, user); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error unlocking account"})
+	}
+	recordAudit(c, audit.PasswordReset, user.ID, nil)
+
+	// 8) Start a fresh session, or ask for the second factor, and return the response
+	return loginResponse(c, user, "pwd")
+}
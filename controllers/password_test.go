@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"context"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JongSinister/WTFiber/mailer"
+	"github.com/JongSinister/WTFiber/mailer/mailtest"
+	"github.com/JongSinister/WTFiber/models"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// withSMTPSink points the mailer at a local SMTP sink for the test
+func withSMTPSink(t *testing.T) *mailtest.Server {
+	t.Helper()
+	sink := mailtest.NewServer(t)
+	t.Setenv("SMTP_HOST", sink.Host())
+	t.Setenv("SMTP_PORT", sink.Port())
+	mailer.Setup()
+	t.Cleanup(func() {
+		t.Setenv("SMTP_HOST", "")
+		mailer.Setup()
+	})
+	return sink
+}
+
+func TestIssuePasswordReset(t *testing.T) {
+	t.Setenv("PASSWORD_RESET_URL", "https://hotels.example.com/reset/")
+	sink := withSMTPSink(t)
+	user := models.User{ID: primitive.NewObjectID(), Name: "Somchai", Email: "guest@example.com"}
+
+	runWithMockDB(t, "registered email", func(mt *mtest.T) {
+		mt.AddMockResponses(mockFind(mt, userCollection, user), mockWrite(1), mockWrite(1))
+
+		if err := issuePasswordReset(context.Background(), user.Email); err != nil {
+			mt.Fatal(err)
+		}
+
+		msg := sink.Wait(mt.T, 5*time.Second)
+		if len(msg.To) != 1 || msg.To[0] != user.Email || !strings.Contains(msg.Data, "Subject: Reset your password") {
+			mt.Fatalf("unexpected message to %v:\n%s", msg.To, msg.Data)
+		}
+		link := regexp.MustCompile(`https://hotels\.example\.com/reset/(\S+)`).FindStringSubmatch(msg.Data)
+		if link == nil {
+			mt.Fatalf("no reset link in:\n%s", msg.Data)
+		}
+
+		// Only the hash of the emailed token is stored
+		events := mt.GetAllStartedEvents()
+		if got := startedCommands(mt); !reflect.DeepEqual(got, []string{"find", "update", "insert"}) {
+			mt.Fatalf("sent %v", got)
+		}
+		stored := events[2].Command.Lookup("documents").Array().Index(0).Value().Document().Lookup("hash").StringValue()
+		if stored != models.HashToken(link[1]) {
+			mt.Error("stored hash does not match the emailed token")
+		}
+	})
+
+	runWithMockDB(t, "unknown email", func(mt *mtest.T) {
+		mt.AddMockResponses(mockFind(mt, userCollection))
+
+		if err := issuePasswordReset(context.Background(), "nobody@example.com"); err != nil {
+			mt.Fatal(err)
+		}
+		if got := startedCommands(mt); !reflect.DeepEqual(got, []string{"find"}) {
+			mt.Errorf("sent %v, want only the lookup", got)
+		}
+		time.Sleep(50 * time.Millisecond)
+		if !sink.Empty() {
+			mt.Error("an email was sent for an unknown address")
+		}
+	})
+}
+
+func TestForgotPasswordThrottled(t *testing.T) {
+	locked := models.LoginAttempt{
+		Key:         models.ResetAttemptKey("guest@example.com"),
+		LockedUntil: primitive.NewDateTimeFromTime(time.Now().Add(time.Minute)),
+	}
+
+	runWithMockDB(t, "locked address", func(mt *mtest.T) {
+		mt.AddMockResponses(mockFind(mt, loginAttemptCollection, locked))
+
+		app := fiber.New()
+		app.Post("/forgot-password", ForgotPassword)
+		req := httptest.NewRequest("POST", "/forgot-password", strings.NewReader(`{"email":"Guest@Example.com"}`))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		resp, err := app.Test(req)
+		if err != nil {
+			mt.Fatal(err)
+		}
+
+		if resp.StatusCode != fiber.StatusTooManyRequests || resp.Header.Get(fiber.HeaderRetryAfter) == "" {
+			mt.Errorf("got %d with Retry-After %q, want 429", resp.StatusCode, resp.Header.Get(fiber.HeaderRetryAfter))
+		}
+		keys := mt.GetStartedEvent().Command.Lookup("filter", "key", "$in").Array()
+		want := bson.A{models.ResetAttemptKey("guest@example.com"), models.ResetIPAttemptKey("0.0.0.0")}
+		if values, _ := keys.Values(); len(values) != len(want) || values[0].StringValue() != want[0] || values[1].StringValue() != want[1] {
+			mt.Errorf("checked %v, want %v", keys, want)
+		}
+	})
+}
+
+func TestResetKeysDoNotBackOff(t *testing.T) {
+	for _, key := range []string{models.ResetAttemptKey("guest@example.com"), models.ResetIPAttemptKey("10.0.0.1")} {
+		if backsOff(key) {
+			t.Errorf("%s backs off", key)
+		}
+	}
+}
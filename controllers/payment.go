@@ -0,0 +1,414 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/JongSinister/WTFiber/config"
+	"github.com/JongSinister/WTFiber/dto"
+	"github.com/JongSinister/WTFiber/models"
+	"github.com/JongSinister/WTFiber/payment"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const paymentCollection = "payments"
+
+// Default deposit when PAYMENT_DEPOSIT_AMOUNT is unset (in minor units)
+const defaultDepositAmount = 50000
+
+// @desc    Create a deposit payment for an appointment
+// @route   POST /api/v1/appointments/:id/payments
+// @access  Private (owner or admin)
+func CreatePayment(c *fiber.Ctx) error {
+	// 1) Get the appointment ID from the URL and convert it to an ObjectID
+	objectID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID Format"})
+	}
+
+	// 2) Fetch the appointment and check ownership
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	appointment := new(models.Appointment)
+	err = config.DB.Collection(appointmentCollection).FindOne(ctx, scoped(c, bson.M{"_id": objectID})).Decode(appointment)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Appointment not found"})
+	}
+
+	if !canAccess(c, "payments:create", appointment.User) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	// 3) The deposit is worked out from the hotel, never taken from the client
+	hotel := new(models.Hotel)
+	err = config.DB.Collection(hotelCollection).FindOne(ctx, scoped(c, bson.M{"_id": appointment.Hotel})).Decode(hotel)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Hotel not found"})
+	}
+	amount, currency := depositFor(hotel), paymentCurrency()
+
+	// 4) Refuse a second deposit while one is pending or paid
+	count, err := config.DB.Collection(paymentCollection).CountDocuments(ctx, bson.M{
+		"appointment": appointment.ID,
+		"status":      bson.M{"$in": []string{models.PaymentPending, models.PaymentPaid}},
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error checking payments"})
+	}
+	if count > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Appointment already has an open payment"})
+	}
+
+	// 5) Open the charge with the payment provider
+	provider, err := payment.Default()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Payment provider not configured"})
+	}
+
+	charge, err := provider.CreateCharge(ctx, payment.ChargeRequest{
+		Amount:      amount,
+		Currency:    currency,
+		Reference:   appointment.ID.Hex(),
+		Description: "Appointment deposit",
+	})
+	if err != nil || charge.Amount != amount || charge.Currency != currency {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "Failed to create charge"})
+	}
+
+	// 6) Store the payment
+	newPayment := &models.Payment{
+		Appointment: appointment.ID,
+		User:        appointment.User,
+		Amount:      charge.Amount,
+		Currency:    charge.Currency,
+		Status:      models.PaymentPending,
+		Provider:    provider.Name(),
+		ProviderRef: charge.Ref,
+		CreatedAt:   primitive.NewDateTimeFromTime(time.Now()),
+	}
+
+	res, err := config.DB.Collection(paymentCollection).InsertOne(ctx, newPayment)
+	if mongo.IsDuplicateKeyError(err) {
+		// A concurrent request opened a payment after the check in step 4
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Appointment already has an open payment"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create payment"})
+	}
+	newPayment.ID = res.InsertedID.(primitive.ObjectID)
+
+	// 7) Return the response
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Payment created successfully",
+		"payment": dto.NewPayment(newPayment),
+	})
+}
+
+// @desc    Get the payments of an appointment
+// @route   GET /api/v1/appointments/:id/payments
+// @access  Private (owner or admin)
+func GetAppointmentPayments(c *fiber.Ctx) error {
+	// 1) Get the appointment ID from the URL and convert it to an ObjectID
+	objectID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID Format"})
+	}
+
+	// 2) Fetch the appointment and check ownership
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	appointment := new(models.Appointment)
+	err = config.DB.Collection(appointmentCollection).FindOne(ctx, scoped(c, bson.M{"_id": objectID})).Decode(appointment)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Appointment not found"})
+	}
+
+	if !canAccess(c, "payments:read", appointment.User) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	// 3) Fetch the payments, newest first
+	opts := options.Find().SetSort(bson.M{"createdAt": -1})
+	cursor, err := config.DB.Collection(paymentCollection).Find(ctx, bson.M{"appointment": objectID}, opts)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching payments"})
+	}
+	defer cursor.Close(ctx)
+
+	payments := []models.Payment{}
+	if err := cursor.All(ctx, &payments); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching payments"})
+	}
+
+	// 4) Return payments
+	return c.JSON(dto.List(payments, dto.NewPayment))
+}
+
+// @desc    Get a payment
+// @route   GET /api/v1/payments/:id
+// @access  Private (owner or admin)
+func GetPayment(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	existPayment, status, msg := findOwnedPayment(ctx, c, "payments:read")
+	if existPayment == nil {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+
+	return c.JSON(dto.NewPayment(existPayment))
+}
+
+// @desc    Confirm (capture) a pending payment
+// @route   POST /api/v1/payments/:id/confirm
+// @access  Private (staff; guest payments are confirmed by the webhook)
+func ConfirmPayment(c *fiber.Ctx) error {
+	// 1) Get the ID from the URL and convert it to an ObjectID
+	objectID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID Format"})
+	}
+
+	// 2) Fetch the payment
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	existPayment, status, msg := findScopedPayment(ctx, c, objectID)
+	if existPayment == nil {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+
+	if !existPayment.CanTransition(models.PaymentPaid) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Payment is " + existPayment.Status})
+	}
+
+	// 3) Capture the charge with the provider
+	provider, err := payment.Get(existPayment.Provider)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Payment provider not configured"})
+	}
+
+	charge, err := provider.Capture(ctx, existPayment.ProviderRef)
+	if err != nil {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "Failed to confirm payment"})
+	}
+
+	// 4) Record the new status
+	newStatus := models.PaymentPaid
+	if charge.Status != payment.ChargeSucceeded {
+		newStatus = models.PaymentFailed
+	}
+
+	updated, err := applyPaymentStatus(ctx, existPayment, newStatus)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update payment"})
+	}
+
+	// 5) Return the response
+	return c.JSON(fiber.Map{
+		"message": "Payment " + updated.Status,
+		"payment": dto.NewPayment(updated),
+	})
+}
+
+// @desc    Refund a paid payment
+// @route   POST /api/v1/payments/:id/refund
+// @access  Private (admin)
+func RefundPayment(c *fiber.Ctx) error {
+	// 1) Get the ID from the URL and convert it to an ObjectID
+	objectID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID Format"})
+	}
+
+	// 2) Fetch the payment
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	existPayment, status, msg := findScopedPayment(ctx, c, objectID)
+	if existPayment == nil {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+
+	if !existPayment.CanTransition(models.PaymentRefunded) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Payment is " + existPayment.Status})
+	}
+
+	// 3) Refund the charge with the provider
+	provider, err := payment.Get(existPayment.Provider)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Payment provider not configured"})
+	}
+
+	if _, err := provider.Refund(ctx, existPayment.ProviderRef); err != nil {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "Failed to refund payment"})
+	}
+
+	// 4) Record the new status
+	updated, err := applyPaymentStatus(ctx, existPayment, models.PaymentRefunded)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update payment"})
+	}
+
+	// 5) Return the response
+	return c.JSON(fiber.Map{
+		"message": "Payment refunded",
+		"payment": dto.NewPayment(updated),
+	})
+}
+
+// @desc    Receive a signed payment provider webhook
+// @route   POST /api/v1/payments/webhook/:provider
+// @access  Public (signature verified)
+func PaymentWebhook(c *fiber.Ctx) error {
+	// 1) Find the provider and verify the payload
+	provider, err := payment.Get(c.Params("provider"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Unknown payment provider"})
+	}
+
+	event, err := provider.ParseWebhook(c.Body(), c.Get("X-Payment-Signature"))
+	if errors.Is(err, payment.ErrInvalidSignature) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid signature"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid webhook payload"})
+	}
+
+	// 2) Map the event to a payment status
+	var newStatus string
+	switch event.Type {
+	case payment.EventChargeSucceeded:
+		newStatus = models.PaymentPaid
+	case payment.EventChargeFailed:
+		newStatus = models.PaymentFailed
+	case payment.EventChargeRefunded:
+		newStatus = models.PaymentRefunded
+	default:
+		// Acknowledge events we do not care about so the provider stops retrying
+		return c.JSON(fiber.Map{"received": true})
+	}
+
+	// 3) Fetch the payment the event refers to
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	existPayment := new(models.Payment)
+	err = config.DB.Collection(paymentCollection).FindOne(ctx, bson.M{
+		"provider":    provider.Name(),
+		"providerRef": event.Ref,
+	}).Decode(existPayment)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Payment not found"})
+	}
+
+	// 4) Apply the transition; replayed events are a no-op
+	if existPayment.Status != newStatus {
+		if !existPayment.CanTransition(newStatus) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Payment is " + existPayment.Status})
+		}
+		if _, err := applyPaymentStatus(ctx, existPayment, newStatus); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update payment"})
+		}
+	}
+
+	return c.JSON(fiber.Map{"received": true})
+}
+
+// findOwnedPayment loads the payment in the :id param and checks that the
+// principal may apply perm to it. It returns the HTTP status and message on
+// failure.
+func findOwnedPayment(ctx context.Context, c *fiber.Ctx, perm string) (*models.Payment, int, string) {
+	objectID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return nil, fiber.StatusBadRequest, "Invalid ID Format"
+	}
+
+	existPayment, status, msg := findScopedPayment(ctx, c, objectID)
+	if existPayment == nil {
+		return nil, status, msg
+	}
+
+	if !canAccess(c, perm, existPayment.User) {
+		return nil, fiber.StatusForbidden, "Access denied"
+	}
+
+	return existPayment, 0, ""
+}
+
+// findScopedPayment loads a payment whose appointment belongs to the
+// request's organization. It returns the HTTP status and message on failure.
+func findScopedPayment(ctx context.Context, c *fiber.Ctx, objectID primitive.ObjectID) (*models.Payment, int, string) {
+	existPayment := new(models.Payment)
+	err := config.DB.Collection(paymentCollection).FindOne(ctx, bson.M{"_id": objectID}).Decode(existPayment)
+	if err != nil {
+		return nil, fiber.StatusNotFound, "Payment not found"
+	}
+
+	ok, err := inScope(ctx, c, appointmentCollection, existPayment.Appointment)
+	if err != nil {
+		return nil, fiber.StatusInternalServerError, "Error fetching payment"
+	}
+	if !ok {
+		return nil, fiber.StatusNotFound, "Payment not found"
+	}
+	return existPayment, 0, ""
+}
+
+// applyPaymentStatus moves a payment to a new status, guarding against
+// concurrent updates by matching on the current status.
+func applyPaymentStatus(ctx context.Context, existPayment *models.Payment, status string) (*models.Payment, error) {
+	set := bson.M{"status": status}
+	now := primitive.NewDateTimeFromTime(time.Now())
+	switch status {
+	case models.PaymentPaid:
+		set["paidAt"] = now
+	case models.PaymentRefunded:
+		set["refundedAt"] = now
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	updated := new(models.Payment)
+	err := config.DB.Collection(paymentCollection).FindOneAndUpdate(ctx,
+		bson.M{"_id": existPayment.ID, "status": existPayment.Status},
+		bson.M{"$set": set},
+		opts,
+	).Decode(updated)
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// depositFor is the deposit for a one-night booking at the hotel: the flat
+// deposit, but never more than the night costs
+func depositFor(hotel *models.Hotel) int64 {
+	deposit := depositAmount()
+	if hotel.Price > 0 && hotel.Price < deposit {
+		return hotel.Price
+	}
+	return deposit
+}
+
+// paymentCurrency reads PAYMENT_CURRENCY, falling back to THB
+func paymentCurrency() string {
+	return config.GetEnv("PAYMENT_CURRENCY", "THB")
+}
+
+// depositAmount reads PAYMENT_DEPOSIT_AMOUNT, falling back to the default deposit
+func depositAmount() int64 {
+	amount, err := strconv.ParseInt(os.Getenv("PAYMENT_DEPOSIT_AMOUNT"), 10, 64)
+	if err != nil || amount <= 0 {
+		return defaultDepositAmount
+	}
+	return amount
+}
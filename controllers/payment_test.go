@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/JongSinister/WTFiber/models"
+	"github.com/JongSinister/WTFiber/payment"
+	"github.com/JongSinister/WTFiber/tenant"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestDepositFor(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		price   int64
+		deposit int64
+	}{
+		{"no price uses the flat deposit", "", 0, defaultDepositAmount},
+		{"price above the deposit", "", 250000, defaultDepositAmount},
+		{"price below the deposit", "", 30000, 30000},
+		{"configured deposit", "20000", 250000, 20000},
+		{"invalid configuration", "-5", 0, defaultDepositAmount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PAYMENT_DEPOSIT_AMOUNT", tt.env)
+			if got := depositFor(&models.Hotel{Price: tt.price}); got != tt.deposit {
+				t.Errorf("got %d, want %d", got, tt.deposit)
+			}
+		})
+	}
+}
+
+func TestCreatePaymentConcurrentConflict(t *testing.T) {
+	payment.Register(payment.NewMockProvider("secret"))
+	withRoles(t, map[string][]string{"user": {"payments:create:own"}})
+
+	org := primitive.NewObjectID()
+	userID := primitive.NewObjectID()
+	appointment := models.Appointment{ID: primitive.NewObjectID(), User: userID, Hotel: primitive.NewObjectID(), Organization: org}
+	hotel := models.Hotel{ID: appointment.Hotel, Organization: org, Price: 250000}
+
+	tests := []struct {
+		name   string
+		insert func(mt *mtest.T) bson.D
+		status int
+	}{
+		{"first request", func(mt *mtest.T) bson.D { return mockWrite(1) }, fiber.StatusCreated},
+		// The other request inserted between the count and the insert; the
+		// unique index on open payments turns it away
+		{"lost the race", func(mt *mtest.T) bson.D {
+			return mtest.CreateWriteErrorsResponse(mtest.WriteError{Code: 11000, Message: "E11000 duplicate key error"})
+		}, fiber.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		runWithMockDB(t, tt.name, func(mt *mtest.T) {
+			mt.AddMockResponses(
+				mockFind(mt, appointmentCollection, appointment),
+				mockFind(mt, hotelCollection, hotel),
+				mockCount(mt, paymentCollection, 0),
+				tt.insert(mt),
+			)
+
+			app := fiber.New()
+			app.Post("/appointments/:id/payments", func(c *fiber.Ctx) error {
+				c.Locals("user", jwt.MapClaims{"sub": userID.Hex(), "role": "user", "amr": []interface{}{"pwd"}})
+				c.Locals("tenant", tenant.Of(org))
+				return c.Next()
+			}, CreatePayment)
+
+			resp, err := app.Test(httptest.NewRequest("POST", "/appointments/"+appointment.ID.Hex()+"/payments", nil))
+			if err != nil {
+				mt.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				mt.Errorf("got %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JongSinister/WTFiber/audit"
+	"github.com/JongSinister/WTFiber/config"
+	"github.com/JongSinister/WTFiber/dto"
+	"github.com/JongSinister/WTFiber/middleware"
+	"github.com/JongSinister/WTFiber/models"
+	"github.com/JongSinister/WTFiber/password"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// @desc    Update the current user's name and phone number
+// @route   PUT /api/v1/auth/me
+// @access  Private
+func UpdateMe(c *fiber.Ctx) error {
+	// 1) Parse the request body; only name and tel can change here
+	body := new(dto.UpdateProfileRequest)
+	if err := c.BodyParser(body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request format"})
+	}
+
+	set := bson.M{}
+	if name := strings.TrimSpace(body.Name); name != "" {
+		set["name"] = name
+	}
+	if tel := strings.TrimSpace(body.Tel); tel != "" {
+		set["tel"] = tel
+	}
+	if len(set) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "No fields to update"})
+	}
+
+	// 2) Update the user
+	userID, ok := currentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error parsing claims"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user := new(models.User)
+	err := config.DB.Collection(userCollection).FindOneAndUpdate(ctx,
+		bson.M{"_id": userID},
+		bson.M{"$set": set},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(user)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	// Only the names of the changed fields are logged, not their values
+	recordAudit(c, audit.ProfileUpdate, user.ID, map[string]interface{}{"fields": changedFields(set)})
+
+	return c.JSON(fiber.Map{"success": true, "data": dto.NewUser(user)})
+}
+
+// @desc    Change the current user's password
+// @route   PUT /api/v1/auth/password
+// @access  Private
+func ChangePassword(c *fiber.Ctx) error {
+	// 1) Parse the request body
+	body := new(dto.ChangePasswordRequest)
+	if err := c.BodyParser(body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request format"})
+	}
+
+	// 2) Check the current password, or a recent sign-in for users without one
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, status, msg := findCurrentUser(ctx, c)
+	if user == nil {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	if status, msg := confirmIdentity(ctx, c, user, body.CurrentPassword, "Current password is incorrect"); status != 0 {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+
+	// 3) Validate and store the new password
+	if problems := password.Validate(body.NewPassword, user.Email, user.Name); len(problems) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Password does not meet the requirements", "details": problems})
+	}
+
+	user.Password = body.NewPassword
+	if err := user.HashPassword(); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error hashing password"})
+	}
+
+	_, err := config.DB.Collection(userCollection).UpdateOne(ctx,
+		bson.M{"_id": user.ID},
+		bson.M{"$set": bson.M{"password": user.Password}, "$unset": bson.M{"noPassword": ""}},
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error updating password"})
+	}
+
+	// 4) Log out every other session; this one stays signed in
+	if err := revokeUserTokensExcept(ctx, user.ID, currentSessionID(c)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error revoking sessions"})
+	}
+
+	recordAudit(c, audit.PasswordChange, user.ID, nil)
+
+	return c.JSON(fiber.Map{"success": true, "message": "Password changed successfully"})
+}
+
+// @desc    Delete the current user's account
+// @route   DELETE /api/v1/auth/me
+// @access  Private
+func DeleteMe(c *fiber.Ctx) error {
+	// 1) Confirm with the password, or a recent sign-in for users without one
+	body := new(dto.DeleteAccountRequest)
+	if err := c.BodyParser(body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request format"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, status, msg := findCurrentUser(ctx, c)
+	if user == nil {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	if status, msg := confirmIdentity(ctx, c, user, body.Password, "Password is incorrect"); status != 0 {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+
+	// 2) Cancel the bookings that have not happened yet
+	now := time.Now()
+	_, err := config.DB.Collection(appointmentCollection).UpdateMany(ctx,
+		bson.M{
+			"user":     user.ID,
+			"apptDate": bson.M{"$gte": now},
+			"status":   bson.M{"$ne": models.AppointmentCancelled},
+		},
+		bson.M{"$set": bson.M{
+			"status":      models.AppointmentCancelled,
+			"cancelledAt": primitive.NewDateTimeFromTime(now),
+		}},
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error cancelling appointments"})
+	}
+
+	// 3) End every session
+	if err := revokeAllUserTokens(ctx, user.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error revoking sessions"})
+	}
+
+	// 4) Anonymize the account. The record stays so past bookings and
+	// payments keep a valid reference, but nothing in it identifies the user.
+	deletedAt := primitive.NewDateTimeFromTime(now)
+	_, err = config.DB.Collection(userCollection).UpdateOne(ctx,
+		bson.M{"_id": user.ID},
+		bson.M{
+			"$set": bson.M{
+				"name":          "Deleted user",
+				"tel":           "",
+				"email":         "deleted-" + user.ID.Hex() + "@deleted.invalid",
+				"password":      "",
+				"emailVerified": false,
+				"mfaEnabled":    false,
+				"disabled":      true,
+				"disabledAt":    deletedAt,
+				"deletedAt":     deletedAt,
+			},
+			"$unset": bson.M{
+				"emailVerifiedAt":  "",
+				"mfaSecret":        "",
+				"mfaPendingSecret": "",
+				"mfaRecoveryCodes": "",
+				"mfaLastStep":      "",
+				"noPassword":       "",
+			},
+		},
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error deleting account"})
+	}
+	middleware.ForgetAccount(user.ID.Hex())
+
+	// 5) Drop linked sign-ins, pending tokens and data exports, and the
+	// network details kept about the user's sessions, logins and audited
+	// actions
+	for _, collection := range []string{identityCollection, passwordResetCollection} {
+		if _, err := config.DB.Collection(collection).DeleteMany(ctx, bson.M{"user": user.ID}); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error deleting account"})
+		}
+	}
+	if err := deleteUserDataExports(ctx, user.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error deleting account"})
+	}
+	if err := scrubUserActivity(ctx, user); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error deleting account"})
+	}
+
+	// The deletion itself is audited without the request's IP and client
+	audit.Record(models.AuditEntry{Action: audit.AccountDelete, Actor: user.ID, Subject: user.ID})
+
+	clearAuthCookies(c)
+	return c.JSON(fiber.Map{"success": true, "message": "Account deleted"})
+}
+
+// changedFields lists the fields an update sets, in a stable order
+func changedFields(set bson.M) []string {
+	fields := make([]string, 0, len(set))
+	for field := range set {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// confirmIdentity checks the password a user typed to confirm a sensitive
+// change. Wrong passwords count against the same limits as logins, so a
+// stolen access token cannot be used to guess the password. Users who signed
+// up through an identity provider have none of their own, so a sign-in
+// within the re-authentication window stands in for it.
+func confirmIdentity(ctx context.Context, c *fiber.Ctx, user *models.User, plain, wrongPassword string) (int, string) {
+	if user.HasPassword() {
+		wait, err := loginWait(ctx, user.Email, c.IP())
+		if err != nil {
+			return fiber.StatusInternalServerError, "Error checking login attempts"
+		}
+		if wait > 0 {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			return fiber.StatusTooManyRequests, "Too many failed attempts, try again later"
+		}
+
+		if !user.CheckPassword(plain) {
+			if err := recordLoginFailure(ctx, user, user.Email, c.IP()); err != nil {
+				log.Printf("Error recording failed password check: %v", err)
+			}
+			return fiber.StatusUnauthorized, wrongPassword
+		}
+		if err := clearLoginFailures(ctx, models.AccountAttemptKey(user.Email), models.ClientAttemptKey(user.Email, c.IP())); err != nil {
+			log.Printf("Error clearing failed logins: %v", err)
+		}
+		return 0, ""
+	}
+
+	session := new(models.Session)
+	err := config.DB.Collection(sessionCollection).FindOne(ctx, bson.M{"_id": currentSessionID(c), "user": user.ID}).Decode(session)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return fiber.StatusInternalServerError, "Error checking session"
+	}
+	if err != nil || !recentSignIn(session, time.Now()) {
+		return fiber.StatusUnauthorized, "Sign in again to confirm this change"
+	}
+	return 0, ""
+}
+
+// recentSignIn reports whether the session started within the
+// re-authentication window and is still live
+func recentSignIn(session *models.Session, now time.Time) bool {
+	return session.RevokedAt == 0 && now.Sub(session.CreatedAt.Time()) <= config.ReauthWindow()
+}
+
+// scrubUserActivity removes the IPs and clients recorded about a deleted
+// user: on their sessions, in the audit log and in login failure counters
+func scrubUserActivity(ctx context.Context, user *models.User) error {
+	_, err := config.DB.Collection(sessionCollection).UpdateMany(ctx,
+		bson.M{"user": user.ID},
+		bson.M{"$set": bson.M{"ip": "", "userAgent": "", "device": ""}},
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = config.DB.Collection(audit.Collection).UpdateMany(ctx,
+		bson.M{"$or": bson.A{bson.M{"subject": user.ID}, bson.M{"actor": user.ID}}},
+		bson.M{"$unset": bson.M{"ip": "", "userAgent": ""}},
+	)
+	if err != nil {
+		return err
+	}
+
+	return clearAccountFailures(ctx, user)
+}
+
+// currentSessionID returns the session the request's token belongs to
+func currentSessionID(c *fiber.Ctx) primitive.ObjectID {
+	claims, ok := c.Locals("user").(jwt.MapClaims)
+	if !ok {
+		return primitive.NilObjectID
+	}
+	sid, _ := claims["sid"].(string)
+	sessionID, err := primitive.ObjectIDFromHex(sid)
+	if err != nil {
+		return primitive.NilObjectID
+	}
+	return sessionID
+}
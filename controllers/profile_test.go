@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/JongSinister/WTFiber/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestRecentSignIn(t *testing.T) {
+	t.Setenv("REAUTH_WINDOW", "10m")
+
+	now := time.Now()
+	at := func(d time.Duration) primitive.DateTime { return primitive.NewDateTimeFromTime(now.Add(d)) }
+
+	tests := []struct {
+		name    string
+		session models.Session
+		want    bool
+	}{
+		{"just signed in", models.Session{CreatedAt: at(-time.Minute)}, true},
+		{"signed in long ago", models.Session{CreatedAt: at(-time.Hour)}, false},
+		{"revoked session", models.Session{CreatedAt: at(-time.Minute), RevokedAt: at(0)}, false},
+	}
+
+	for _, tt := range tests {
+		if got := recentSignIn(&tt.session, now); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestConfirmIdentityWithPassword(t *testing.T) {
+	t.Setenv("LOGIN_LOCKOUT", "15m")
+
+	user := &models.User{ID: primitive.NewObjectID(), Email: "guest@example.com", Password: "correct horse battery staple"}
+	if err := user.HashPassword(); err != nil {
+		t.Fatal(err)
+	}
+	locked := models.LoginAttempt{
+		Key:         models.AccountAttemptKey(user.Email),
+		LockedUntil: primitive.NewDateTimeFromTime(time.Now().Add(time.Minute)),
+	}
+	counted := models.LoginAttempt{ID: primitive.NewObjectID(), Failures: 1}
+
+	tests := []struct {
+		name      string
+		plain     string
+		responses func(mt *mtest.T) []bson.D
+		status    int
+		commands  []string
+	}{
+		{"right password clears the counters", "correct horse battery staple", func(mt *mtest.T) []bson.D {
+			return []bson.D{mockFind(mt, loginAttemptCollection), mockWrite(0)}
+		}, 0, []string{"find", "delete"}},
+		{"wrong password is counted like a login", "hunter2", func(mt *mtest.T) []bson.D {
+			return []bson.D{mockFind(mt, loginAttemptCollection), mockFindAndModify(mt, counted), mockFindAndModify(mt, counted), mockFindAndModify(mt, counted)}
+		}, fiber.StatusUnauthorized, []string{"find", "findAndModify", "findAndModify", "findAndModify"}},
+		{"locked account is not checked", "correct horse battery staple", func(mt *mtest.T) []bson.D {
+			return []bson.D{mockFind(mt, loginAttemptCollection, locked)}
+		}, fiber.StatusTooManyRequests, []string{"find"}},
+	}
+
+	app := fiber.New()
+	for _, tt := range tests {
+		runWithMockDB(t, tt.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tt.responses(mt)...)
+			c := app.AcquireCtx(&fasthttp.RequestCtx{})
+			defer app.ReleaseCtx(c)
+
+			if status, _ := confirmIdentity(context.Background(), c, user, tt.plain, "Password is incorrect"); status != tt.status {
+				mt.Errorf("got %d, want %d", status, tt.status)
+			}
+			if got := startedCommands(mt); !reflect.DeepEqual(got, tt.commands) {
+				mt.Errorf("sent %v, want %v", got, tt.commands)
+			}
+		})
+	}
+}
+
+func TestChangedFields(t *testing.T) {
+	got := changedFields(bson.M{"tel": "0812345678", "name": "Somchai"})
+	if len(got) != 2 || got[0] != "name" || got[1] != "tel" {
+		t.Errorf("got %v, want [name tel]", got)
+	}
+}
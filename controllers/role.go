@@ -0,0 +1,265 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/JongSinister/WTFiber/config"
+	"github.com/JongSinister/WTFiber/dto"
+	"github.com/JongSinister/WTFiber/models"
+	"github.com/JongSinister/WTFiber/rbac"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const grantCollection = "grants"
+
+// @desc    List the permissions roles and grants can hold
+// @route   GET /api/v1/admin/permissions
+// @access  Private (roles:manage)
+func GetPermissions(c *fiber.Ctx) error {
+	return c.JSON(rbac.Permissions)
+}
+
+// @desc    List role definitions
+// @route   GET /api/v1/admin/roles
+// @access  Private (roles:manage)
+func GetRoles(c *fiber.Ctx) error {
+	roles := rbac.Roles()
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+	return c.JSON(dto.List(roles, dto.NewRole))
+}
+
+// @desc    Get a role definition
+// @route   GET /api/v1/admin/roles/:name
+// @access  Private (roles:manage)
+func GetRole(c *fiber.Ctx) error {
+	role, ok := rbac.GetRole(c.Params("name"))
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Role not found"})
+	}
+	return c.JSON(dto.NewRole(&role))
+}
+
+// @desc    Create or replace a role definition
+// @route   PUT /api/v1/admin/roles/:name
+// @access  Private (super-admin, roles:manage)
+func PutRole(c *fiber.Ctx) error {
+	// 1) Parse the request body
+	body := struct {
+		Description string   `json:"description"`
+		Permissions []string `json:"permissions"`
+	}{}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request format"})
+	}
+
+	// 2) Validate the permissions
+	name := c.Params("name")
+	if body.Permissions == nil {
+		body.Permissions = []string{}
+	}
+	for _, perm := range body.Permissions {
+		if !rbac.ValidPermission(perm) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unknown permission " + perm})
+		}
+	}
+
+	// 3) Keep admins from locking themselves out of role management
+	if role, ok := currentRole(c); ok && role == name && !grantsPermission(body.Permissions, "roles:manage") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Your own role must keep roles:manage"})
+	}
+
+	// 4) Save the role
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	role, err := rbac.SaveRole(ctx, name, body.Description, body.Permissions)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error saving role"})
+	}
+
+	return c.JSON(dto.NewRole(&role))
+}
+
+// @desc    Delete a custom role that no user holds
+// @route   DELETE /api/v1/admin/roles/:name
+// @access  Private (super-admin, roles:manage)
+func DeleteRole(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := rbac.DeleteRole(ctx, c.Params("name"))
+	switch {
+	case errors.Is(err, rbac.ErrRoleNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Role not found"})
+	case errors.Is(err, rbac.ErrBuiltinRole), errors.Is(err, rbac.ErrRoleInUse):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error deleting role"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Role deleted successfully"})
+}
+
+// @desc    List the resource grants of a user
+// @route   GET /api/v1/admin/users/:id/grants
+// @access  Private (roles:manage)
+func GetUserGrants(c *fiber.Ctx) error {
+	// 1) Get the ID from the URL and convert it to an ObjectID
+	objectID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID Format"})
+	}
+
+	// 2) Make sure the user is in the admin's organization
+	if status, msg := checkUserInScope(c, objectID); status != 0 {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+
+	// 3) Fetch the grants
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.DB.Collection(grantCollection).Find(ctx, bson.M{"user": objectID})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching grants"})
+	}
+	defer cursor.Close(ctx)
+
+	grants := []models.Grant{}
+	if err := cursor.All(ctx, &grants); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching grants"})
+	}
+
+	return c.JSON(dto.List(grants, dto.NewGrant))
+}
+
+// @desc    Grant a user a permission on one resource
+// @route   POST /api/v1/admin/grants
+// @access  Private (roles:manage)
+func CreateGrant(c *fiber.Ctx) error {
+	// 1) Parse and validate the request body
+	body := struct {
+		User       string `json:"user"`
+		Permission string `json:"permission"`
+		Resource   string `json:"resource"`
+	}{}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request format"})
+	}
+
+	userID, err := primitive.ObjectIDFromHex(body.User)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+	resourceID, err := primitive.ObjectIDFromHex(body.Resource)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid resource ID"})
+	}
+	if !rbac.ValidPermission(body.Permission) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unknown permission " + body.Permission})
+	}
+
+	adminID, ok := currentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error parsing claims"})
+	}
+
+	// 2) Make sure the user and the hotel are both in the admin's organization
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ok, err = inScope(ctx, c, userCollection, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error checking user"})
+	}
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	ok, err = inScope(ctx, c, hotelCollection, resourceID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error checking resource"})
+	}
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Resource not found"})
+	}
+
+	// 3) Store the grant
+	grant := models.Grant{
+		ID:         primitive.NewObjectID(),
+		User:       userID,
+		Permission: body.Permission,
+		Resource:   resourceID,
+		CreatedBy:  adminID,
+		CreatedAt:  primitive.NewDateTimeFromTime(time.Now()),
+	}
+	if _, err := config.DB.Collection(grantCollection).InsertOne(ctx, grant); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error creating grant"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(dto.NewGrant(&grant))
+}
+
+// @desc    Remove a resource grant
+// @route   DELETE /api/v1/admin/grants/:id
+// @access  Private (roles:manage)
+func DeleteGrant(c *fiber.Ctx) error {
+	// 1) Get the ID from the URL and convert it to an ObjectID
+	objectID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID Format"})
+	}
+
+	// 2) Make sure the grant's user is in the admin's organization
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	grant := new(models.Grant)
+	if err := config.DB.Collection(grantCollection).FindOne(ctx, bson.M{"_id": objectID}).Decode(grant); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Grant not found"})
+	}
+	ok, err := inScope(ctx, c, userCollection, grant.User)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error deleting grant"})
+	}
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Grant not found"})
+	}
+
+	// 3) Delete the grant
+	result, err := config.DB.Collection(grantCollection).DeleteOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error deleting grant"})
+	}
+	if result.DeletedCount == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Grant not found"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Grant deleted successfully"})
+}
+
+// currentRole returns the role of the authenticated user
+func currentRole(c *fiber.Ctx) (string, bool) {
+	claims, ok := c.Locals("user").(jwt.MapClaims)
+	if !ok {
+		return "", false
+	}
+	role, ok := claims["role"].(string)
+	return role, ok
+}
+
+// grantsPermission reports whether any of the permissions covers perm
+func grantsPermission(permissions []string, perm string) bool {
+	for _, p := range permissions {
+		if rbac.Match(p, perm) {
+			return true
+		}
+	}
+	return false
+}
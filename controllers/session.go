@@ -0,0 +1,234 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/JongSinister/WTFiber/config"
+	"github.com/JongSinister/WTFiber/dto"
+	"github.com/JongSinister/WTFiber/models"
+	"github.com/JongSinister/WTFiber/revocation"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const sessionCollection = "sessions"
+
+// @desc    Get the active sessions of the current user
+// @route   GET /api/v1/auth/sessions
+// @access  Private
+func GetMySessions(c *fiber.Ctx) error {
+	// 1) Get the user ID from the JWT claims
+	userID, ok := currentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error parsing claims"})
+	}
+
+	// 2) Fetch and return the sessions
+	return sendUserSessions(c, userID, false)
+}
+
+// @desc    Log out one of the current user's sessions
+// @route   DELETE /api/v1/auth/sessions/:id
+// @access  Private
+func DeleteMySession(c *fiber.Ctx) error {
+	// 1) Get the user ID from the JWT claims
+	userID, ok := currentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error parsing claims"})
+	}
+
+	// 2) Get the ID from the URL and convert it to an ObjectID
+	objectID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID Format"})
+	}
+
+	// 3) Make sure the session belongs to the user
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	count, err := config.DB.Collection(sessionCollection).CountDocuments(ctx, bson.M{"_id": objectID, "user": userID})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching session"})
+	}
+	if count == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Session not found"})
+	}
+
+	// 4) Revoke the session
+	if err := revokeSession(ctx, objectID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error revoking session"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Session revoked successfully"})
+}
+
+// @desc    Get the active sessions of any user
+// @route   GET /api/v1/admin/users/:id/sessions
+// @access  Private (admin)
+func GetUserSessions(c *fiber.Ctx) error {
+	// 1) Get the ID from the URL and convert it to an ObjectID
+	objectID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID Format"})
+	}
+
+	// 2) Make sure the user is in the admin's organization
+	if status, msg := checkUserInScope(c, objectID); status != 0 {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+
+	// 3) Fetch and return the sessions
+	return sendUserSessions(c, objectID, true)
+}
+
+// @desc    Log out every session of any user
+// @route   DELETE /api/v1/admin/users/:id/sessions
+// @access  Private (admin)
+func DeleteUserSessions(c *fiber.Ctx) error {
+	// 1) Get the ID from the URL and convert it to an ObjectID
+	objectID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID Format"})
+	}
+
+	// 2) Make sure the user is in the admin's organization
+	if status, msg := checkUserInScope(c, objectID); status != 0 {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+
+	// 3) Revoke everything the user holds
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := revokeAllUserTokens(ctx, objectID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error revoking sessions"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Sessions revoked successfully"})
+}
+
+// @desc    Log out a single session of any user
+// @route   DELETE /api/v1/admin/sessions/:id
+// @access  Private (admin)
+func DeleteSession(c *fiber.Ctx) error {
+	// 1) Get the ID from the URL and convert it to an ObjectID
+	objectID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID Format"})
+	}
+
+	// 2) Make sure the session's user is in the admin's organization
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	session := new(models.Session)
+	if err := config.DB.Collection(sessionCollection).FindOne(ctx, bson.M{"_id": objectID}).Decode(session); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Session not found"})
+	}
+	ok, err := inScope(ctx, c, userCollection, session.User)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching session"})
+	}
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Session not found"})
+	}
+
+	// 3) Revoke the session
+	if err := revokeSession(ctx, objectID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error revoking session"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Session revoked successfully"})
+}
+
+// sendUserSessions responds with the active sessions of a user, flagging the
+// one the request was made from. Admins managing the user also see the IPs.
+func sendUserSessions(c *fiber.Ctx, userID primitive.ObjectID, managed bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"user":      userID,
+		"revokedAt": bson.M{"$exists": false},
+		"expiresAt": bson.M{"$gt": primitive.NewDateTimeFromTime(time.Now())},
+	}
+	opts := options.Find().SetSort(bson.M{"lastSeenAt": -1})
+	cursor, err := config.DB.Collection(sessionCollection).Find(ctx, filter, opts)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching sessions"})
+	}
+	defer cursor.Close(ctx)
+
+	var sessions []models.Session
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching sessions"})
+	}
+
+	currentSession := ""
+	if claims, ok := c.Locals("user").(jwt.MapClaims); ok {
+		currentSession, _ = claims["sid"].(string)
+	}
+
+	response := dto.NewSession
+	if managed {
+		response = dto.NewManagedSession
+	}
+
+	result := make([]dto.SessionResponse, 0, len(sessions))
+	for i := range sessions {
+		result = append(result, response(&sessions[i], sessions[i].ID.Hex() == currentSession))
+	}
+
+	return c.JSON(result)
+}
+
+// createSession records a new login from the requesting device
+func createSession(ctx context.Context, c *fiber.Ctx, userID primitive.ObjectID, amr []string) (*models.Session, error) {
+	now := time.Now()
+	userAgent := c.Get(fiber.HeaderUserAgent)
+
+	device := c.Get("X-Device-Name")
+	if device == "" {
+		device = models.DescribeDevice(userAgent)
+	}
+
+	session := &models.Session{
+		ID:         primitive.NewObjectID(),
+		User:       userID,
+		Device:     device,
+		IP:         c.IP(),
+		UserAgent:  userAgent,
+		AMR:        amr,
+		CreatedAt:  primitive.NewDateTimeFromTime(now),
+		LastSeenAt: primitive.NewDateTimeFromTime(now),
+		ExpiresAt:  primitive.NewDateTimeFromTime(now.Add(config.RefreshTokenTTL())),
+	}
+
+	if _, err := config.DB.Collection(sessionCollection).InsertOne(ctx, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// revokeSession ends a session: the record is marked revoked, its refresh
+// tokens stop working and Protect rejects its access tokens
+func revokeSession(ctx context.Context, sessionID primitive.ObjectID) error {
+	if err := revocation.RevokeSession(ctx, sessionID.Hex()); err != nil {
+		return err
+	}
+
+	if err := revokeRefreshFamily(ctx, sessionID); err != nil {
+		return err
+	}
+
+	_, err := config.DB.Collection(sessionCollection).UpdateOne(ctx,
+		bson.M{"_id": sessionID, "revokedAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revokedAt": primitive.NewDateTimeFromTime(time.Now())}},
+	)
+	return err
+}
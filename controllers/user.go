@@ -0,0 +1,352 @@
+package controllers
+
+import (
+	"context"
+	"math"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/JongSinister/WTFiber/audit"
+	"github.com/JongSinister/WTFiber/config"
+	"github.com/JongSinister/WTFiber/dto"
+	"github.com/JongSinister/WTFiber/middleware"
+	"github.com/JongSinister/WTFiber/models"
+	"github.com/JongSinister/WTFiber/rbac"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// @desc    List users with search and pagination
+// @route   GET /api/v1/users?search=&role=&status=&page=&limit=
+// @access  Private (admin)
+func GetUsers(c *fiber.Ctx) error {
+	// 1) Build the filter from the query string
+	filter := scoped(c, bson.M{})
+	if search := c.Query("search"); search != "" {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(search), Options: "i"}
+		filter["$or"] = bson.A{bson.M{"name": pattern}, bson.M{"email": pattern}}
+	}
+	if role := c.Query("role"); role != "" {
+		filter["role"] = role
+	}
+	switch c.Query("status") {
+	case "", "active":
+		filter["disabled"] = bson.M{"$ne": true}
+		filter["deletedAt"] = bson.M{"$exists": false}
+	case "disabled":
+		filter["disabled"] = true
+		filter["deletedAt"] = bson.M{"$exists": false}
+	case "deleted":
+		filter["deletedAt"] = bson.M{"$exists": true}
+	case "all":
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Status must be active, disabled, deleted or all"})
+	}
+
+	// 2) Work out the page
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(c.Query("limit", "25"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 25
+	}
+
+	// 3) Count and fetch the users
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	total, err := config.DB.Collection(userCollection).CountDocuments(ctx, filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching users"})
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+	cursor, err := config.DB.Collection(userCollection).Find(ctx, filter, opts)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching users"})
+	}
+	defer cursor.Close(ctx)
+
+	var users []models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching users"})
+	}
+
+	// 4) Return the page
+	data := dto.List(users, dto.NewUser)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"count":   len(data),
+		"total":   total,
+		"page":    page,
+		"pages":   int(math.Ceil(float64(total) / float64(limit))),
+		"data":    data,
+	})
+}
+
+// @desc    Get a user by ID
+// @route   GET /api/v1/users/:id
+// @access  Private (admin)
+func GetUser(c *fiber.Ctx) error {
+	// 1) Get the ID from the URL and convert it to an ObjectID
+	objectID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID Format"})
+	}
+
+	// 2) Fetch the user
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user := new(models.User)
+	if err := config.DB.Collection(userCollection).FindOne(ctx, scoped(c, bson.M{"_id": objectID})).Decode(user); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	return c.JSON(dto.NewUser(user))
+}
+
+// @desc    Change a user's role
+// @route   PUT /api/v1/users/:id/role
+// @access  Private (admin)
+func UpdateUserRole(c *fiber.Ctx) error {
+	// 1) Parse the request body
+	body := struct {
+		Role string `json:"role"`
+	}{}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request format"})
+	}
+	if status, message := checkAssignableRole(c, body.Role); status != 0 {
+		return c.Status(status).JSON(fiber.Map{"error": message})
+	}
+
+	// 2) Update the user; their sessions end so new tokens carry the new role
+	return updateUserAccount(c, bson.M{"role": body.Role}, audit.UserRoleChange, "Role updated successfully")
+}
+
+// @desc    Disable a user's account
+// @route   PUT /api/v1/users/:id/disable
+// @access  Private (admin)
+func DisableUser(c *fiber.Ctx) error {
+	update := bson.M{"disabled": true, "disabledAt": primitive.NewDateTimeFromTime(time.Now())}
+	return updateUserAccount(c, update, audit.UserDisable, "User disabled successfully")
+}
+
+// @desc    Enable a disabled user's account
+// @route   PUT /api/v1/users/:id/enable
+// @access  Private (admin)
+func EnableUser(c *fiber.Ctx) error {
+	// 1) Get the ID from the URL and convert it to an ObjectID
+	objectID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID Format"})
+	}
+
+	// 2) Clear the disabled flag; deleted accounts stay deleted
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := config.DB.Collection(userCollection).UpdateOne(ctx,
+		scoped(c, bson.M{"_id": objectID, "deletedAt": bson.M{"$exists": false}}),
+		bson.M{"$set": bson.M{"disabled": false}, "$unset": bson.M{"disabledAt": ""}},
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error updating user"})
+	}
+	if result.MatchedCount == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	middleware.ForgetAccount(objectID.Hex())
+	recordAudit(c, audit.UserEnable, objectID, nil)
+
+	return c.JSON(fiber.Map{"message": "User enabled successfully"})
+}
+
+// @desc    Delete a user; soft by default, permanently with ?hard=true
+// @route   DELETE /api/v1/users/:id
+// @access  Private (admin)
+func DeleteUser(c *fiber.Ctx) error {
+	if c.QueryBool("hard") {
+		return hardDeleteUser(c)
+	}
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	update := bson.M{"disabled": true, "disabledAt": now, "deletedAt": now}
+	return updateUserAccount(c, update, audit.UserDelete, "User deleted successfully")
+}
+
+// hardDeleteUser removes a user along with everything tied to the account
+func hardDeleteUser(c *fiber.Ctx) error {
+	// 1) Get the ID from the URL and convert it to an ObjectID
+	objectID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID Format"})
+	}
+	if isCurrentUser(c, objectID) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "You cannot delete your own account here"})
+	}
+
+	// 2) Only users the admin could have given their role can be deleted
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user := new(models.User)
+	if err := config.DB.Collection(userCollection).FindOne(ctx, scoped(c, bson.M{"_id": objectID})).Decode(user); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	if status, message := checkManageableUser(c, user); status != 0 {
+		return c.Status(status).JSON(fiber.Map{"error": message})
+	}
+
+	// 3) End the sessions, then delete the user and everything tied to them
+	if err := revokeAllUserTokens(ctx, objectID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error revoking sessions"})
+	}
+
+	result, err := config.DB.Collection(userCollection).DeleteOne(ctx, scoped(c, bson.M{"_id": objectID}))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error deleting user"})
+	}
+	if result.DeletedCount == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	middleware.ForgetAccount(objectID.Hex())
+
+	if err := deleteUserRecords(ctx, user); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error deleting user data"})
+	}
+
+	recordAudit(c, audit.UserDelete, objectID, map[string]interface{}{"hard": true})
+	return c.JSON(fiber.Map{"message": "User deleted permanently"})
+}
+
+// updateUserAccount applies an admin change to the user in the URL, ends
+// their sessions and records the action in the audit log
+func updateUserAccount(c *fiber.Ctx, set bson.M, action, message string) error {
+	// 1) Get the ID from the URL and convert it to an ObjectID
+	objectID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID Format"})
+	}
+	if isCurrentUser(c, objectID) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "You cannot change your own account here"})
+	}
+
+	// 2) Only users the admin could have given their role can be changed, so
+	// nobody acts on an account more privileged than their own
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user := new(models.User)
+	err = config.DB.Collection(userCollection).FindOne(ctx, scoped(c, bson.M{"_id": objectID, "deletedAt": bson.M{"$exists": false}})).Decode(user)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	if status, message := checkManageableUser(c, user); status != 0 {
+		return c.Status(status).JSON(fiber.Map{"error": message})
+	}
+
+	// 3) Update the user
+	result, err := config.DB.Collection(userCollection).UpdateOne(ctx,
+		scoped(c, bson.M{"_id": objectID, "deletedAt": bson.M{"$exists": false}}),
+		bson.M{"$set": set},
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error updating user"})
+	}
+	if result.MatchedCount == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	// 4) Log the user out everywhere
+	if err := revokeAllUserTokens(ctx, objectID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error revoking sessions"})
+	}
+	middleware.ForgetAccount(objectID.Hex())
+	recordAudit(c, action, objectID, map[string]interface{}(set))
+
+	return c.JSON(fiber.Map{"message": message})
+}
+
+// checkAssignableRole returns an error status and message unless the role
+// exists and the principal holds every permission it grants
+func checkAssignableRole(c *fiber.Ctx, role string) (int, string) {
+	if _, ok := rbac.GetRole(role); !ok {
+		return fiber.StatusBadRequest, "Unknown role " + role
+	}
+
+	principal, status, message := middleware.Principal(c)
+	if status != 0 {
+		return status, message
+	}
+	if !principal.CanAssign(role) {
+		return fiber.StatusForbidden, "You cannot assign the role " + role
+	}
+	return 0, ""
+}
+
+// checkManageableUser returns an error status and message unless the
+// principal could assign the user's role, and so may disable, delete or
+// unlock them. A role that no longer exists grants nothing and does not
+// stand in the way.
+func checkManageableUser(c *fiber.Ctx, user *models.User) (int, string) {
+	switch status, message := checkAssignableRole(c, user.Role); status {
+	case 0, fiber.StatusBadRequest:
+		return 0, ""
+	case fiber.StatusForbidden:
+		return status, "You cannot manage a user with the role " + user.Role
+	default:
+		return status, message
+	}
+}
+
+// deleteUserRecords removes everything kept about a user who is deleted for
+// good: bookings and their payments, sign-ins, tokens, grants, invites they
+// used or were sent, data exports and login failure counters
+func deleteUserRecords(ctx context.Context, user *models.User) error {
+	collections := []string{
+		appointmentCollection,
+		paymentCollection,
+		identityCollection,
+		sessionCollection,
+		refreshTokenCollection,
+		passwordResetCollection,
+		verificationEmailCollection,
+		grantCollection,
+	}
+	for _, collection := range collections {
+		if _, err := config.DB.Collection(collection).DeleteMany(ctx, bson.M{"user": user.ID}); err != nil {
+			return err
+		}
+	}
+
+	_, err := config.DB.Collection(inviteCollection).DeleteMany(ctx, bson.M{"$or": bson.A{
+		bson.M{"usedBy": user.ID},
+		bson.M{"email": user.Email},
+	}})
+	if err != nil {
+		return err
+	}
+
+	if err := deleteUserDataExports(ctx, user.ID); err != nil {
+		return err
+	}
+	return clearAccountFailures(ctx, user)
+}
+
+// isCurrentUser reports whether id is the authenticated user, so admins do
+// not lock themselves out
+func isCurrentUser(c *fiber.Ctx, id primitive.ObjectID) bool {
+	userID, ok := currentUserID(c)
+	return ok && userID == id
+}
@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/JongSinister/WTFiber/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/valyala/fasthttp"
+)
+
+func TestCheckManageableUser(t *testing.T) {
+	t.Setenv("MFA_REQUIRED_ROLES", "admin")
+	withRoles(t, map[string][]string{
+		"admin":   {"*"},
+		"support": {"users:manage", "users:read", "hotels:read"},
+		"user":    {"hotels:read"},
+	})
+
+	tests := []struct {
+		name   string
+		caller string
+		target string
+		status int
+	}{
+		{"admin manages an admin", "admin", "admin", 0},
+		{"users:manage alone cannot touch an admin", "support", "admin", fiber.StatusForbidden},
+		{"users:manage manages a plain user", "support", "user", 0},
+		{"users:manage manages its peers", "support", "support", 0},
+		{"a role that no longer exists grants nothing", "support", "retired", 0},
+	}
+
+	app := fiber.New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := app.AcquireCtx(&fasthttp.RequestCtx{})
+			defer app.ReleaseCtx(c)
+			c.Locals("user", jwt.MapClaims{"role": tt.caller, "amr": []interface{}{"pwd", "otp"}})
+
+			if status, _ := checkManageableUser(c, &models.User{Role: tt.target}); status != tt.status {
+				t.Errorf("got %d, want %d", status, tt.status)
+			}
+		})
+	}
+}
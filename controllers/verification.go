@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/JongSinister/WTFiber/auth"
+	"github.com/JongSinister/WTFiber/config"
+	"github.com/JongSinister/WTFiber/mailer"
+	"github.com/JongSinister/WTFiber/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const verificationEmailCollection = "verification_emails"
+
+// Resend limits: one email per interval and a fixed number per day
+const (
+	verificationResendInterval = time.Minute
+	verificationDailyLimit     = 5
+)
+
+// @desc    Verify an email address with the emailed link
+// @route   GET /api/v1/auth/verify/:token
+// @access  Public
+func VerifyEmail(c *fiber.Ctx) error {
+	// 1) Verify the signed token
+	claims, err := auth.Parse(c.Params("token"), verificationAudience())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired verification link"})
+	}
+
+	userID, err := primitive.ObjectIDFromHex(claims["sub"].(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired verification link"})
+	}
+	email, _ := claims["email"].(string)
+
+	// 2) Mark the address as verified; matching on the email makes links
+	// sent to a previous address useless
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res, err := config.DB.Collection(userCollection).UpdateOne(ctx,
+		bson.M{"_id": userID, "email": email},
+		bson.M{"$set": bson.M{
+			"emailVerified":   true,
+			"emailVerifiedAt": primitive.NewDateTimeFromTime(time.Now()),
+		}},
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error verifying email"})
+	}
+	if res.MatchedCount == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired verification link"})
+	}
+
+	// 3) Return the response
+	return c.JSON(fiber.Map{"success": true, "message": "Email verified successfully"})
+}
+
+// @desc    Send the verification email again
+// @route   POST /api/v1/auth/verify/resend
+// @access  Private
+func ResendVerification(c *fiber.Ctx) error {
+	// 1) Get the user ID from the JWT claims
+	userID, ok := currentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error parsing claims"})
+	}
+
+	// 2) Fetch the user
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user := new(models.User)
+	err := config.DB.Collection(userCollection).FindOne(ctx, bson.M{"_id": userID}).Decode(user)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	if user.EmailVerified {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Email already verified"})
+	}
+
+	// 3) Apply the rate limit
+	since := primitive.NewDateTimeFromTime(time.Now().Add(-24 * time.Hour))
+	count, err := config.DB.Collection(verificationEmailCollection).CountDocuments(ctx, bson.M{
+		"user":   userID,
+		"sentAt": bson.M{"$gt": since},
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error checking rate limit"})
+	}
+	if count >= verificationDailyLimit {
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "Too many verification emails, try again tomorrow"})
+	}
+
+	last := struct {
+		SentAt primitive.DateTime `bson:"sentAt"`
+	}{}
+	opts := options.FindOne().SetSort(bson.M{"sentAt": -1})
+	err = config.DB.Collection(verificationEmailCollection).FindOne(ctx, bson.M{"user": userID}, opts).Decode(&last)
+	if err == nil {
+		if wait := verificationResendInterval - time.Since(last.SentAt.Time()); wait > 0 {
+			c.Set(fiber.HeaderRetryAfter, fmt.Sprintf("%.0f", wait.Seconds()+0.5))
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "Please wait before requesting another email"})
+		}
+	}
+
+	// 4) Send the email
+	if err := sendVerificationEmail(ctx, user); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error sending verification email"})
+	}
+
+	return c.JSON(fiber.Map{"success": true, "message": "Verification email sent"})
+}
+
+// sendVerificationEmail emails the user a signed verification link and
+// records the send for rate limiting
+func sendVerificationEmail(ctx context.Context, user *models.User) error {
+	ttl := config.EmailVerificationTTL()
+	token, err := auth.Sign(jwt.MapClaims{
+		"sub":   user.ID.Hex(),
+		"aud":   verificationAudience(),
+		"email": user.Email,
+	}, ttl)
+	if err != nil {
+		return err
+	}
+
+	_, err = config.DB.Collection(verificationEmailCollection).InsertOne(ctx, bson.M{
+		"user":   user.ID,
+		"sentAt": primitive.NewDateTimeFromTime(time.Now()),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := config.AppURL() + "/api/v1/auth/verify/" + token
+	mailer.SendAsync(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below within %s:\n\n%s\n",
+			user.Name, ttl, link),
+	})
+	return nil
+}
+
+// verificationAudience keeps verification links from being used as access tokens
+func verificationAudience() string {
+	return auth.Issuer() + "/verify-email"
+}
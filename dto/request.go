@@ -0,0 +1,171 @@
+// Package dto holds the shapes of request and response bodies. They are kept
+// apart from the models so clients can only set the fields meant for them.
+package dto
+
+import (
+	"strings"
+
+	"github.com/JongSinister/WTFiber/models"
+)
+
+// RegisterRequest is the body of POST /auth/register
+type RegisterRequest struct {
+	Name        string `json:"name"`
+	Tel         string `json:"tel"`
+	Email       string `json:"email"`
+	Password    string `json:"password"`
+	InviteToken string `json:"inviteToken"`
+}
+
+// User builds the new account. Everyone starts with the user role; higher
+// roles come from an invite.
+func (req *RegisterRequest) User() *models.User {
+	return &models.User{
+		Name:     req.Name,
+		Tel:      req.Tel,
+		Email:    req.Email,
+		Password: req.Password,
+		Role:     "user",
+	}
+}
+
+// LoginRequest is the body of POST /auth/login
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// InviteRequest is the body of POST /admin/invites
+type InviteRequest struct {
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	ExpiresIn string `json:"expiresIn"`
+}
+
+// UpdateProfileRequest is the body of PUT /auth/me
+type UpdateProfileRequest struct {
+	Name string `json:"name"`
+	Tel  string `json:"tel"`
+}
+
+// ChangePasswordRequest is the body of PUT /auth/password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+// DeleteAccountRequest is the body of DELETE /auth/me
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
+// HotelFields are the hotel details clients edit directly. Managers, Wi-Fi
+// and schedule have their own endpoints and checks, so they are not here.
+// Fields left out of the body are nil.
+type HotelFields struct {
+	Name       *string `json:"name"`
+	Address    *string `json:"address"`
+	District   *string `json:"district"`
+	Province   *string `json:"province"`
+	PostalCode *string `json:"postalCode"`
+	Tel        *string `json:"tel"`
+	Region     *string `json:"region"`
+	Price      *int64  `json:"price"`
+	// Chain is the hex ID of the chain; empty removes the hotel from it
+	Chain *string `json:"chain"`
+}
+
+// CreateHotelRequest is the body of POST /hotels
+type CreateHotelRequest struct {
+	HotelFields
+	// Organization is only read for super-admins working across tenants
+	Organization string `json:"organization"`
+}
+
+// UpdateHotelRequest is the body of PUT /hotels/:id
+type UpdateHotelRequest struct {
+	HotelFields
+}
+
+// Problem returns what is wrong with the fields, or "" when they are valid.
+// A partial update only checks the fields it sets.
+func (f *HotelFields) Problem(partial bool) string {
+	required := []struct {
+		label string
+		value *string
+	}{
+		{"Name", f.Name},
+		{"Address", f.Address},
+		{"District", f.District},
+		{"Province", f.Province},
+		{"Postal code", f.PostalCode},
+		{"Region", f.Region},
+	}
+	for _, field := range required {
+		if field.value == nil && partial {
+			continue
+		}
+		if field.value == nil || strings.TrimSpace(*field.value) == "" {
+			return field.label + " is required"
+		}
+	}
+
+	switch {
+	case f.Name != nil && len(*f.Name) > 50:
+		return "Name must be at most 50 characters"
+	case f.PostalCode != nil && len(*f.PostalCode) != 5:
+		return "Postal code must be 5 characters"
+	case f.Price != nil && *f.Price < 0:
+		return "Price cannot be negative"
+	}
+	return ""
+}
+
+// Set returns the stored fields the request changes, keyed by their bson
+// names. The chain is left to the caller, which must check it.
+func (f *HotelFields) Set() map[string]interface{} {
+	set := map[string]interface{}{}
+	for key, value := range map[string]*string{
+		"name":       f.Name,
+		"address":    f.Address,
+		"district":   f.District,
+		"province":   f.Province,
+		"postalcode": f.PostalCode,
+		"tel":        f.Tel,
+		"region":     f.Region,
+	} {
+		if value != nil {
+			set[key] = *value
+		}
+	}
+	if f.Price != nil {
+		set["price"] = *f.Price
+	}
+	return set
+}
+
+// Hotel builds a new hotel from the fields, without its chain or
+// organization
+func (f *HotelFields) Hotel() *models.Hotel {
+	hotel := new(models.Hotel)
+	for _, field := range []struct {
+		dst *string
+		src *string
+	}{
+		{&hotel.Name, f.Name},
+		{&hotel.Address, f.Address},
+		{&hotel.District, f.District},
+		{&hotel.Province, f.Province},
+		{&hotel.PostalCode, f.PostalCode},
+		{&hotel.Tel, f.Tel},
+		{&hotel.Region, f.Region},
+	} {
+		if field.src != nil {
+			*field.dst = *field.src
+		}
+	}
+	if f.Price != nil {
+		hotel.Price = *f.Price
+	}
+	return hotel
+}
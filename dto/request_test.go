@@ -0,0 +1,82 @@
+package dto
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHotelFieldsProblem(t *testing.T) {
+	full := `{"name":"Riverside","address":"1 Main Rd","district":"Bang Rak","province":"Bangkok","postalCode":"10500","region":"Central"}`
+
+	tests := []struct {
+		name    string
+		body    string
+		partial bool
+		want    string
+	}{
+		{"complete hotel", full, false, ""},
+		{"missing name", `{"address":"1 Main Rd","district":"Bang Rak","province":"Bangkok","postalCode":"10500","region":"Central"}`, false, "Name is required"},
+		{"blank address", `{"name":"Riverside","address":" ","district":"Bang Rak","province":"Bangkok","postalCode":"10500","region":"Central"}`, false, "Address is required"},
+		{"long name", `{"name":"` + strings.Repeat("a", 51) + `"}`, true, "Name must be at most 50 characters"},
+		{"short postal code", `{"postalCode":"105"}`, true, "Postal code must be 5 characters"},
+		{"negative price", `{"price":-1}`, true, "Price cannot be negative"},
+		{"partial update", `{"tel":"021234567"}`, true, ""},
+		{"partial update clearing a required field", `{"region":""}`, true, "Region is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fields HotelFields
+			if err := json.Unmarshal([]byte(tt.body), &fields); err != nil {
+				t.Fatal(err)
+			}
+			if got := fields.Problem(tt.partial); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateHotelRequestIgnoresProtectedFields(t *testing.T) {
+	body := `{
+		"name": "Riverside",
+		"price": 1500,
+		"managers": ["64b7f0c2a1e4d3b2c1a09f8e"],
+		"managers.0": "64b7f0c2a1e4d3b2c1a09f8e",
+		"wifi": {"ssid": "free"},
+		"wifi.password": "hunter2",
+		"schedule.closedDates": ["2026-12-25"],
+		"organization": "64b7f0c2a1e4d3b2c1a09f8f",
+		"_id": "64b7f0c2a1e4d3b2c1a09f90",
+		"unknown": true
+	}`
+
+	var req UpdateHotelRequest
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{"name": "Riverside", "price": int64(1500)}
+	if got := req.Set(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestCreateHotelRequestHotel(t *testing.T) {
+	body := `{"name":"Riverside","postalcode":"10500","price":1500,"managers":["64b7f0c2a1e4d3b2c1a09f8e"],"wifi":{"ssid":"free"},"schedule":{"checkIn":"14:00"}}`
+
+	var req CreateHotelRequest
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		t.Fatal(err)
+	}
+
+	hotel := req.Hotel()
+	if hotel.Name != "Riverside" || hotel.PostalCode != "10500" || hotel.Price != 1500 {
+		t.Errorf("public fields not copied: %+v", hotel)
+	}
+	if hotel.Managers != nil || hotel.Wifi != nil || hotel.Schedule != nil {
+		t.Errorf("protected fields set from the body: %+v", hotel)
+	}
+}
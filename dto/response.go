@@ -0,0 +1,495 @@
+package dto
+
+import (
+	"strings"
+	"time"
+
+	"github.com/JongSinister/WTFiber/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Responses use camelCase names, hex string IDs and RFC 3339 dates in UTC.
+// Only the fields listed here are sent, so secrets such as password hashes
+// and MFA secrets cannot slip into a response when a model gains a field.
+
+// UserResponse is a user account
+type UserResponse struct {
+	ID            string     `json:"id"`
+	Name          string     `json:"name"`
+	Tel           string     `json:"tel"`
+	Email         string     `json:"email"`
+	Role          string     `json:"role"`
+	EmailVerified bool       `json:"emailVerified"`
+	MFAEnabled    bool       `json:"mfaEnabled"`
+	HasPassword   bool       `json:"hasPassword"`
+	Disabled      bool       `json:"disabled"`
+	CreatedAt     *time.Time `json:"createdAt,omitempty"`
+	DisabledAt    *time.Time `json:"disabledAt,omitempty"`
+	DeletedAt     *time.Time `json:"deletedAt,omitempty"`
+}
+
+// NewUser builds the response for a user
+func NewUser(user *models.User) UserResponse {
+	return UserResponse{
+		ID:            user.ID.Hex(),
+		Name:          user.Name,
+		Tel:           user.Tel,
+		Email:         user.Email,
+		Role:          user.Role,
+		EmailVerified: user.EmailVerified,
+		MFAEnabled:    user.MFAEnabled,
+		HasPassword:   user.HasPassword(),
+		Disabled:      user.Disabled,
+		CreatedAt:     Time(user.CreatedAt),
+		DisabledAt:    Time(user.DisabledAt),
+		DeletedAt:     Time(user.DeletedAt),
+	}
+}
+
+// HotelResponse is a hotel. Managers are only listed for callers who may
+// assign them.
+type HotelResponse struct {
+	ID         string                 `json:"id"`
+	Name       string                 `json:"name"`
+	Address    string                 `json:"address"`
+	District   string                 `json:"district"`
+	Province   string                 `json:"province"`
+	PostalCode string                 `json:"postalCode"`
+	Tel        string                 `json:"tel,omitempty"`
+	Region     string                 `json:"region"`
+	Price      int64                  `json:"price,omitempty"`
+	Chain      string                 `json:"chain,omitempty"`
+	Managers   []string               `json:"managers,omitempty"`
+	Wifi       *HotelWifiResponse     `json:"wifi,omitempty"`
+	Schedule   *HotelScheduleResponse `json:"schedule,omitempty"`
+}
+
+// HotelWifiResponse is the guest network of a hotel
+type HotelWifiResponse struct {
+	SSID      string     `json:"ssid"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+}
+
+// HotelScheduleResponse is the opening schedule of a hotel
+type HotelScheduleResponse struct {
+	CheckIn     string   `json:"checkIn"`
+	CheckOut    string   `json:"checkOut"`
+	ClosedDates []string `json:"closedDates"`
+}
+
+// NewHotel builds the response for a hotel without its managers
+func NewHotel(hotel *models.Hotel) HotelResponse {
+	response := HotelResponse{
+		ID:         hotel.ID.Hex(),
+		Name:       hotel.Name,
+		Address:    hotel.Address,
+		District:   hotel.District,
+		Province:   hotel.Province,
+		PostalCode: hotel.PostalCode,
+		Tel:        hotel.Tel,
+		Region:     hotel.Region,
+		Price:      hotel.Price,
+	}
+	if !hotel.Chain.IsZero() {
+		response.Chain = hotel.Chain.Hex()
+	}
+	if hotel.Wifi != nil {
+		response.Wifi = NewHotelWifi(hotel.Wifi)
+	}
+	if hotel.Schedule != nil {
+		response.Schedule = NewHotelSchedule(hotel.Schedule)
+	}
+	return response
+}
+
+// NewManagedHotel builds the response for a hotel including its managers
+func NewManagedHotel(hotel *models.Hotel) HotelResponse {
+	response := NewHotel(hotel)
+	response.Managers = IDs(hotel.Managers)
+	return response
+}
+
+// NewHotelWifi builds the response for a hotel's guest network
+func NewHotelWifi(wifi *models.HotelWifi) *HotelWifiResponse {
+	return &HotelWifiResponse{SSID: wifi.SSID, UpdatedAt: Time(wifi.UpdatedAt)}
+}
+
+// NewHotelSchedule builds the response for a hotel's schedule
+func NewHotelSchedule(schedule *models.HotelSchedule) *HotelScheduleResponse {
+	closed := schedule.ClosedDates
+	if closed == nil {
+		closed = []string{}
+	}
+	return &HotelScheduleResponse{CheckIn: schedule.CheckIn, CheckOut: schedule.CheckOut, ClosedDates: closed}
+}
+
+// ChainResponse is a hotel brand
+type ChainResponse struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	CreatedAt   *time.Time `json:"createdAt,omitempty"`
+}
+
+// NewChain builds the response for a chain
+func NewChain(chain *models.Chain) ChainResponse {
+	return ChainResponse{
+		ID:          chain.ID.Hex(),
+		Name:        chain.Name,
+		Description: chain.Description,
+		CreatedAt:   Time(chain.CreatedAt),
+	}
+}
+
+// AppointmentResponse is a booking
+type AppointmentResponse struct {
+	ID           string     `json:"id"`
+	ApptDate     time.Time  `json:"apptDate"`
+	User         string     `json:"user"`
+	Hotel        string     `json:"hotel"`
+	Status       string     `json:"status"`
+	WifiPassword string     `json:"wifiPassword,omitempty"`
+	CreatedAt    *time.Time `json:"createdAt,omitempty"`
+	ConfirmedAt  *time.Time `json:"confirmedAt,omitempty"`
+	ConfirmedBy  string     `json:"confirmedBy,omitempty"`
+	CancelledAt  *time.Time `json:"cancelledAt,omitempty"`
+}
+
+// NewAppointment builds the response for an appointment. Bookings made
+// before statuses existed are reported as pending.
+func NewAppointment(appointment *models.Appointment) AppointmentResponse {
+	status := appointment.Status
+	if status == "" {
+		status = models.AppointmentPending
+	}
+
+	response := AppointmentResponse{
+		ID:           appointment.ID.Hex(),
+		ApptDate:     appointment.ApptDate.UTC(),
+		User:         appointment.User.Hex(),
+		Hotel:        appointment.Hotel.Hex(),
+		Status:       status,
+		WifiPassword: appointment.WifiPassword,
+		CreatedAt:    Time(appointment.CreatedAt),
+		ConfirmedAt:  Time(appointment.ConfirmedAt),
+		CancelledAt:  Time(appointment.CancelledAt),
+	}
+	if !appointment.ConfirmedBy.IsZero() {
+		response.ConfirmedBy = appointment.ConfirmedBy.Hex()
+	}
+	return response
+}
+
+// PaymentResponse is a deposit taken for an appointment
+type PaymentResponse struct {
+	ID          string     `json:"id"`
+	Appointment string     `json:"appointment"`
+	User        string     `json:"user"`
+	Amount      int64      `json:"amount"`
+	Currency    string     `json:"currency"`
+	Status      string     `json:"status"`
+	Provider    string     `json:"provider"`
+	CreatedAt   *time.Time `json:"createdAt,omitempty"`
+	PaidAt      *time.Time `json:"paidAt,omitempty"`
+	RefundedAt  *time.Time `json:"refundedAt,omitempty"`
+}
+
+// NewPayment builds the response for a payment. The provider's reference
+// stays internal.
+func NewPayment(payment *models.Payment) PaymentResponse {
+	return PaymentResponse{
+		ID:          payment.ID.Hex(),
+		Appointment: payment.Appointment.Hex(),
+		User:        payment.User.Hex(),
+		Amount:      payment.Amount,
+		Currency:    payment.Currency,
+		Status:      payment.Status,
+		Provider:    payment.Provider,
+		CreatedAt:   Time(payment.CreatedAt),
+		PaidAt:      Time(payment.PaidAt),
+		RefundedAt:  Time(payment.RefundedAt),
+	}
+}
+
+// RoleResponse is a role and its permissions
+type RoleResponse struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Permissions []string   `json:"permissions"`
+	Builtin     bool       `json:"builtin"`
+	UpdatedAt   *time.Time `json:"updatedAt,omitempty"`
+}
+
+// NewRole builds the response for a role
+func NewRole(role *models.Role) RoleResponse {
+	permissions := role.Permissions
+	if permissions == nil {
+		permissions = []string{}
+	}
+	return RoleResponse{
+		Name:        role.Name,
+		Description: role.Description,
+		Permissions: permissions,
+		Builtin:     role.Builtin,
+		UpdatedAt:   Time(role.UpdatedAt),
+	}
+}
+
+// GrantResponse is a permission granted to a user on one resource
+type GrantResponse struct {
+	ID         string     `json:"id"`
+	User       string     `json:"user"`
+	Permission string     `json:"permission"`
+	Resource   string     `json:"resource"`
+	CreatedBy  string     `json:"createdBy"`
+	CreatedAt  *time.Time `json:"createdAt,omitempty"`
+}
+
+// NewGrant builds the response for a grant
+func NewGrant(grant *models.Grant) GrantResponse {
+	return GrantResponse{
+		ID:         grant.ID.Hex(),
+		User:       grant.User.Hex(),
+		Permission: grant.Permission,
+		Resource:   grant.Resource.Hex(),
+		CreatedBy:  grant.CreatedBy.Hex(),
+		CreatedAt:  Time(grant.CreatedAt),
+	}
+}
+
+// InviteResponse is an invitation to register. The token hash is never sent.
+type InviteResponse struct {
+	ID           string     `json:"id"`
+	Email        string     `json:"email,omitempty"`
+	Role         string     `json:"role"`
+	Organization string     `json:"organization,omitempty"`
+	CreatedBy    string     `json:"createdBy"`
+	CreatedAt    *time.Time `json:"createdAt,omitempty"`
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty"`
+	UsedAt       *time.Time `json:"usedAt,omitempty"`
+	UsedBy       string     `json:"usedBy,omitempty"`
+}
+
+// NewInvite builds the response for an invite
+func NewInvite(invite *models.Invite) InviteResponse {
+	return InviteResponse{
+		ID:           invite.ID.Hex(),
+		Email:        invite.Email,
+		Role:         invite.Role,
+		Organization: optionalID(invite.Organization),
+		CreatedBy:    invite.CreatedBy.Hex(),
+		CreatedAt:    Time(invite.CreatedAt),
+		ExpiresAt:    Time(invite.ExpiresAt),
+		UsedAt:       Time(invite.UsedAt),
+		UsedBy:       optionalID(invite.UsedBy),
+	}
+}
+
+// CreatedInviteResponse is a new invite with its token, shown only once
+type CreatedInviteResponse struct {
+	InviteResponse
+	Token string `json:"token"`
+	Link  string `json:"link"`
+}
+
+// APIKeyResponse is an API key. The key hash is never sent.
+type APIKeyResponse struct {
+	ID           string     `json:"id"`
+	Name         string     `json:"name"`
+	Prefix       string     `json:"prefix"`
+	Role         string     `json:"role"`
+	Scopes       []string   `json:"scopes"`
+	Organization string     `json:"organization,omitempty"`
+	Active       bool       `json:"active"`
+	CreatedBy    string     `json:"createdBy"`
+	CreatedAt    *time.Time `json:"createdAt,omitempty"`
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt   *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt    *time.Time `json:"revokedAt,omitempty"`
+}
+
+// NewAPIKey builds the response for an API key
+func NewAPIKey(key *models.APIKey) APIKeyResponse {
+	scopes := key.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return APIKeyResponse{
+		ID:           key.ID.Hex(),
+		Name:         key.Name,
+		Prefix:       key.Prefix,
+		Role:         key.Role,
+		Scopes:       scopes,
+		Organization: optionalID(key.Organization),
+		Active:       key.IsActive(),
+		CreatedBy:    key.CreatedBy.Hex(),
+		CreatedAt:    Time(key.CreatedAt),
+		ExpiresAt:    Time(key.ExpiresAt),
+		LastUsedAt:   Time(key.LastUsedAt),
+		RevokedAt:    Time(key.RevokedAt),
+	}
+}
+
+// CreatedAPIKeyResponse is a new API key with its plaintext value, shown
+// only once
+type CreatedAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+// SessionResponse is a login on one device. The IP address is only sent to
+// admins reviewing another user's sessions.
+type SessionResponse struct {
+	ID         string     `json:"id"`
+	Device     string     `json:"device"`
+	IP         string     `json:"ip,omitempty"`
+	Current    bool       `json:"current"`
+	CreatedAt  *time.Time `json:"createdAt,omitempty"`
+	LastSeenAt *time.Time `json:"lastSeenAt,omitempty"`
+}
+
+// NewSession builds the response for one of the caller's own sessions
+func NewSession(session *models.Session, current bool) SessionResponse {
+	return SessionResponse{
+		ID:         session.ID.Hex(),
+		Device:     session.Device,
+		Current:    current,
+		CreatedAt:  Time(session.CreatedAt),
+		LastSeenAt: Time(session.LastSeenAt),
+	}
+}
+
+// NewManagedSession builds the response for an admin, with the IP address
+func NewManagedSession(session *models.Session, current bool) SessionResponse {
+	response := NewSession(session, current)
+	response.IP = session.IP
+	return response
+}
+
+// LockoutResponse is a failed-login counter. It names what is counted: an
+// account, a client IP, an account from one IP, or a user's second factor.
+type LockoutResponse struct {
+	Kind          string     `json:"kind"`
+	Account       string     `json:"account,omitempty"`
+	IP            string     `json:"ip,omitempty"`
+	User          string     `json:"user,omitempty"`
+	Failures      int        `json:"failures"`
+	Locked        bool       `json:"locked"`
+	LastFailureAt *time.Time `json:"lastFailureAt,omitempty"`
+	LockedUntil   *time.Time `json:"lockedUntil,omitempty"`
+}
+
+// NewLockout builds the response for a failed-login counter
+func NewLockout(attempt *models.LoginAttempt, now time.Time) LockoutResponse {
+	kind, value, _ := strings.Cut(attempt.Key, ":")
+	response := LockoutResponse{
+		Kind:          kind,
+		Failures:      attempt.Failures,
+		Locked:        attempt.LockedUntil != 0 && now.Before(attempt.LockedUntil.Time()),
+		LastFailureAt: Time(attempt.LastFailureAt),
+		LockedUntil:   Time(attempt.LockedUntil),
+	}
+	switch kind {
+	case "account":
+		response.Account = value
+	case "ip":
+		response.IP = value
+	case "client":
+		response.Account, response.IP, _ = strings.Cut(value, "|")
+	case "mfa":
+		response.User = value
+	case "reset":
+		response.Account = value
+	case "reset-ip":
+		response.IP = value
+	}
+	return response
+}
+
+// DataExportResponse is a personal data export, without its contents or
+// token hash
+type DataExportResponse struct {
+	ID          string     `json:"id"`
+	User        string     `json:"user"`
+	Status      string     `json:"status"`
+	Size        int64      `json:"size,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   *time.Time `json:"createdAt,omitempty"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+}
+
+// NewDataExport builds the response for a data export
+func NewDataExport(export *models.DataExport) DataExportResponse {
+	return DataExportResponse{
+		ID:          export.ID.Hex(),
+		User:        export.User.Hex(),
+		Status:      export.Status,
+		Size:        export.Size,
+		Error:       export.Error,
+		CreatedAt:   Time(export.CreatedAt),
+		CompletedAt: Time(export.CompletedAt),
+		ExpiresAt:   Time(export.ExpiresAt),
+	}
+}
+
+// CreatedDataExportResponse is a requested export with its download link,
+// shown only once
+type CreatedDataExportResponse struct {
+	DataExportResponse
+	Link string `json:"link"`
+}
+
+// OrganizationResponse is an organization (hotel chain tenant)
+type OrganizationResponse struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Slug      string     `json:"slug"`
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+}
+
+// NewOrganization builds the response for an organization
+func NewOrganization(organization *models.Organization) OrganizationResponse {
+	return OrganizationResponse{
+		ID:        organization.ID.Hex(),
+		Name:      organization.Name,
+		Slug:      organization.Slug,
+		CreatedAt: Time(organization.CreatedAt),
+	}
+}
+
+// List converts a slice of models with the given constructor, returning an
+// empty list rather than null when there are none
+func List[M any, R any](items []M, convert func(*M) R) []R {
+	result := make([]R, 0, len(items))
+	for i := range items {
+		result = append(result, convert(&items[i]))
+	}
+	return result
+}
+
+// Time converts a stored date, leaving it out when unset
+func Time(dt primitive.DateTime) *time.Time {
+	if dt == 0 {
+		return nil
+	}
+	t := dt.Time().UTC()
+	return &t
+}
+
+// optionalID converts an object ID, leaving it out when unset
+func optionalID(id primitive.ObjectID) string {
+	if id.IsZero() {
+		return ""
+	}
+	return id.Hex()
+}
+
+// IDs converts object IDs to their hex strings
+func IDs(ids []primitive.ObjectID) []string {
+	result := make([]string, 0, len(ids))
+	for _, id := range ids {
+		result = append(result, id.Hex())
+	}
+	return result
+}
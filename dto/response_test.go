@@ -0,0 +1,66 @@
+package dto
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JongSinister/WTFiber/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestResponsesLeaveOutSecrets(t *testing.T) {
+	now := primitive.NewDateTimeFromTime(time.Now())
+	session := &models.Session{ID: primitive.NewObjectID(), Device: "Firefox on Linux", IP: "203.0.113.7", UserAgent: "Mozilla/5.0", CreatedAt: now}
+
+	tests := []struct {
+		name     string
+		response interface{}
+		secrets  []string
+	}{
+		{"invite", NewInvite(&models.Invite{ID: primitive.NewObjectID(), Hash: "invitehash", Role: "manager"}), []string{"invitehash"}},
+		{"API key", NewAPIKey(&models.APIKey{ID: primitive.NewObjectID(), Hash: "keyhash", Prefix: "ab12cd34"}), []string{"keyhash"}},
+		{"data export", NewDataExport(&models.DataExport{ID: primitive.NewObjectID(), Hash: "exporthash"}), []string{"exporthash"}},
+		{"own session", NewSession(session, true), []string{"203.0.113.7", "Mozilla"}},
+		{"managed session", NewManagedSession(session, false), []string{"Mozilla"}},
+	}
+
+	for _, tt := range tests {
+		body, err := json.Marshal(tt.response)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		for _, secret := range tt.secrets {
+			if strings.Contains(string(body), secret) {
+				t.Errorf("%s: response contains %q: %s", tt.name, secret, body)
+			}
+		}
+	}
+
+	if got := NewManagedSession(session, false).IP; got != "203.0.113.7" {
+		t.Errorf("managed session: got IP %q", got)
+	}
+}
+
+func TestNewLockout(t *testing.T) {
+	now := time.Now()
+	userID := primitive.NewObjectID()
+
+	tests := []struct {
+		key  string
+		want LockoutResponse
+	}{
+		{models.AccountAttemptKey("guest@example.com"), LockoutResponse{Kind: "account", Account: "guest@example.com"}},
+		{models.IPAttemptKey("10.0.0.1"), LockoutResponse{Kind: "ip", IP: "10.0.0.1"}},
+		{models.ClientAttemptKey("guest@example.com", "10.0.0.1"), LockoutResponse{Kind: "client", Account: "guest@example.com", IP: "10.0.0.1"}},
+		{models.MFAAttemptKey(userID), LockoutResponse{Kind: "mfa", User: userID.Hex()}},
+	}
+
+	for _, tt := range tests {
+		got := NewLockout(&models.LoginAttempt{Key: tt.key}, now)
+		if got.Kind != tt.want.Kind || got.Account != tt.want.Account || got.IP != tt.want.IP || got.User != tt.want.User {
+			t.Errorf("%s: got %+v, want %+v", tt.key, got, tt.want)
+		}
+	}
+}
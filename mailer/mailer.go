@@ -0,0 +1,116 @@
+// Package mailer sends transactional email. SMTP is used when SMTP_HOST is
+// set; otherwise messages are written to the log, which is enough for local
+// development. For tests, point SMTP_HOST/SMTP_PORT at a local sink such as
+// MailHog or Mailpit (localhost:1025).
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/JongSinister/WTFiber/config"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers a message or returns an error
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+var current Mailer = LogMailer{}
+
+// Setup selects the mailer from the environment
+func Setup() {
+	host := config.GetEnv("SMTP_HOST", "")
+	if host == "" {
+		log.Println("SMTP_HOST not set, emails will be logged")
+		current = LogMailer{}
+		return
+	}
+
+	current = &SMTPMailer{
+		Addr:     net.JoinHostPort(host, config.GetEnv("SMTP_PORT", "25")),
+		Username: config.GetEnv("SMTP_USERNAME", ""),
+		Password: config.GetEnv("SMTP_PASSWORD", ""),
+		From:     config.GetEnv("SMTP_FROM", "no-reply@wtfiber.local"),
+	}
+}
+
+// Send delivers a message with the configured mailer
+func Send(ctx context.Context, msg Message) error {
+	return current.Send(ctx, msg)
+}
+
+// SendAsync delivers a message in the background and logs failures, so the
+// response time does not reveal whether an email was sent
+func SendAsync(msg Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := Send(ctx, msg); err != nil {
+			log.Printf("Error sending %q to %s: %v", msg.Subject, msg.To, err)
+		}
+	}()
+}
+
+// LogMailer prints messages instead of sending them
+type LogMailer struct{}
+
+func (LogMailer) Send(ctx context.Context, msg Message) error {
+	log.Printf("Email to %s\nSubject: %s\n\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// SMTPMailer sends plain-text messages through an SMTP server
+type SMTPMailer struct {
+	Addr     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if strings.ContainsAny(msg.To, "\r\n") || strings.ContainsAny(msg.Subject, "\r\n") {
+		return fmt.Errorf("invalid header value")
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, _ := net.SplitHostPort(m.Addr)
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+
+	body := strings.Join([]string{
+		"From: " + m.From,
+		"To: " + msg.To,
+		"Subject: " + msg.Subject,
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		msg.Body,
+	}, "\r\n")
+
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(m.Addr, auth, m.From, []string{msg.To}, []byte(body))
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,131 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JongSinister/WTFiber/mailer/mailtest"
+)
+
+func TestSMTPMailerSend(t *testing.T) {
+	sink := mailtest.NewServer(t)
+
+	tests := []struct {
+		name     string
+		username string
+	}{
+		{"without authentication", ""},
+		{"with authentication", "mailer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &SMTPMailer{Addr: sink.Addr, Username: tt.username, Password: "secret", From: "no-reply@wtfiber.local"}
+			err := m.Send(context.Background(), Message{To: "guest@example.com", Subject: "Reset your password", Body: "Use the link below."})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := sink.Wait(t, 5*time.Second)
+			if got.Username != tt.username {
+				t.Errorf("authenticated as %q, want %q", got.Username, tt.username)
+			}
+			if got.From != "no-reply@wtfiber.local" || len(got.To) != 1 || got.To[0] != "guest@example.com" {
+				t.Errorf("envelope from %q to %v", got.From, got.To)
+			}
+			for _, want := range []string{
+				"From: no-reply@wtfiber.local\r\n",
+				"To: guest@example.com\r\n",
+				"Subject: Reset your password\r\n",
+				"Content-Type: text/plain; charset=UTF-8\r\n",
+				"\r\n\r\nUse the link below.",
+			} {
+				if !strings.Contains(got.Data, want) {
+					t.Errorf("message lacks %q:\n%s", want, got.Data)
+				}
+			}
+		})
+	}
+}
+
+func TestSMTPMailerRejectsHeaderInjection(t *testing.T) {
+	sink := mailtest.NewServer(t)
+	m := &SMTPMailer{Addr: sink.Addr, From: "no-reply@wtfiber.local"}
+
+	tests := []struct {
+		name string
+		msg  Message
+	}{
+		{"recipient", Message{To: "guest@example.com\r\nBcc: victim@example.com", Subject: "Hi"}},
+		{"subject", Message{To: "guest@example.com", Subject: "Hi\r\nBcc: victim@example.com"}},
+	}
+
+	for _, tt := range tests {
+		if err := m.Send(context.Background(), tt.msg); err == nil {
+			t.Errorf("%s: sent a message with a line break in a header", tt.name)
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+	if !sink.Empty() {
+		t.Error("the sink received a message")
+	}
+}
+
+func TestSMTPMailerHonoursContext(t *testing.T) {
+	// A server that accepts connections but never greets the client
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				<-done
+				conn.Close()
+			}()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	m := &SMTPMailer{Addr: listener.Addr().String(), From: "no-reply@wtfiber.local"}
+	err = m.Send(ctx, Message{To: "guest@example.com", Subject: "Hi"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want the context deadline", err)
+	}
+}
+
+func TestSetup(t *testing.T) {
+	sink := mailtest.NewServer(t)
+	saved := current
+	t.Cleanup(func() { current = saved })
+
+	t.Setenv("SMTP_HOST", "")
+	Setup()
+	if _, ok := current.(LogMailer); !ok {
+		t.Errorf("without SMTP_HOST got %T, want LogMailer", current)
+	}
+
+	t.Setenv("SMTP_HOST", sink.Host())
+	t.Setenv("SMTP_PORT", sink.Port())
+	t.Setenv("SMTP_FROM", "hotels@example.com")
+	Setup()
+	if err := Send(context.Background(), Message{To: "guest@example.com", Subject: "Hi", Body: "Hello"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := sink.Wait(t, 5*time.Second); got.From != "hotels@example.com" {
+		t.Errorf("sent from %q, want SMTP_FROM", got.From)
+	}
+}
@@ -0,0 +1,182 @@
+// Package mailtest runs a local SMTP sink for tests. It accepts every
+// message, and AUTH PLAIN with any credentials, and hands what it received
+// back to the test.
+package mailtest
+
+import (
+	"bufio"
+	"encoding/base64"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Message is an email the sink received
+type Message struct {
+	// Username is the name the client authenticated with, if it did
+	Username string
+	From     string
+	To       []string
+	// Data is the raw message: headers, a blank line and the body
+	Data string
+}
+
+// Server is an SMTP sink listening on a local port
+type Server struct {
+	Addr string
+
+	listener net.Listener
+	messages chan Message
+	wg       sync.WaitGroup
+}
+
+// NewServer starts a sink on a free local port. It is closed when the test
+// ends.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("mailtest: %v", err)
+	}
+
+	s := &Server{Addr: listener.Addr().String(), listener: listener, messages: make(chan Message, 16)}
+	s.wg.Add(1)
+	go s.serve()
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Host returns the host the sink listens on
+func (s *Server) Host() string {
+	host, _, _ := net.SplitHostPort(s.Addr)
+	return host
+}
+
+// Port returns the port the sink listens on
+func (s *Server) Port() string {
+	_, port, _ := net.SplitHostPort(s.Addr)
+	return port
+}
+
+// Wait returns the next message, failing the test if none arrives in time
+func (s *Server) Wait(t testing.TB, timeout time.Duration) Message {
+	t.Helper()
+	select {
+	case msg := <-s.messages:
+		return msg
+	case <-time.After(timeout):
+		t.Fatal("mailtest: no message received")
+		return Message{}
+	}
+}
+
+// Empty reports whether no message is waiting
+func (s *Server) Empty() bool {
+	return len(s.messages) == 0
+}
+
+// Close stops the sink
+func (s *Server) Close() {
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+// handle speaks just enough SMTP for net/smtp.SendMail
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	r := bufio.NewReader(conn)
+	reply := func(lines ...string) {
+		for _, line := range lines {
+			conn.Write([]byte(line + "\r\n"))
+		}
+	}
+
+	var msg Message
+	reply("220 mailtest ready")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb, arg, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			reply("250-mailtest", "250-AUTH PLAIN", "250 8BITMIME")
+		case "HELO", "NOOP":
+			reply("250 OK")
+		case "AUTH":
+			mechanism, initial, _ := strings.Cut(arg, " ")
+			decoded, err := base64.StdEncoding.DecodeString(initial)
+			if !strings.EqualFold(mechanism, "PLAIN") || err != nil {
+				reply("504 unsupported authentication")
+				continue
+			}
+			if parts := strings.Split(string(decoded), "\x00"); len(parts) == 3 {
+				msg.Username = parts[1]
+			}
+			reply("235 authenticated")
+		case "MAIL":
+			msg.From = address(arg)
+			reply("250 OK")
+		case "RCPT":
+			msg.To = append(msg.To, address(arg))
+			reply("250 OK")
+		case "DATA":
+			reply("354 end data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(line, "."))
+			}
+			msg.Data = data.String()
+			s.messages <- msg
+			msg = Message{Username: msg.Username}
+			reply("250 queued")
+		case "RSET":
+			msg = Message{Username: msg.Username}
+			reply("250 OK")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 command not implemented")
+		}
+	}
+}
+
+// address extracts the address from "FROM:<a@b>" or "TO:<a@b>"
+func address(arg string) string {
+	_, rest, _ := strings.Cut(arg, ":")
+	rest = strings.TrimSpace(rest)
+	if end := strings.IndexByte(rest, '>'); strings.HasPrefix(rest, "<") && end > 0 {
+		return rest[1:end]
+	}
+	return rest
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/JongSinister/WTFiber/config"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Account status is cached briefly so Protect does not read the user on
+// every request. Disabling an account also revokes its sessions, so the
+// cache only matters for tokens that slip through that.
+const accountCacheTTL = 30 * time.Second
+
+type accountStatus struct {
+	active    bool
+	checkedAt time.Time
+}
+
+var (
+	accountsMu sync.Mutex
+	accounts   = map[string]accountStatus{}
+)
+
+// accountActive reports whether the user exists and is neither disabled nor
+// deleted
+func accountActive(userID string) (bool, error) {
+	now := time.Now()
+	accountsMu.Lock()
+	status, ok := accounts[userID]
+	accountsMu.Unlock()
+	if ok && now.Sub(status.checkedAt) < accountCacheTTL {
+		return status.active, nil
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return false, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var user struct {
+		Disabled  bool               `bson:"disabled"`
+		DeletedAt primitive.DateTime `bson:"deletedAt,omitempty"`
+	}
+	opts := options.FindOne().SetProjection(bson.M{"disabled": 1, "deletedAt": 1})
+	err = config.DB.Collection("users").FindOne(ctx, bson.M{"_id": objectID}, opts).Decode(&user)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return false, err
+	}
+
+	active := err == nil && !user.Disabled && user.DeletedAt == 0
+	accountsMu.Lock()
+	accounts[userID] = accountStatus{active: active, checkedAt: now}
+	if len(accounts) > 10000 {
+		for id, status := range accounts {
+			if now.Sub(status.checkedAt) >= accountCacheTTL {
+				delete(accounts, id)
+			}
+		}
+	}
+	accountsMu.Unlock()
+
+	return active, nil
+}
+
+// ForgetAccount drops the cached status of a user after an admin changes it
+func ForgetAccount(userID string) {
+	accountsMu.Lock()
+	delete(accounts, userID)
+	accountsMu.Unlock()
+}
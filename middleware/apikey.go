@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/JongSinister/WTFiber/config"
+	"github.com/JongSinister/WTFiber/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	keyUsedMu sync.Mutex
+	keyUsed   = map[primitive.ObjectID]time.Time{}
+)
+
+// protectAPIKey authenticates a request made with the X-API-Key header. The
+// key is exposed to handlers as claims carrying its role and scopes; rbac
+// limits it to the permissions of its role that its scopes cover.
+func protectAPIKey(c *fiber.Ctx, plain string) error {
+	// 1) Find the key by its prefix
+	prefix, ok := models.ParseAPIKeyPrefix(plain)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid API key"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	key := new(models.APIKey)
+	err := config.DB.Collection("api_keys").FindOne(ctx, bson.M{"prefix": prefix}).Decode(key)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid API key"})
+	}
+
+	// 2) Compare the hash and check that the key is still usable
+	if subtle.ConstantTimeCompare([]byte(models.HashToken(plain)), []byte(key.Hash)) != 1 || !key.IsActive() {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid API key"})
+	}
+	touchAPIKey(key.ID)
+
+	// 3) Set the key in the locals
+	scopes := make([]interface{}, len(key.Scopes))
+	for i, scope := range key.Scopes {
+		scopes[i] = scope
+	}
+
+	claims := jwt.MapClaims{
+		"sub":    key.ID.Hex(),
+		"role":   key.Role,
+		"apiKey": key.ID.Hex(),
+		"scopes": scopes,
+		"amr":    []interface{}{"apikey"},
+	}
+	if !key.Organization.IsZero() {
+		claims["org"] = key.Organization.Hex()
+	}
+
+	// 4) Keys are confined to the organization they were issued for
+	scope, status, message := resolveTenant(c, claims)
+	if status != 0 {
+		return c.Status(status).JSON(fiber.Map{"error": message})
+	}
+	c.Locals("tenant", scope)
+
+	c.Locals("user", claims)
+	return c.Next()
+}
+
+// RequireUser refuses API keys on routes that act for the signed-in user:
+// a key's subject is the key itself, so records it created there would
+// belong to no one.
+func RequireUser(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(jwt.MapClaims)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error parsing claims"})
+	}
+	if _, isKey := claims["apiKey"]; isKey {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "API keys cannot act for a user"})
+	}
+	return c.Next()
+}
+
+// touchAPIKey records the key's last use, at most once a minute
+func touchAPIKey(id primitive.ObjectID) {
+	now := time.Now()
+	keyUsedMu.Lock()
+	if now.Sub(keyUsed[id]) < lastSeenInterval {
+		keyUsedMu.Unlock()
+		return
+	}
+	keyUsed[id] = now
+	keyUsedMu.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		_, err := config.DB.Collection("api_keys").UpdateOne(ctx,
+			bson.M{"_id": id},
+			bson.M{"$set": bson.M{"lastUsedAt": primitive.NewDateTimeFromTime(now)}},
+		)
+		if err != nil {
+			log.Printf("Error updating API key %s: %v", id.Hex(), err)
+		}
+	}()
+}
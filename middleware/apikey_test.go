@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestRequireUser(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		status int
+	}{
+		{"user token", jwt.MapClaims{"sub": "64b7f0c2a1e4d3b2c1a09f8e", "role": "user"}, fiber.StatusOK},
+		{"api key", jwt.MapClaims{"sub": "64b7f0c2a1e4d3b2c1a09f8f", "role": "admin", "apiKey": "64b7f0c2a1e4d3b2c1a09f8f"}, fiber.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/", func(c *fiber.Ctx) error {
+				c.Locals("user", tt.claims)
+				return c.Next()
+			}, RequireUser, func(c *fiber.Ctx) error {
+				return c.SendStatus(fiber.StatusOK)
+			})
+
+			resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Errorf("got %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
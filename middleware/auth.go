@@ -1,64 +1,179 @@
 package middleware
 
 import (
-	"os"
+	"context"
+	"strings"
+	"time"
 
+	"github.com/JongSinister/WTFiber/auth"
+	"github.com/JongSinister/WTFiber/config"
+	"github.com/JongSinister/WTFiber/rbac"
+	"github.com/JongSinister/WTFiber/revocation"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // Protected middleware
 func Protect(c *fiber.Ctx) error {
-	// 1) Get the token from the request header
-	authHeader := c.Get("Authorization")
-	if authHeader == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Missing or malformed token"})
+	// Service-to-service calls authenticate with an API key instead
+	if apiKey := c.Get("X-API-Key"); apiKey != "" {
+		return protectAPIKey(c, apiKey)
 	}
 
-	// 2) Parse the token
-	tokenString := authHeader[len("Bearer "):]
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return []byte(os.Getenv("JWT_SECRET")), nil
-	})
+	// 1) Get the token from the configured sources; a cookie also needs the
+	// matching CSRF token
+	tokenString, source, err := extractToken(c)
+	if err != nil || tokenString == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Missing or malformed token"})
+	}
+	if source == TokenFromCookie && !ValidCSRF(c) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Invalid CSRF token"})
+	}
 
-	if err != nil || !token.Valid {
+	// 2) Verify the token and extract the claims
+	claims, err := auth.ParseAccessToken(tokenString)
+	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired token"})
 	}
 
-	// 3) Extract the claims
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error parsing claims"})
+	// 3) Reject tokens revoked by a logout or a killed session
+	jti, _ := claims["jti"].(string)
+	sessionID, _ := claims["sid"].(string)
+	userID, _ := claims["sub"].(string)
+	issuedAt, _ := claims["iat"].(float64)
+	if revocation.IsRevoked(jti, sessionID, userID, time.Unix(int64(issuedAt), 0)) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token has been revoked"})
+	}
+	touchSession(sessionID)
+
+	// 4) Reject users whose account was disabled or deleted
+	active, err := accountActive(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error checking account"})
+	}
+	if !active {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Account is disabled"})
+	}
+
+	// 5) Resolve the organization the request acts for
+	scope, status, message := resolveTenant(c, claims)
+	if status != 0 {
+		return c.Status(status).JSON(fiber.Map{"error": message})
+	}
+	c.Locals("tenant", scope)
+
+	// 6) Admins acting as the user go through extra checks
+	if actorID, actorSession, ok := auth.Actor(claims); ok {
+		return protectImpersonation(c, claims, actorID, actorSession)
 	}
 
-	// 4) Set the user in the locals
+	// 7) Set the user in the locals
 	c.Locals("user", claims)
 	return c.Next()
 }
 
-// Authorize checks if the user has the required role
-func Authorize(roles ...string) fiber.Handler {
+// RequirePermission lets the request through when the principal holds any
+// of the permissions. Handlers narrow ":own" permissions to the user's
+// records.
+func RequirePermission(perms ...string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		// 1) Get the user from the locals
-		userClaims, ok := c.Locals("user").(jwt.MapClaims)
-		if !ok {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error parsing claims"})
-		}
-
-		// 2) Check if the user has the required role
-		userRole, ok := userClaims["role"].(string)
-		if !ok {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error parsing role"})
+		// 1) Get the principal from the locals
+		principal, status, message := Principal(c)
+		if status != 0 {
+			return c.Status(status).JSON(fiber.Map{"error": message})
 		}
 
-		for _, role := range roles {
-			if role == userRole {
+		// 2) Check the role's permissions
+		for _, perm := range perms {
+			if principal.Can(perm) {
 				return c.Next()
 			}
 		}
 
-		// 3) Return an error if the user does not have the required role
+		// 3) Return an error if the principal holds none of them
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+	}
+}
+
+// RequireMFA applies the MFA policy to routes whose handlers work out the
+// permissions themselves, so the principal learns why it was refused
+func RequireMFA(c *fiber.Ctx) error {
+	if _, status, message := Principal(c); status != 0 {
+		return c.Status(status).JSON(fiber.Map{"error": message})
+	}
+	return c.Next()
+}
+
+// RequirePermissionOn lets the request through when the principal holds the
+// permission through its role, or was granted it on the resource whose ID is
+// in the given route parameter
+func RequirePermissionOn(perm, param string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// 1) Get the principal from the locals
+		principal, status, message := Principal(c)
+		if status != 0 {
+			return c.Status(status).JSON(fiber.Map{"error": message})
+		}
+
+		// 2) Check the role, then the grants on the resource
+		resource, err := primitive.ObjectIDFromHex(c.Params(param))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID Format"})
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		allowed, err := principal.CanOn(ctx, perm, resource)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error checking permissions"})
+		}
+		if !allowed {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+		}
+		return c.Next()
+	}
+}
+
+// Principal reads the principal Protect stored and applies the MFA
+// policy: roles it covers need a session that passed a second factor. A
+// non-zero status means the request must be refused with the message. Every
+// permission check goes through it, in routes and in handlers alike.
+func Principal(c *fiber.Ctx) (rbac.Principal, int, string) {
+	claims, ok := c.Locals("user").(jwt.MapClaims)
+	if !ok {
+		return rbac.Principal{}, fiber.StatusInternalServerError, "Error parsing claims"
+	}
+
+	principal := rbac.FromClaims(claims)
+	if principal.Role == "" {
+		return principal, fiber.StatusInternalServerError, "Error parsing role"
+	}
+
+	if !principal.APIKey && requiresMFA(principal.Role) && !hasAMR(claims, "otp") {
+		return principal, fiber.StatusForbidden, "Multi-factor authentication required"
+	}
+	return principal, 0, ""
+}
 
+// requiresMFA reports whether the MFA policy covers the role
+func requiresMFA(role string) bool {
+	for _, required := range config.MFARequiredRoles() {
+		if strings.TrimSpace(required) == role {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAMR reports whether the token was issued after the given authentication method
+func hasAMR(claims jwt.MapClaims, method string) bool {
+	amr, _ := claims["amr"].([]interface{})
+	for _, m := range amr {
+		if m == method {
+			return true
+		}
 	}
+	return false
 }
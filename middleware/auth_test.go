@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/valyala/fasthttp"
+)
+
+func TestPrincipalMFAPolicy(t *testing.T) {
+	t.Setenv("MFA_REQUIRED_ROLES", "admin, manager")
+
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		status int
+	}{
+		{"admin without a second factor", jwt.MapClaims{"role": "admin", "amr": []interface{}{"pwd"}}, fiber.StatusForbidden},
+		{"admin after a second factor", jwt.MapClaims{"role": "admin", "amr": []interface{}{"pwd", "otp"}}, 0},
+		{"manager listed after a space", jwt.MapClaims{"role": "manager", "amr": []interface{}{"oidc"}}, fiber.StatusForbidden},
+		{"role outside the policy", jwt.MapClaims{"role": "user", "amr": []interface{}{"pwd"}}, 0},
+		{"API key with a covered role", jwt.MapClaims{"role": "admin", "apiKey": "k", "amr": []interface{}{"apikey"}}, 0},
+		{"no role", jwt.MapClaims{"amr": []interface{}{"otp"}}, fiber.StatusInternalServerError},
+	}
+
+	app := fiber.New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := app.AcquireCtx(&fasthttp.RequestCtx{})
+			defer app.ReleaseCtx(c)
+			c.Locals("user", tt.claims)
+
+			if _, status, _ := Principal(c); status != tt.status {
+				t.Errorf("got %d, want %d", status, tt.status)
+			}
+		})
+	}
+}
+
+func TestRequireMFA(t *testing.T) {
+	t.Setenv("MFA_REQUIRED_ROLES", "admin")
+
+	tests := []struct {
+		name   string
+		amr    []interface{}
+		status int
+	}{
+		{"password only", []interface{}{"pwd"}, fiber.StatusForbidden},
+		{"second factor", []interface{}{"pwd", "otp"}, fiber.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Put("/hotels/:id", func(c *fiber.Ctx) error {
+				c.Locals("user", jwt.MapClaims{"role": "admin", "amr": tt.amr})
+				return c.Next()
+			}, RequireMFA, func(c *fiber.Ctx) error {
+				return c.SendStatus(fiber.StatusOK)
+			})
+
+			resp, err := app.Test(httptest.NewRequest("PUT", "/hotels/1", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Errorf("got %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
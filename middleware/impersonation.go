@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/JongSinister/WTFiber/audit"
+	"github.com/JongSinister/WTFiber/auth"
+	"github.com/JongSinister/WTFiber/models"
+	"github.com/JongSinister/WTFiber/revocation"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Requests an impersonating admin may make besides reads, by method and route
+// pattern. Everything else that changes state, such as credentials, sessions,
+// money or other accounts, stays with the real user.
+var impersonationWrites = map[string]bool{
+	"PUT /api/v1/auth/me":                       true,
+	"POST /api/v1/hotels/:hotelId/appointments": true,
+	"PUT /api/v1/appointments/:id":              true,
+}
+
+// Reads an impersonating admin may not make because they reveal secrets
+var impersonationHiddenReads = map[string]bool{
+	"GET /api/v1/auth/mfa/qr.png": true,
+}
+
+// protectImpersonation finishes Protect for a token an admin uses to act as
+// a user. The admin must still be allowed in, destructive requests are
+// refused and every request is written to the audit log.
+func protectImpersonation(c *fiber.Ctx, claims jwt.MapClaims, actorID, actorSession string) error {
+	// 1) The token dies with the admin's session or account
+	issuedAt, _ := claims["iat"].(float64)
+	if revocation.IsRevoked("", actorSession, actorID, time.Unix(int64(issuedAt), 0)) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token has been revoked"})
+	}
+	active, err := accountActive(actorID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error checking account"})
+	}
+	if !active {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Account is disabled"})
+	}
+
+	// 2) Mark the response and refuse destructive requests
+	userID, _ := claims["sub"].(string)
+	c.Set("X-Impersonated-By", actorID)
+
+	if !impersonationAllows(c.Method(), c.Route().Path) {
+		recordImpersonation(c, actorID, userID, fiber.StatusForbidden)
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Not allowed while impersonating"})
+	}
+
+	// 3) Handle the request as the user, then log it
+	c.Locals("user", claims)
+	err = c.Next()
+	recordImpersonation(c, actorID, userID, c.Response().StatusCode())
+	return err
+}
+
+// impersonationAllows reports whether a request may be made while
+// impersonating. It matches the registered route pattern rather than the
+// request path, which routing compares case-insensitively.
+func impersonationAllows(method, route string) bool {
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+	if method == http.MethodGet {
+		return !impersonationHiddenReads[method+" "+route]
+	}
+	return impersonationWrites[method+" "+route]
+}
+
+// recordImpersonation writes a request made under impersonation to the audit log
+func recordImpersonation(c *fiber.Ctx, actorID, userID string, status int) {
+	actor, _ := primitive.ObjectIDFromHex(actorID)
+	subject, _ := primitive.ObjectIDFromHex(userID)
+
+	audit.Record(models.AuditEntry{
+		Action:    audit.ImpersonationRequest,
+		Actor:     actor,
+		Subject:   subject,
+		IP:        strings.Clone(c.IP()),
+		UserAgent: strings.Clone(c.Get("User-Agent")),
+		Details: map[string]interface{}{
+			"method": strings.Clone(c.Method()),
+			"path":   strings.Clone(c.Path()),
+			"status": status,
+		},
+	})
+}
+
+// Impersonating reports whether the request was made with an impersonation token
+func Impersonating(c *fiber.Ctx) bool {
+	claims, ok := c.Locals("user").(jwt.MapClaims)
+	if !ok {
+		return false
+	}
+	_, _, ok = auth.Actor(claims)
+	return ok
+}
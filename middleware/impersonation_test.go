@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestImpersonationAllows(t *testing.T) {
+	// Route the requests the way routes.Setup does, so the patterns are the
+	// ones registered through the groups
+	app := fiber.New()
+	check := func(c *fiber.Ctx) error {
+		if !impersonationAllows(c.Method(), c.Route().Path) {
+			return c.SendStatus(fiber.StatusForbidden)
+		}
+		return c.SendStatus(fiber.StatusOK)
+	}
+	api := app.Group("/api/v1")
+	api.Group("/appointments").Get("/:id", check)
+	api.Group("/appointments").Put("/:id", check)
+	api.Group("/appointments").Post("/:id/payments", check)
+	api.Group("/hotels").Post("/:hotelId/appointments", check)
+	api.Group("/auth").Put("/me", check)
+	api.Group("/auth").Put("/password", check)
+	api.Group("/auth").Get("/mfa/qr.png", check)
+	api.Group("/auth").Post("/logout-all", check)
+	api.Group("/payments").Post("/:id/refund", check)
+	api.Group("/admin").Delete("/sessions/:id", check)
+
+	tests := []struct {
+		method, path string
+		status       int
+	}{
+		{"GET", "/api/v1/appointments/1", fiber.StatusOK},
+		{"HEAD", "/api/v1/appointments/1", fiber.StatusOK},
+		{"PUT", "/api/v1/appointments/1", fiber.StatusOK},
+		{"POST", "/api/v1/hotels/1/appointments", fiber.StatusOK},
+		{"PUT", "/api/v1/auth/me", fiber.StatusOK},
+		{"POST", "/api/v1/appointments/1/payments", fiber.StatusForbidden},
+		{"POST", "/API/V1/Appointments/1/Payments", fiber.StatusForbidden},
+		{"PUT", "/API/v1/auth/PASSWORD", fiber.StatusForbidden},
+		{"POST", "/api/v1/auth/logout-all", fiber.StatusForbidden},
+		{"POST", "/api/v1/payments/1/refund", fiber.StatusForbidden},
+		{"DELETE", "/api/v1/admin/sessions/1", fiber.StatusForbidden},
+		{"GET", "/api/v1/auth/mfa/qr.png", fiber.StatusForbidden},
+		{"GET", "/api/v1/AUTH/mfa/QR.png", fiber.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest(tt.method, tt.path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s %s: got %d, want %d", tt.method, tt.path, resp.StatusCode, tt.status)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/JongSinister/WTFiber/config"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Sessions are written to at most once per interval to keep Protect cheap
+const lastSeenInterval = time.Minute
+
+var (
+	lastSeenMu sync.Mutex
+	lastSeen   = map[string]time.Time{}
+)
+
+// touchSession records that the session was used just now
+func touchSession(sessionID string) {
+	objectID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	lastSeenMu.Lock()
+	if now.Sub(lastSeen[sessionID]) < lastSeenInterval {
+		lastSeenMu.Unlock()
+		return
+	}
+	lastSeen[sessionID] = now
+	if len(lastSeen) > 10000 {
+		for id, seen := range lastSeen {
+			if now.Sub(seen) >= lastSeenInterval {
+				delete(lastSeen, id)
+			}
+		}
+	}
+	lastSeenMu.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		_, err := config.DB.Collection("sessions").UpdateOne(ctx,
+			bson.M{"_id": objectID},
+			bson.M{"$set": bson.M{"lastSeenAt": primitive.NewDateTimeFromTime(now)}},
+		)
+		if err != nil {
+			log.Printf("Error updating session %s: %v", sessionID, err)
+		}
+	}()
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"github.com/JongSinister/WTFiber/rbac"
+	"github.com/JongSinister/WTFiber/tenant"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Tenant resolves the organization of a public request from the
+// X-Organization header; without it the default tenant is used
+func Tenant(c *fiber.Ctx) error {
+	scope := tenant.Default
+	if header := c.Get(tenant.Header); header != "" {
+		organization, err := primitive.ObjectIDFromHex(header)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid organization"})
+		}
+		scope = tenant.Of(organization)
+	}
+
+	c.Locals("tenant", scope)
+	return c.Next()
+}
+
+// resolveTenant works out the organization an authenticated request acts
+// for. Members of an organization are confined to it. Principals outside
+// every organization who may manage organizations are super-admins: they
+// see all tenants unless they pick one with the X-Organization header.
+func resolveTenant(c *fiber.Ctx, claims jwt.MapClaims) (tenant.Scope, int, string) {
+	if org, _ := claims["org"].(string); org != "" {
+		organization, err := primitive.ObjectIDFromHex(org)
+		if err != nil {
+			return tenant.Scope{}, fiber.StatusUnauthorized, "Invalid organization"
+		}
+		return tenant.Of(organization), 0, ""
+	}
+
+	if !rbac.FromClaims(claims).Can("organizations:manage") {
+		return tenant.Default, 0, ""
+	}
+
+	scope := tenant.Scope{All: true, SuperAdmin: true}
+	if header := c.Get(tenant.Header); header != "" {
+		organization, err := primitive.ObjectIDFromHex(header)
+		if err != nil {
+			return tenant.Scope{}, fiber.StatusBadRequest, "Invalid organization"
+		}
+		scope = tenant.Scope{Organization: organization, SuperAdmin: true}
+	}
+	return scope, 0, ""
+}
+
+// RequireSuperAdmin lets the request through only for principals who may
+// work across organizations
+func RequireSuperAdmin(c *fiber.Ctx) error {
+	if _, status, message := Principal(c); status != 0 {
+		return c.Status(status).JSON(fiber.Map{"error": message})
+	}
+	if scope, ok := c.Locals("tenant").(tenant.Scope); !ok || !scope.SuperAdmin {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+	}
+	return c.Next()
+}
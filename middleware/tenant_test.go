@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/JongSinister/WTFiber/tenant"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRequireSuperAdmin(t *testing.T) {
+	t.Setenv("MFA_REQUIRED_ROLES", "admin")
+
+	tests := []struct {
+		name   string
+		scope  interface{}
+		status int
+	}{
+		{"super-admin across tenants", tenant.Scope{All: true, SuperAdmin: true}, fiber.StatusOK},
+		{"super-admin picking an organization", tenant.Scope{Organization: primitive.NewObjectID(), SuperAdmin: true}, fiber.StatusOK},
+		{"organization admin", tenant.Of(primitive.NewObjectID()), fiber.StatusForbidden},
+		{"default tenant admin", tenant.Default, fiber.StatusForbidden},
+		{"no scope resolved", nil, fiber.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Put("/roles/:name", func(c *fiber.Ctx) error {
+				c.Locals("user", jwt.MapClaims{"role": "admin", "amr": []interface{}{"pwd", "otp"}})
+				if tt.scope != nil {
+					c.Locals("tenant", tt.scope)
+				}
+				return c.Next()
+			}, RequireSuperAdmin, func(c *fiber.Ctx) error {
+				return c.SendStatus(fiber.StatusOK)
+			})
+
+			resp, err := app.Test(httptest.NewRequest("PUT", "/roles/support", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Errorf("got %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
+
+func TestResolveTenant(t *testing.T) {
+	org := primitive.NewObjectID()
+
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		header string
+		want   tenant.Scope
+		status int
+	}{
+		{"organization member", jwt.MapClaims{"role": "admin", "org": org.Hex()}, "", tenant.Of(org), 0},
+		{"member cannot pick another organization", jwt.MapClaims{"role": "admin", "org": org.Hex()}, primitive.NewObjectID().Hex(), tenant.Of(org), 0},
+		{"malformed organization", jwt.MapClaims{"role": "admin", "org": "nope"}, "", tenant.Scope{}, fiber.StatusUnauthorized},
+		{"default tenant user", jwt.MapClaims{"role": "user"}, org.Hex(), tenant.Default, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			var got tenant.Scope
+			var status int
+			app.Get("/", func(c *fiber.Ctx) error {
+				got, status, _ = resolveTenant(c, tt.claims)
+				return nil
+			})
+
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.header != "" {
+				req.Header.Set(tenant.Header, tt.header)
+			}
+			if _, err := app.Test(req); err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want || status != tt.status {
+				t.Errorf("got (%+v, %d), want (%+v, %d)", got, status, tt.want, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"errors"
+	"strings"
+
+	"github.com/JongSinister/WTFiber/config"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Names of the cookies and header that carry the access token and the CSRF
+// token paired with it
+const (
+	TokenCookie = "token"
+	CSRFCookie  = "csrf_token"
+	CSRFHeader  = "X-CSRF-Token"
+)
+
+// Places an access token can be read from
+const (
+	TokenFromHeader = "header"
+	TokenFromCookie = "cookie"
+	TokenFromQuery  = "query"
+)
+
+// tokenQueryParam is the query parameter read by the query source
+const tokenQueryParam = "access_token"
+
+// Routes, by pattern, that read the token from the query string. They only
+// serve downloads a client may have to fetch by plain link; everywhere else
+// a token in the URL would end up in logs and browser history.
+var queryTokenRoutes = map[string]bool{
+	"/api/v1/appointments/:id/invoice.pdf": true,
+}
+
+var errMalformedToken = errors.New("malformed token")
+
+// tokenExtractors read the access token from one place. They return an empty
+// token when the place does not hold one.
+var tokenExtractors = map[string]func(c *fiber.Ctx) (string, error){
+	TokenFromHeader: tokenFromHeader,
+	TokenFromCookie: func(c *fiber.Ctx) (string, error) { return c.Cookies(TokenCookie), nil },
+	TokenFromQuery:  tokenFromQuery,
+}
+
+// extractToken returns the access token from the first configured source
+// that holds one, and the name of that source
+func extractToken(c *fiber.Ctx) (string, string, error) {
+	for _, source := range config.TokenSources() {
+		source = strings.TrimSpace(source)
+		extract, ok := tokenExtractors[source]
+		if !ok {
+			continue
+		}
+
+		token, err := extract(c)
+		if err != nil {
+			return "", source, err
+		}
+		if token != "" {
+			return token, source, nil
+		}
+	}
+	return "", "", nil
+}
+
+// tokenFromHeader reads an "Authorization: Bearer <token>" header. The scheme
+// is matched case-insensitively; any other value is malformed.
+func tokenFromHeader(c *fiber.Ctx) (string, error) {
+	header := strings.TrimSpace(c.Get(fiber.HeaderAuthorization))
+	if header == "" {
+		return "", nil
+	}
+
+	scheme, token, found := strings.Cut(header, " ")
+	token = strings.TrimSpace(token)
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" || strings.ContainsAny(token, " \t") {
+		return "", errMalformedToken
+	}
+	return token, nil
+}
+
+// tokenFromQuery reads the access_token query parameter on GET requests to
+// the listed download routes only, so a leaked link cannot be used to change
+// anything or to read the rest of the API
+func tokenFromQuery(c *fiber.Ctx) (string, error) {
+	if !queryTokenAllowed(c.Method(), c.Route().Path) {
+		return "", nil
+	}
+	return c.Query(tokenQueryParam), nil
+}
+
+// queryTokenAllowed reports whether a request to the route pattern may carry
+// its token in the query string
+func queryTokenAllowed(method, route string) bool {
+	if method != fiber.MethodGet && method != fiber.MethodHead {
+		return false
+	}
+	return queryTokenRoutes[route]
+}
+
+// ValidCSRF reports whether a request authenticated by cookie may proceed.
+// Browsers attach cookies to cross-site requests, so requests that change
+// state must echo the csrf_token cookie in the X-CSRF-Token header, which
+// another site cannot read.
+func ValidCSRF(c *fiber.Ctx) bool {
+	switch c.Method() {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+		return true
+	}
+
+	cookie, header := c.Cookies(CSRFCookie), c.Get(CSRFHeader)
+	if cookie == "" || header == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(cookie), []byte(header)) == 1
+}
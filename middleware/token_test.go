@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestExtractToken(t *testing.T) {
+	t.Setenv("TOKEN_SOURCES", "header, cookie, query")
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		header string
+		cookie string
+		token  string
+		source string
+		ok     bool
+	}{
+		{"bearer header", "GET", "/api/v1/auth/me", "Bearer abc", "", "abc", TokenFromHeader, true},
+		{"lowercase scheme", "GET", "/api/v1/auth/me", "bearer abc", "", "abc", TokenFromHeader, true},
+		{"header wins over cookie", "GET", "/api/v1/auth/me", "Bearer abc", "def", "abc", TokenFromHeader, true},
+		{"basic scheme", "GET", "/api/v1/auth/me", "Basic abc", "", "", TokenFromHeader, false},
+		{"cookie", "GET", "/api/v1/auth/me", "", "def", "def", TokenFromCookie, true},
+		{"query on a download", "GET", "/api/v1/appointments/42/invoice.pdf?access_token=ghi", "", "", "ghi", TokenFromQuery, true},
+		{"query on another read", "GET", "/api/v1/auth/me?access_token=ghi", "", "", "", "", true},
+		{"query on a write", "POST", "/api/v1/auth/logout?access_token=ghi", "", "", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var token, source string
+			var err error
+			app := fiber.New()
+			handler := func(c *fiber.Ctx) error {
+				token, source, err = extractToken(c)
+				return nil
+			}
+			app.Get("/api/v1/auth/me", handler)
+			app.Get("/api/v1/appointments/:id/invoice.pdf", handler)
+			app.Post("/api/v1/auth/logout", handler)
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(fiber.HeaderAuthorization, tt.header)
+			}
+			if tt.cookie != "" {
+				req.Header.Set(fiber.HeaderCookie, TokenCookie+"="+tt.cookie)
+			}
+			if _, testErr := app.Test(req); testErr != nil {
+				t.Fatal(testErr)
+			}
+
+			if token != tt.token || source != tt.source || (err == nil) != tt.ok {
+				t.Errorf("got (%q, %q, %v), want (%q, %q, ok=%v)", token, source, err, tt.token, tt.source, tt.ok)
+			}
+		})
+	}
+}
+
+func TestQueryTokenAllowed(t *testing.T) {
+	tests := []struct {
+		method string
+		route  string
+		want   bool
+	}{
+		{"GET", "/api/v1/appointments/:id/invoice.pdf", true},
+		{"HEAD", "/api/v1/appointments/:id/invoice.pdf", true},
+		{"POST", "/api/v1/appointments/:id/invoice.pdf", false},
+		{"GET", "/api/v1/auth/me", false},
+		{"GET", "/api/v1/users/:id", false},
+		{"GET", "/api/v1/appointments/42/invoice.pdf", false},
+	}
+
+	for _, tt := range tests {
+		if got := queryTokenAllowed(tt.method, tt.route); got != tt.want {
+			t.Errorf("%s %s: got %v, want %v", tt.method, tt.route, got, tt.want)
+		}
+	}
+}
+
+func TestValidCSRF(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		cookie string
+		header string
+		want   bool
+	}{
+		{"safe method", "GET", "", "", true},
+		{"matching token", "POST", "s3cret", "s3cret", true},
+		{"missing header", "POST", "s3cret", "", false},
+		{"missing cookie", "DELETE", "", "s3cret", false},
+		{"mismatched token", "PUT", "s3cret", "other", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got bool
+			app := fiber.New()
+			app.All("/", func(c *fiber.Ctx) error {
+				got = ValidCSRF(c)
+				return nil
+			})
+
+			req := httptest.NewRequest(tt.method, "/", nil)
+			if tt.cookie != "" {
+				req.Header.Set(fiber.HeaderCookie, CSRFCookie+"="+tt.cookie)
+			}
+			if tt.header != "" {
+				req.Header.Set(CSRFHeader, tt.header)
+			}
+			if _, err := app.Test(req); err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProtectRequiresCSRFWithCookie(t *testing.T) {
+	t.Setenv("TOKEN_SOURCES", "header,cookie")
+
+	app := fiber.New()
+	app.Post("/api/v1/auth/logout", Protect, func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	req := httptest.NewRequest("POST", "/api/v1/auth/logout", nil)
+	req.Header.Set(fiber.HeaderCookie, TokenCookie+"=abc; "+CSRFCookie+"=s3cret")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusForbidden {
+		t.Errorf("got %d, want %d", resp.StatusCode, fiber.StatusForbidden)
+	}
+}
@@ -13,4 +13,20 @@ type Appointment struct {
 	Hotel        primitive.ObjectID `bson:"hotel" validate:"required"`
 	WifiPassword string             `bson:"wifiPassword,omitempty"`
 	CreatedAt    primitive.DateTime `bson:"createdAt,omitempty"`
+
+	// Copied from the hotel so appointment queries can be scoped directly
+	Organization primitive.ObjectID `bson:"organization,omitempty"`
+
+	// Bookings start pending until the hotel confirms them
+	Status      string             `bson:"status,omitempty"`
+	ConfirmedAt primitive.DateTime `bson:"confirmedAt,omitempty"`
+	ConfirmedBy primitive.ObjectID `bson:"confirmedBy,omitempty"`
+	CancelledAt primitive.DateTime `bson:"cancelledAt,omitempty"`
 }
+
+// Appointment statuses
+const (
+	AppointmentPending   = "pending"
+	AppointmentConfirmed = "confirmed"
+	AppointmentCancelled = "cancelled"
+)
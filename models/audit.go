@@ -0,0 +1,18 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuditEntry records a security-relevant action. Actor is who did it and
+// Subject the user it was done to; they are the same for self-service actions.
+type AuditEntry struct {
+	ID        primitive.ObjectID     `bson:"_id,omitempty"`
+	Action    string                 `bson:"action"`
+	Actor     primitive.ObjectID     `bson:"actor,omitempty"`
+	Subject   primitive.ObjectID     `bson:"subject,omitempty"`
+	IP        string                 `bson:"ip,omitempty"`
+	UserAgent string                 `bson:"userAgent,omitempty"`
+	Details   map[string]interface{} `bson:"details,omitempty"`
+	CreatedAt primitive.DateTime     `bson:"createdAt"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Chain is a hotel brand. An organization can run several; hotels may
+// belong to one of their organization's chains.
+type Chain struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"`
+	Name         string             `bson:"name"`
+	Description  string             `bson:"description,omitempty"`
+	Organization primitive.ObjectID `bson:"organization,omitempty"`
+	CreatedAt    primitive.DateTime `bson:"createdAt"`
+}
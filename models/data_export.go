@@ -0,0 +1,29 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Data export statuses
+const (
+	DataExportPending = "pending"
+	DataExportReady   = "ready"
+	DataExportFailed  = "failed"
+)
+
+// DataExport is a ZIP of everything stored about a user, built in the
+// background and stored in GridFS under the export's ID. It is downloaded with
+// a token whose hash is kept here and is removed by a TTL index once it
+// expires.
+type DataExport struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"`
+	User        primitive.ObjectID `bson:"user"`
+	RequestedBy primitive.ObjectID `bson:"requestedBy"`
+	Hash        string             `bson:"hash" json:"-"`
+	Status      string             `bson:"status"`
+	Size        int64              `bson:"size,omitempty"`
+	Error       string             `bson:"error,omitempty"`
+	CreatedAt   primitive.DateTime `bson:"createdAt"`
+	CompletedAt primitive.DateTime `bson:"completedAt,omitempty"`
+	ExpiresAt   primitive.DateTime `bson:"expiresAt"`
+}
@@ -3,6 +3,7 @@ package models
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -18,6 +19,55 @@ type Hotel struct {
 	PostalCode string             `bson:"postalcode" validate:"required,len=5"`
 	Tel        string             `bson:"tel,omitempty"`
 	Region     string             `bson:"region" validate:"required"`
+	Price      int64              `bson:"price,omitempty"` // per night, in minor currency units
+
+	// The hotel chain that owns the hotel; unset for the default tenant
+	Organization primitive.ObjectID `bson:"organization,omitempty"`
+	// The brand the hotel trades under, if any
+	Chain primitive.ObjectID `bson:"chain,omitempty"`
+
+	// Users with the manager role who run this hotel
+	Managers []primitive.ObjectID `bson:"managers,omitempty"`
+	Wifi     *HotelWifi           `bson:"wifi,omitempty"`
+	Schedule *HotelSchedule       `bson:"schedule,omitempty"`
+}
+
+// HotelWifi is the guest network; each appointment gets its own password
+type HotelWifi struct {
+	SSID      string             `bson:"ssid"`
+	UpdatedAt primitive.DateTime `bson:"updatedAt"`
+}
+
+// HotelSchedule holds check-in/check-out times (HH:MM) and the dates
+// (YYYY-MM-DD) the hotel takes no bookings
+type HotelSchedule struct {
+	CheckIn     string   `bson:"checkIn"`
+	CheckOut    string   `bson:"checkOut"`
+	ClosedDates []string `bson:"closedDates,omitempty"`
+}
+
+// HasManager reports whether the user manages the hotel
+func (hotel *Hotel) HasManager(userID primitive.ObjectID) bool {
+	for _, id := range hotel.Managers {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// IsClosedOn reports whether the schedule closes the hotel on the date
+func (hotel *Hotel) IsClosedOn(date time.Time) bool {
+	if hotel.Schedule == nil {
+		return false
+	}
+	day := date.Format("2006-01-02")
+	for _, closed := range hotel.Schedule.ClosedDates {
+		if closed == day {
+			return true
+		}
+	}
+	return false
 }
 
 // PreDeleteHook performs cascading deletion of related appointments when a hotel is deleted.
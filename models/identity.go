@@ -0,0 +1,26 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Identity links an account at an external OpenID provider to a user
+type Identity struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	Provider  string             `bson:"provider"`
+	Subject   string             `bson:"subject"`
+	User      primitive.ObjectID `bson:"user"`
+	Email     string             `bson:"email"`
+	CreatedAt primitive.DateTime `bson:"createdAt"`
+}
+
+// OIDCState is what we remember between redirecting to a provider and its
+// callback: the state value, the ID token nonce and the PKCE verifier
+type OIDCState struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	State     string             `bson:"state"`
+	Provider  string             `bson:"provider"`
+	Nonce     string             `bson:"nonce"`
+	Verifier  string             `bson:"verifier"`
+	ExpiresAt primitive.DateTime `bson:"expiresAt"`
+}
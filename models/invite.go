@@ -0,0 +1,22 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Invite lets someone register with a role other than user. Only the hash of
+// the token is stored; when Email is set the invite only works for it.
+type Invite struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	Hash      string             `bson:"hash" json:"-"`
+	Email     string             `bson:"email,omitempty"`
+	Role      string             `bson:"role"`
+	CreatedBy primitive.ObjectID `bson:"createdBy"`
+	CreatedAt primitive.DateTime `bson:"createdAt"`
+	ExpiresAt primitive.DateTime `bson:"expiresAt"`
+	UsedAt    primitive.DateTime `bson:"usedAt,omitempty"`
+	UsedBy    primitive.ObjectID `bson:"usedBy,omitempty"`
+
+	// The organization the new user joins; unset for the default tenant
+	Organization primitive.ObjectID `bson:"organization,omitempty"`
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LoginAttempt counts recent failed logins for one account, one client IP or
+// one account from one client IP, wrong second-factor codes for one user, or
+// password reset requests for one address or client IP
+type LoginAttempt struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty"`
+	Key           string             `bson:"key"`
+	Failures      int                `bson:"failures"`
+	LastFailureAt primitive.DateTime `bson:"lastFailureAt"`
+	LockedUntil   primitive.DateTime `bson:"lockedUntil,omitempty"`
+	ExpiresAt     primitive.DateTime `bson:"expiresAt"`
+
+	// The account the counter belongs to and its organization; unset for
+	// client IPs, addresses without an account and reset requests
+	User         primitive.ObjectID `bson:"user,omitempty"`
+	Organization primitive.ObjectID `bson:"organization,omitempty"`
+}
+
+// AccountAttemptKey identifies the failed-login counter of an email address.
+// It is tracked whether or not the account exists.
+func AccountAttemptKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// ClientAttemptKey identifies the failed-login counter of an email address
+// tried from one client IP
+func ClientAttemptKey(email, ip string) string {
+	return ClientAttemptPrefix(email) + ip
+}
+
+// ClientAttemptPrefix starts the keys of every client IP's counter for an
+// email address
+func ClientAttemptPrefix(email string) string {
+	return "client:" + strings.ToLower(strings.TrimSpace(email)) + "|"
+}
+
+// IPAttemptKey identifies the failed-login counter of a client IP
+func IPAttemptKey(ip string) string {
+	return "ip:" + ip
+}
+
+// MFAAttemptKey identifies the counter of wrong second-factor codes for a
+// user, which persists across MFA challenges
+func MFAAttemptKey(userID primitive.ObjectID) string {
+	return "mfa:" + userID.Hex()
+}
+
+// ResetAttemptKey identifies the counter of password reset requests for an
+// email address. It is tracked whether or not the account exists.
+func ResetAttemptKey(email string) string {
+	return "reset:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// ResetIPAttemptKey identifies the counter of password reset requests from a
+// client IP
+func ResetIPAttemptKey(ip string) string {
+	return "reset-ip:" + ip
+}
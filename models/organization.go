@@ -0,0 +1,14 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Organization is a hotel chain. Users, hotels and appointments of one
+// organization are not visible to the others.
+type Organization struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	Name      string             `bson:"name"`
+	Slug      string             `bson:"slug"`
+	CreatedAt primitive.DateTime `bson:"createdAt"`
+}
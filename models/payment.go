@@ -0,0 +1,39 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Payment statuses
+const (
+	PaymentPending  = "pending"
+	PaymentPaid     = "paid"
+	PaymentFailed   = "failed"
+	PaymentRefunded = "refunded"
+)
+
+type Payment struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"`
+	Appointment primitive.ObjectID `bson:"appointment" validate:"required"`
+	User        primitive.ObjectID `bson:"user" validate:"required"`
+	Amount      int64              `bson:"amount" validate:"required"`
+	Currency    string             `bson:"currency" validate:"required,len=3"`
+	Status      string             `bson:"status"`
+	Provider    string             `bson:"provider"`
+	ProviderRef string             `bson:"providerRef"`
+	CreatedAt   primitive.DateTime `bson:"createdAt,omitempty"`
+	PaidAt      primitive.DateTime `bson:"paidAt,omitempty"`
+	RefundedAt  primitive.DateTime `bson:"refundedAt,omitempty"`
+}
+
+// CanTransition reports whether the payment may move to the given status.
+// The only allowed flow is pending -> paid -> refunded, or pending -> failed.
+func (payment *Payment) CanTransition(status string) bool {
+	switch payment.Status {
+	case PaymentPending:
+		return status == PaymentPaid || status == PaymentFailed
+	case PaymentPaid:
+		return status == PaymentRefunded
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package models
+
+import "testing"
+
+func TestPaymentCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{PaymentPending, PaymentPaid, true},
+		{PaymentPending, PaymentFailed, true},
+		{PaymentPending, PaymentRefunded, false},
+		{PaymentPending, PaymentPending, false},
+		{PaymentPaid, PaymentRefunded, true},
+		{PaymentPaid, PaymentFailed, false},
+		{PaymentPaid, PaymentPending, false},
+		{PaymentFailed, PaymentPaid, false},
+		{PaymentFailed, PaymentPending, false},
+		{PaymentRefunded, PaymentPaid, false},
+		{PaymentRefunded, PaymentPending, false},
+		{"", PaymentPaid, false},
+	}
+
+	for _, tt := range tests {
+		payment := &Payment{Status: tt.from}
+		if got := payment.CanTransition(tt.to); got != tt.want {
+			t.Errorf("%q -> %q: got %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
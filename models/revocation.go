@@ -0,0 +1,22 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Revocation kinds
+const (
+	RevokeToken   = "jti"     // a single access token, keyed by its jti
+	RevokeUser    = "user"    // every token issued to a user before RevokedAt
+	RevokeSession = "session" // every token issued for a session, keyed by its sid
+)
+
+// Revocation marks access tokens as no longer valid. Entries are removed by a
+// TTL index once every token they cover has expired on its own.
+type Revocation struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	Kind      string             `bson:"kind"`
+	Key       string             `bson:"key"`
+	RevokedAt primitive.DateTime `bson:"revokedAt"`
+	ExpiresAt primitive.DateTime `bson:"expiresAt"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Role maps a role name to the permissions its users hold. Built-in roles
+// can be edited but not deleted.
+type Role struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"`
+	Name        string             `bson:"name"`
+	Description string             `bson:"description,omitempty"`
+	Permissions []string           `bson:"permissions"`
+	Builtin     bool               `bson:"builtin"`
+	UpdatedAt   primitive.DateTime `bson:"updatedAt"`
+}
+
+// Grant gives one user a permission on a single resource, on top of what
+// their role allows (e.g. hotels:update on one hotel)
+type Grant struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	User       primitive.ObjectID `bson:"user"`
+	Permission string             `bson:"permission"`
+	Resource   primitive.ObjectID `bson:"resource"`
+	CreatedBy  primitive.ObjectID `bson:"createdBy"`
+	CreatedAt  primitive.DateTime `bson:"createdAt"`
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Session is a login on one device. Its ID doubles as the refresh token
+// family and is carried in the "sid" claim of every access token it issues.
+type Session struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	User       primitive.ObjectID `bson:"user"`
+	Device     string             `bson:"device"`
+	IP         string             `bson:"ip"`
+	UserAgent  string             `bson:"userAgent"`
+	AMR        []string           `bson:"amr"` // authentication methods, e.g. ["pwd", "otp"]
+	CreatedAt  primitive.DateTime `bson:"createdAt"`
+	LastSeenAt primitive.DateTime `bson:"lastSeenAt"`
+	ExpiresAt  primitive.DateTime `bson:"expiresAt"`
+	RevokedAt  primitive.DateTime `bson:"revokedAt,omitempty"`
+}
+
+// DescribeDevice turns a User-Agent header into a short label such as "Chrome on Windows"
+func DescribeDevice(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+
+	os := "Unknown OS"
+	switch {
+	case strings.Contains(ua, "android"):
+		os = "Android"
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"):
+		os = "iOS"
+	case strings.Contains(ua, "windows"):
+		os = "Windows"
+	case strings.Contains(ua, "mac os"):
+		os = "macOS"
+	case strings.Contains(ua, "linux"):
+		os = "Linux"
+	}
+
+	browser := "Unknown client"
+	switch {
+	case strings.Contains(ua, "edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "chrome/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "safari/"):
+		browser = "Safari"
+	case strings.Contains(ua, "curl/"):
+		browser = "curl"
+	case strings.Contains(ua, "postman"):
+		browser = "Postman"
+	}
+
+	return browser + " on " + os
+}
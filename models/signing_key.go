@@ -0,0 +1,20 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SigningKey is an asymmetric key used to sign JWTs. A key signs new tokens
+// between ActivatesAt and RetiresAt and is published in the JWKS until
+// ExpiresAt, so tokens it signed keep verifying after it is rotated out.
+type SigningKey struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"`
+	Kid          string             `bson:"kid"`
+	Alg          string             `bson:"alg"`
+	PrivateKey   string             `bson:"privateKey,omitempty"`   // PKCS #8 PEM, only when unencrypted
+	EncryptedKey []byte             `bson:"encryptedKey,omitempty"` // the PEM encrypted with AES-GCM
+	CreatedAt    primitive.DateTime `bson:"createdAt"`
+	ActivatesAt  primitive.DateTime `bson:"activatesAt"`
+	RetiresAt    primitive.DateTime `bson:"retiresAt"`
+	ExpiresAt    primitive.DateTime `bson:"expiresAt"`
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RefreshToken is an opaque, single-use token exchanged for a new access token.
+// Tokens issued from the same login share a Family so that a replayed token
+// can revoke every descendant at once.
+type RefreshToken struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	User       primitive.ObjectID `bson:"user"`
+	Family     primitive.ObjectID `bson:"family"`
+	Hash       string             `bson:"hash"`
+	ExpiresAt  primitive.DateTime `bson:"expiresAt"`
+	CreatedAt  primitive.DateTime `bson:"createdAt"`
+	UsedAt     primitive.DateTime `bson:"usedAt,omitempty"`
+	RevokedAt  primitive.DateTime `bson:"revokedAt,omitempty"`
+	ReplacedBy primitive.ObjectID `bson:"replacedBy,omitempty"`
+}
+
+// NewRefreshToken creates a refresh token in the given family and returns it
+// together with the plaintext value that is handed to the client.
+func NewRefreshToken(userID, family primitive.ObjectID, ttl time.Duration) (*RefreshToken, string, error) {
+	plain, err := RandomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	token := &RefreshToken{
+		User:      userID,
+		Family:    family,
+		Hash:      HashToken(plain),
+		ExpiresAt: primitive.NewDateTimeFromTime(now.Add(ttl)),
+		CreatedAt: primitive.NewDateTimeFromTime(now),
+	}
+	return token, plain, nil
+}
+
+// IsExpired reports whether the refresh token is past its expiry
+func (token *RefreshToken) IsExpired() bool {
+	return time.Now().After(token.ExpiresAt.Time())
+}
+
+// RandomToken returns n random bytes encoded as URL-safe base64
+func RandomToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashToken returns the SHA-256 hex digest under which opaque tokens are stored
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// PasswordResetToken is a single-use token emailed to a user who forgot their password
+type PasswordResetToken struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	User      primitive.ObjectID `bson:"user"`
+	Hash      string             `bson:"hash"`
+	ExpiresAt primitive.DateTime `bson:"expiresAt"`
+	CreatedAt primitive.DateTime `bson:"createdAt"`
+	UsedAt    primitive.DateTime `bson:"usedAt,omitempty"`
+}
@@ -2,11 +2,10 @@ package models
 
 import (
 	"regexp"
-	"time"
+	"sync"
 
-	"github.com/golang-jwt/jwt/v4"
+	"github.com/JongSinister/WTFiber/password"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type User struct {
@@ -17,44 +16,84 @@ type User struct {
 	Role      string             `bson:"role" valodate:"required"`
 	Password  string             `bson:"password" valodate:"required"`
 	CreatedAt primitive.DateTime `bson:"created_at,omitempty"`
+
+	// Set for users who signed up through an identity provider and never
+	// chose a password; their stored hash is of a random one
+	NoPassword bool `bson:"noPassword,omitempty"`
+
+	// The hotel chain the user belongs to; unset for the default tenant
+	Organization primitive.ObjectID `bson:"organization,omitempty"`
+
+	// Account status managed by admins; soft-deleted accounts are disabled too
+	Disabled   bool               `bson:"disabled"`
+	DisabledAt primitive.DateTime `bson:"disabledAt,omitempty"`
+	DeletedAt  primitive.DateTime `bson:"deletedAt,omitempty"`
+
+	EmailVerified   bool               `bson:"emailVerified"`
+	EmailVerifiedAt primitive.DateTime `bson:"emailVerifiedAt,omitempty"`
+
+	// TOTP two-factor authentication. Secrets are sealed with auth.SealSecret
+	// and recovery codes are stored hashed.
+	MFAEnabled       bool     `bson:"mfaEnabled"`
+	MFASecret        string   `bson:"mfaSecret,omitempty" json:"-"`
+	MFAPendingSecret string   `bson:"mfaPendingSecret,omitempty" json:"-"`
+	MFARecoveryCodes []string `bson:"mfaRecoveryCodes,omitempty" json:"-"`
+	MFALastStep      int64    `bson:"mfaLastStep,omitempty" json:"-"`
 }
 
 // Check Email Validation
 func (user *User) ValidateEmail() bool {
+	return ValidEmail(user.Email)
+}
+
+// ValidEmail checks the format of an email address
+func ValidEmail(email string) bool {
 	regex := `^(([^<>()[\]\\.,;:\s@\"]+(\.[^<>()[\]\\.,;:\s@\"]+)*)|(\".+\"))@((\[[0-9]{1,3}\.[0-9]{1,3}\.[0-9]{1,3}\.[0-9]{1,3}\])|(([a-zA-Z\-0-9]+\.)+[a-zA-Z]{2,}))$`
 	re := regexp.MustCompile(regex)
-	return re.MatchString(user.Email)
+	return re.MatchString(email)
+}
+
+// IsActive reports whether the account may log in
+func (user *User) IsActive() bool {
+	return !user.Disabled && user.DeletedAt == 0
 }
 
-// HashPassword hashes the user's password using bcrypt
+// HashPassword hashes the user's password with the configured algorithm
 func (user *User) HashPassword() error {
-	hash, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	hash, err := password.Hash(user.Password)
 	if err != nil {
 		return err
 	}
-	user.Password = string(hash)
+	user.Password = hash
 	return nil
 }
 
 // CheckPasword compares a hashed password with the provided password
-func (user *User) CheckPassword(password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
-	return err == nil
-}
-
-// GenerateAuthToken generates a new JWT token for the user
-func (user *User) GenerateToken(secret string) (string, error) {
-	// 1) Create a new token object
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"email": user.Email,
-		"role":  user.Role,
-		"exp":   time.Now().Add(time.Hour * 72).Unix(),
-	})
-
-	// 2) Sign the token with the secret
-	tokenString, err := token.SignedString([]byte(secret))
-	if err != nil {
-		return "", err
-	}
-	return tokenString, nil
+func (user *User) CheckPassword(plain string) bool {
+	return password.Verify(user.Password, plain)
+}
+
+// HasPassword reports whether the user chose a password they can confirm
+// sensitive changes with
+func (user *User) HasPassword() bool {
+	return !user.NoPassword
+}
+
+// PasswordNeedsRehash reports whether the stored hash is a legacy format or
+// weaker than the current settings
+func (user *User) PasswordNeedsRehash() bool {
+	return password.NeedsRehash(user.Password)
+}
+
+// dummyHash is compared against when no account matches a login, so an
+// unknown email takes as long to reject as a wrong password
+var dummyHash = sync.OnceValue(func() string {
+	hash, _ := password.Hash("wtfiber-dummy-password")
+	return hash
+})
+
+// CheckDummyPassword spends the same time as CheckPassword and always fails
+func CheckDummyPassword(plain string) bool {
+	password.Verify(dummyHash(), plain)
+	return false
 }
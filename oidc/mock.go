@@ -0,0 +1,165 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"math/big"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// MockServer is a minimal OpenID provider for local development and tests.
+// It signs in whoever is named by the login_hint parameter without asking
+// for a password, so it must never be enabled in production.
+type MockServer struct {
+	issuer string
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]mockGrant
+}
+
+type mockGrant struct {
+	clientID    string
+	redirectURI string
+	challenge   string
+	nonce       string
+	email       string
+	expiresAt   time.Time
+}
+
+func NewMockServer(issuer string) (*MockServer, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	return &MockServer{
+		issuer: strings.TrimSuffix(issuer, "/"),
+		key:    key,
+		codes:  map[string]mockGrant{},
+	}, nil
+}
+
+// Register mounts the provider endpoints on router
+func (m *MockServer) Register(router fiber.Router) {
+	router.Get("/.well-known/openid-configuration", m.discovery)
+	router.Get("/authorize", m.authorize)
+	router.Post("/token", m.token)
+	router.Get("/jwks", m.jwks)
+}
+
+func (m *MockServer) discovery(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"issuer":                                m.issuer,
+		"authorization_endpoint":                m.issuer + "/authorize",
+		"token_endpoint":                        m.issuer + "/token",
+		"jwks_uri":                              m.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (m *MockServer) authorize(c *fiber.Ctx) error {
+	if c.Query("response_type") != "code" || c.Query("code_challenge_method") != "S256" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "unsupported_response_type"})
+	}
+
+	redirectURI, err := url.Parse(c.Query("redirect_uri"))
+	if err != nil || redirectURI.Scheme == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_request"})
+	}
+
+	email := c.Query("login_hint", "mock.user@example.com")
+
+	code, err := RandomString(24)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "server_error"})
+	}
+
+	m.mu.Lock()
+	m.codes[code] = mockGrant{
+		clientID:    c.Query("client_id"),
+		redirectURI: redirectURI.String(),
+		challenge:   c.Query("code_challenge"),
+		nonce:       c.Query("nonce"),
+		email:       email,
+		expiresAt:   time.Now().Add(time.Minute),
+	}
+	m.mu.Unlock()
+
+	query := redirectURI.Query()
+	query.Set("code", code)
+	query.Set("state", c.Query("state"))
+	redirectURI.RawQuery = query.Encode()
+	return c.Redirect(redirectURI.String(), fiber.StatusFound)
+}
+
+func (m *MockServer) token(c *fiber.Ctx) error {
+	code := c.FormValue("code")
+
+	m.mu.Lock()
+	grant, ok := m.codes[code]
+	delete(m.codes, code)
+	m.mu.Unlock()
+
+	if !ok || time.Now().After(grant.expiresAt) ||
+		c.FormValue("grant_type") != "authorization_code" ||
+		c.FormValue("client_id") != grant.clientID ||
+		c.FormValue("redirect_uri") != grant.redirectURI {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_grant"})
+	}
+
+	sum := sha256.Sum256([]byte(c.FormValue("code_verifier")))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+	if subtle.ConstantTimeCompare([]byte(challenge), []byte(grant.challenge)) != 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_grant"})
+	}
+
+	now := time.Now()
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            m.issuer,
+		"aud":            grant.clientID,
+		"sub":            "mock|" + grant.email,
+		"email":          grant.email,
+		"email_verified": true,
+		"name":           strings.Split(grant.email, "@")[0],
+		"nonce":          grant.nonce,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+	})
+	idToken.Header["kid"] = "mock"
+
+	signed, err := idToken.SignedString(m.key)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "server_error"})
+	}
+
+	return c.JSON(fiber.Map{
+		"access_token": "mock-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     signed,
+	})
+}
+
+func (m *MockServer) jwks(c *fiber.Ctx) error {
+	public := m.key.PublicKey
+	return c.JSON(fiber.Map{"keys": []fiber.Map{{
+		"kty": "RSA",
+		"use": "sig",
+		"alg": "RS256",
+		"kid": "mock",
+		"n":   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+	}}})
+}
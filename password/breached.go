@@ -0,0 +1,104 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/JongSinister/WTFiber/config"
+)
+
+// Breached passwords are looked up k-anonymity style: the SHA-1 of the
+// password is split into a 5 character prefix and the remaining suffix, the
+// source returns every suffix known for the prefix, and the match happens
+// here. With PASSWORD_BREACHED_DIR set the source is a directory of range
+// files named <PREFIX>.txt holding SUFFIX:COUNT lines (the layout of the
+// Pwned Passwords downloader); otherwise a small bundled list is used.
+
+const prefixLength = 5
+
+//go:embed breached.txt
+var bundled string
+
+var (
+	bundledOnce   sync.Once
+	bundledHashes []string
+)
+
+// IsBreached reports whether the password appears in the breach list
+func IsBreached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+
+	suffixes, err := lookupRange(prefix)
+	if err != nil {
+		return false, err
+	}
+	for _, s := range suffixes {
+		if s == suffix {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// lookupRange returns the hash suffixes listed for a prefix
+func lookupRange(prefix string) ([]string, error) {
+	if dir := config.GetEnv("PASSWORD_BREACHED_DIR", ""); dir != "" {
+		return readRangeFile(filepath.Join(dir, prefix+".txt"))
+	}
+	return bundledRange(prefix), nil
+}
+
+// readRangeFile reads the suffixes of one range file; a missing file means
+// no breached password has that prefix
+func readRangeFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var suffixes []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		suffix, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if suffix != "" {
+			suffixes = append(suffixes, strings.ToUpper(suffix))
+		}
+	}
+	return suffixes, scanner.Err()
+}
+
+// bundledRange returns the suffixes of the bundled list for a prefix
+func bundledRange(prefix string) []string {
+	bundledOnce.Do(func() {
+		for _, line := range strings.Split(bundled, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			bundledHashes = append(bundledHashes, strings.ToUpper(line))
+		}
+		sort.Strings(bundledHashes)
+	})
+
+	var suffixes []string
+	i := sort.SearchStrings(bundledHashes, prefix)
+	for ; i < len(bundledHashes) && strings.HasPrefix(bundledHashes[i], prefix); i++ {
+		suffixes = append(suffixes, bundledHashes[i][prefixLength:])
+	}
+	return suffixes
+}
@@ -0,0 +1,162 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/JongSinister/WTFiber/config"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Hashes are stored in a self-describing format so the algorithm and its
+// parameters can change without invalidating existing passwords:
+//
+//	argon2id: $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>  (PHC string format)
+//	bcrypt:   $2a$10$...                                   (legacy)
+
+const (
+	Argon2id = "argon2id"
+	Bcrypt   = "bcrypt"
+)
+
+var errInvalidHash = errors.New("invalid password hash")
+
+// Argon2Params are the argon2id cost parameters
+type Argon2Params struct {
+	Memory    uint32 // KiB
+	Time      uint32
+	Threads   uint8
+	SaltLen   uint32
+	KeyLength uint32
+}
+
+// Algorithm is the algorithm new hashes use (PASSWORD_HASH_ALGO)
+func Algorithm() string {
+	if config.GetEnv("PASSWORD_HASH_ALGO", Argon2id) == Bcrypt {
+		return Bcrypt
+	}
+	return Argon2id
+}
+
+// CurrentArgon2Params reads the argon2id parameters from the environment
+// (ARGON2_MEMORY in KiB, ARGON2_TIME, ARGON2_THREADS). Values argon2 cannot
+// work with are raised to its minimums: one pass, one thread and 8 KiB per
+// thread.
+func CurrentArgon2Params() Argon2Params {
+	threads := clamp(int64(config.GetInt("ARGON2_THREADS", 2)), 1, math.MaxUint8)
+	return Argon2Params{
+		Memory:    uint32(clamp(int64(config.GetInt("ARGON2_MEMORY", 64*1024)), 8*threads, math.MaxUint32)),
+		Time:      uint32(clamp(int64(config.GetInt("ARGON2_TIME", 3)), 1, math.MaxUint32)),
+		Threads:   uint8(threads),
+		SaltLen:   16,
+		KeyLength: 32,
+	}
+}
+
+// clamp limits value to the range [lo, hi]
+func clamp(value, lo, hi int64) int64 {
+	return max(lo, min(value, hi))
+}
+
+// BcryptCost is the cost for new bcrypt hashes (BCRYPT_COST)
+func BcryptCost() int {
+	return config.GetInt("BCRYPT_COST", bcrypt.DefaultCost)
+}
+
+// Hash hashes a password with the configured algorithm
+func Hash(password string) (string, error) {
+	if Algorithm() == Bcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), BcryptCost())
+		return string(hash), err
+	}
+
+	params := CurrentArgon2Params()
+	salt := make([]byte, params.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, params.KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, params.Memory, params.Time, params.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify reports whether password matches the stored hash, whatever format
+// the hash is in
+func Verify(hash, password string) bool {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		params, salt, key, err := decodeArgon2(hash)
+		if err != nil {
+			return false
+		}
+		other := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, params.KeyLength)
+		return subtle.ConstantTimeCompare(key, other) == 1
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// NeedsRehash reports whether a hash uses another algorithm or weaker
+// parameters than new hashes would
+func NeedsRehash(hash string) bool {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		if Algorithm() != Argon2id {
+			return true
+		}
+		params, _, _, err := decodeArgon2(hash)
+		if err != nil {
+			return true
+		}
+		current := CurrentArgon2Params()
+		return params.Memory < current.Memory || params.Time < current.Time || params.Threads < current.Threads
+	}
+
+	if Algorithm() != Bcrypt {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost < BcryptCost()
+}
+
+// decodeArgon2 parses an argon2id PHC string
+func decodeArgon2(hash string) (Argon2Params, []byte, []byte, error) {
+	var params Argon2Params
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != Argon2id {
+		return params, nil, nil, errInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, errInvalidHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+		return params, nil, nil, errInvalidHash
+	}
+	// argon2 panics on zero passes or threads
+	if params.Time < 1 || params.Threads < 1 || params.Memory < 8*uint32(params.Threads) {
+		return params, nil, nil, errInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, errInvalidHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, errInvalidHash
+	}
+	params.SaltLen = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+
+	return params, salt, key, nil
+}
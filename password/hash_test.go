@@ -0,0 +1,134 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// cheapArgon2 keeps argon2id fast enough for tests
+func cheapArgon2(t *testing.T) {
+	t.Helper()
+	t.Setenv("PASSWORD_HASH_ALGO", Argon2id)
+	t.Setenv("ARGON2_MEMORY", "1024")
+	t.Setenv("ARGON2_TIME", "1")
+	t.Setenv("ARGON2_THREADS", "1")
+}
+
+func TestHashAndVerify(t *testing.T) {
+	for _, algo := range []string{Argon2id, Bcrypt} {
+		t.Run(algo, func(t *testing.T) {
+			cheapArgon2(t)
+			t.Setenv("PASSWORD_HASH_ALGO", algo)
+			t.Setenv("BCRYPT_COST", "4")
+
+			hash, err := Hash("Correct horse 1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if algo == Argon2id && !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+				t.Errorf("unexpected argon2id hash %q", hash)
+			}
+			if algo == Bcrypt && !strings.HasPrefix(hash, "$2a$04$") {
+				t.Errorf("unexpected bcrypt hash %q", hash)
+			}
+
+			if !Verify(hash, "Correct horse 1") {
+				t.Error("the right password did not verify")
+			}
+			if Verify(hash, "Correct horse 2") {
+				t.Error("a wrong password verified")
+			}
+			if NeedsRehash(hash) {
+				t.Error("a fresh hash needs a rehash")
+			}
+		})
+	}
+}
+
+func TestHashZeroParams(t *testing.T) {
+	t.Setenv("PASSWORD_HASH_ALGO", Argon2id)
+	t.Setenv("ARGON2_MEMORY", "0")
+	t.Setenv("ARGON2_TIME", "0")
+	t.Setenv("ARGON2_THREADS", "0")
+
+	hash, err := Hash("Correct horse 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=8,t=1,p=1$") {
+		t.Errorf("parameters were not raised to argon2's minimums: %q", hash)
+	}
+	if !Verify(hash, "Correct horse 1") {
+		t.Error("the right password did not verify")
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	cheapArgon2(t)
+
+	legacy, err := bcrypt.GenerateFromPassword([]byte("Correct horse 1"), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !NeedsRehash(string(legacy)) {
+		t.Error("a bcrypt hash is not upgraded to argon2id")
+	}
+	if !Verify(string(legacy), "Correct horse 1") {
+		t.Error("a legacy bcrypt hash no longer verifies")
+	}
+
+	hash, err := Hash("Correct horse 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ARGON2_TIME", "2")
+	if !NeedsRehash(hash) {
+		t.Error("raising ARGON2_TIME does not rehash older hashes")
+	}
+
+	t.Setenv("PASSWORD_HASH_ALGO", Bcrypt)
+	t.Setenv("BCRYPT_COST", "4")
+	if !NeedsRehash(hash) {
+		t.Error("an argon2id hash is not rehashed when bcrypt is configured")
+	}
+	if NeedsRehash(string(legacy)) {
+		t.Error("a current bcrypt hash needs a rehash")
+	}
+	t.Setenv("BCRYPT_COST", "5")
+	if !NeedsRehash(string(legacy)) {
+		t.Error("raising BCRYPT_COST does not rehash older hashes")
+	}
+}
+
+func TestMalformedHashes(t *testing.T) {
+	cheapArgon2(t)
+
+	const salt, key = "c2FsdHNhbHRzYWx0c2FsdA", "a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U"
+	tests := []string{
+		"",
+		"garbage",
+		"$argon2id$",
+		"$argon2id$v=19$m=1024,t=1,p=1$" + salt,
+		"$argon2id$v=18$m=1024,t=1,p=1$" + salt + "$" + key,
+		"$argon2id$v=19$m=1024,t=0,p=1$" + salt + "$" + key,
+		"$argon2id$v=19$m=1024,t=1,p=0$" + salt + "$" + key,
+		"$argon2id$v=19$m=0,t=1,p=1$" + salt + "$" + key,
+		"$argon2id$v=19$m=1024,t=1,p=300$" + salt + "$" + key,
+		"$argon2id$v=19$m=1024,t=1$" + salt + "$" + key,
+		"$argon2id$v=19$m=1024,t=1,p=1$!!!$" + key,
+		"$argon2id$v=19$m=1024,t=1,p=1$" + salt + "$!!!",
+		"$argon2id$v=19$m=1024,t=1,p=1$" + salt + "$",
+		"$2a$04$short",
+	}
+
+	for _, hash := range tests {
+		if Verify(hash, "Correct horse 1") {
+			t.Errorf("%q: verified", hash)
+		}
+		if !NeedsRehash(hash) {
+			t.Errorf("%q: does not need a rehash", hash)
+		}
+	}
+}
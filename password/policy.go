@@ -0,0 +1,131 @@
+// Package password decides whether a new password is acceptable: it applies
+// the configured policy and checks the password against known breaches.
+package password
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/JongSinister/WTFiber/config"
+)
+
+// Policy lists the rules a new password must satisfy
+type Policy struct {
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+// CurrentPolicy reads the policy from the environment (PASSWORD_MIN_LENGTH,
+// PASSWORD_MAX_LENGTH, PASSWORD_REQUIRE_UPPER/LOWER/DIGIT/SYMBOL)
+func CurrentPolicy() Policy {
+	return Policy{
+		MinLength:     config.GetInt("PASSWORD_MIN_LENGTH", 8),
+		MaxLength:     config.GetInt("PASSWORD_MAX_LENGTH", defaultMaxLength()),
+		RequireUpper:  config.GetEnv("PASSWORD_REQUIRE_UPPER", "true") == "true",
+		RequireLower:  config.GetEnv("PASSWORD_REQUIRE_LOWER", "true") == "true",
+		RequireDigit:  config.GetEnv("PASSWORD_REQUIRE_DIGIT", "true") == "true",
+		RequireSymbol: config.GetEnv("PASSWORD_REQUIRE_SYMBOL", "false") == "true",
+	}
+}
+
+// defaultMaxLength keeps passwords within what the hash algorithm uses;
+// bcrypt ignores everything past 72 bytes
+func defaultMaxLength() int {
+	if Algorithm() == Bcrypt {
+		return 72
+	}
+	return 128
+}
+
+// Validate checks a new password against the current policy and the breach
+// list. personal holds the user's email, name and similar values the
+// password must not contain. It returns one message per broken rule.
+func Validate(password string, personal ...string) []string {
+	problems := CurrentPolicy().Check(password, personal...)
+
+	breached, err := IsBreached(password)
+	if err != nil {
+		log.Printf("Error checking breached passwords: %v", err)
+	}
+	if breached {
+		problems = append(problems, "Password has appeared in a data breach; choose a different one")
+	}
+	return problems
+}
+
+// Check applies the policy rules to a password
+func (policy Policy) Check(password string, personal ...string) []string {
+	var problems []string
+
+	length := utf8.RuneCountInString(password)
+	if length < policy.MinLength {
+		problems = append(problems, fmt.Sprintf("Password must be at least %d characters long", policy.MinLength))
+	}
+	// The limit is in bytes since that is what the hash functions see
+	if policy.MaxLength > 0 && len(password) > policy.MaxLength {
+		problems = append(problems, fmt.Sprintf("Password must be at most %d bytes long", policy.MaxLength))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if policy.RequireUpper && !upper {
+		problems = append(problems, "Password must contain an uppercase letter")
+	}
+	if policy.RequireLower && !lower {
+		problems = append(problems, "Password must contain a lowercase letter")
+	}
+	if policy.RequireDigit && !digit {
+		problems = append(problems, "Password must contain a digit")
+	}
+	if policy.RequireSymbol && !symbol {
+		problems = append(problems, "Password must contain a symbol")
+	}
+
+	lowered := strings.ToLower(password)
+	for _, word := range personalWords(personal) {
+		if strings.Contains(lowered, word) {
+			problems = append(problems, "Password must not contain your name or email address")
+			break
+		}
+	}
+
+	return problems
+}
+
+// personalWords splits names and emails into the words worth checking for.
+// Very short words are skipped since they appear in passwords by chance.
+func personalWords(values []string) []string {
+	var words []string
+	for _, value := range values {
+		value = strings.ToLower(value)
+		if local, domain, ok := strings.Cut(value, "@"); ok {
+			value = local + " " + strings.Split(domain, ".")[0]
+		}
+		for _, word := range strings.FieldsFunc(value, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			if utf8.RuneCountInString(word) >= 3 {
+				words = append(words, word)
+			}
+		}
+	}
+	return words
+}
@@ -0,0 +1,101 @@
+package password
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	policy := Policy{MinLength: 8, MaxLength: 16, RequireUpper: true, RequireLower: true, RequireDigit: true}
+	strict := policy
+	strict.RequireSymbol = true
+
+	tests := []struct {
+		name     string
+		policy   Policy
+		password string
+		personal []string
+		want     []string
+	}{
+		{"pass", policy, "Tangerine42", nil, nil},
+		{"pass with personal values", policy, "Tangerine42", []string{"jo@example.com", "Jo Smith"}, nil},
+		{"too short", policy, "Tang42", nil, []string{"Password must be at least 8 characters long"}},
+		{"too long", policy, "Tangerine42Tangerine42", nil, []string{"Password must be at most 16 bytes long"}},
+		// Eight runes but 16 bytes: the minimum counts characters, the maximum bytes
+		{"multibyte", policy, "Ää1ääääa", nil, nil},
+		{"multibyte too long", policy, "Ää1äääääää", nil, []string{"Password must be at most 16 bytes long"}},
+		{"no upper", policy, "tangerine42", nil, []string{"Password must contain an uppercase letter"}},
+		{"no lower", policy, "TANGERINE42", nil, []string{"Password must contain a lowercase letter"}},
+		{"no digit", policy, "Tangerines", nil, []string{"Password must contain a digit"}},
+		{"no symbol", strict, "Tangerine42", nil, []string{"Password must contain a symbol"}},
+		{"symbol", strict, "Tangerine 42", nil, nil},
+		{"contains email", policy, "Xjsmith2024", []string{"JSmith@example.com"}, []string{"Password must not contain your name or email address"}},
+		{"contains email domain", policy, "Example2024", []string{"jo@example.com"}, []string{"Password must not contain your name or email address"}},
+		{"contains name", policy, "Wilhelmina9", []string{"Wilhelmina Jones"}, []string{"Password must not contain your name or email address"}},
+		{"short name words ignored", policy, "Tangerine42", []string{"Jo Ta"}, nil},
+		{"several problems", policy, "abc", nil, []string{
+			"Password must be at least 8 characters long",
+			"Password must contain an uppercase letter",
+			"Password must contain a digit",
+		}},
+	}
+
+	for _, tt := range tests {
+		if got := tt.policy.Check(tt.password, tt.personal...); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIsBreached(t *testing.T) {
+	tests := []struct {
+		password string
+		want     bool
+	}{
+		{"password", true},
+		{"123456", true},
+		{"P@ssw0rd", true},
+		{"Password1", true},
+		{"uncommon Tangerine 42 wombat", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		got, err := IsBreached(tt.password)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%q: got %v, want %v", tt.password, got, tt.want)
+		}
+	}
+}
+
+func TestIsBreachedRangeFiles(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("PASSWORD_BREACHED_DIR", dir)
+
+	// The suffix is written in lower case to check the match ignores case
+	sum := sha1.Sum([]byte("Tangerine42"))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	content := "0000000000000000000000000000000000A:3\n" + strings.ToLower(hash[prefixLength:]) + ":12\n"
+	if err := os.WriteFile(filepath.Join(dir, hash[:prefixLength]+".txt"), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if breached, err := IsBreached("Tangerine42"); err != nil || !breached {
+		t.Errorf("listed password: got %v, %v", breached, err)
+	}
+	if breached, err := IsBreached("Tangerine43"); err != nil || breached {
+		t.Errorf("missing range file: got %v, %v", breached, err)
+	}
+	// The bundled list is not consulted when a directory is configured
+	if breached, err := IsBreached("password"); err != nil || breached {
+		t.Errorf("bundled entry: got %v, %v", breached, err)
+	}
+}
@@ -0,0 +1,149 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// webhookTolerance is how old a signed webhook may be before it is rejected.
+const webhookTolerance = 5 * time.Minute
+
+// MockProvider is an in-memory gateway used for local development.
+// Charges live only as long as the process.
+type MockProvider struct {
+	secret  []byte
+	mu      sync.Mutex
+	charges map[string]*Charge
+}
+
+func NewMockProvider(webhookSecret string) *MockProvider {
+	return &MockProvider{
+		secret:  []byte(webhookSecret),
+		charges: map[string]*Charge{},
+	}
+}
+
+func (m *MockProvider) Name() string {
+	return "mock"
+}
+
+func (m *MockProvider) CreateCharge(ctx context.Context, req ChargeRequest) (*Charge, error) {
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("amount must be positive")
+	}
+
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+
+	charge := &Charge{
+		Ref:      "mock_ch_" + hex.EncodeToString(buf),
+		Status:   ChargePending,
+		Amount:   req.Amount,
+		Currency: req.Currency,
+	}
+
+	m.mu.Lock()
+	m.charges[charge.Ref] = charge
+	m.mu.Unlock()
+
+	copied := *charge
+	return &copied, nil
+}
+
+func (m *MockProvider) Capture(ctx context.Context, ref string) (*Charge, error) {
+	return m.transition(ref, ChargePending, ChargeSucceeded)
+}
+
+func (m *MockProvider) Refund(ctx context.Context, ref string) (*Charge, error) {
+	return m.transition(ref, ChargeSucceeded, ChargeRefunded)
+}
+
+func (m *MockProvider) transition(ref, from, to string) (*Charge, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	charge, ok := m.charges[ref]
+	if !ok {
+		return nil, fmt.Errorf("charge %s not found", ref)
+	}
+	if charge.Status != from {
+		return nil, fmt.Errorf("charge %s is %s, expected %s", ref, charge.Status, from)
+	}
+
+	charge.Status = to
+	copied := *charge
+	return &copied, nil
+}
+
+// ParseWebhook expects a signature header of the form "t=<unix>,v1=<hex>",
+// where v1 is HMAC-SHA256 of "<unix>.<payload>" keyed with the webhook secret.
+func (m *MockProvider) ParseWebhook(payload []byte, signature string) (*Event, error) {
+	if len(m.secret) == 0 {
+		return nil, fmt.Errorf("%w: webhook secret not configured", ErrInvalidSignature)
+	}
+
+	var timestamp, sig string
+	for _, part := range strings.Split(signature, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			sig = value
+		}
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || sig == "" {
+		return nil, ErrInvalidSignature
+	}
+	if time.Since(time.Unix(ts, 0)).Abs() > webhookTolerance {
+		return nil, fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+
+	expected := Sign(m.secret, payload, ts)
+	if !hmac.Equal([]byte(expected), []byte(sig)) {
+		return nil, ErrInvalidSignature
+	}
+
+	event := new(Event)
+	if err := json.Unmarshal(payload, event); err != nil {
+		return nil, fmt.Errorf("invalid webhook payload: %w", err)
+	}
+
+	// Keep the in-memory state in line with what the webhook reports
+	m.mu.Lock()
+	if charge, ok := m.charges[event.Ref]; ok {
+		switch event.Type {
+		case EventChargeSucceeded:
+			charge.Status = ChargeSucceeded
+		case EventChargeFailed:
+			charge.Status = ChargeFailed
+		case EventChargeRefunded:
+			charge.Status = ChargeRefunded
+		}
+	}
+	m.mu.Unlock()
+
+	return event, nil
+}
+
+// Sign computes the v1 signature for a webhook payload sent at ts.
+func Sign(secret, payload []byte, ts int64) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(ts, 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
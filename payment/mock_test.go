@@ -0,0 +1,114 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMockParseWebhook(t *testing.T) {
+	secret := []byte("whsec_test")
+	payload := []byte(`{"type":"charge.succeeded","ref":"mock_ch_1"}`)
+	now := time.Now().Unix()
+
+	header := func(ts int64, sig string) string {
+		return "t=" + strconv.FormatInt(ts, 10) + ",v1=" + sig
+	}
+
+	tests := []struct {
+		name      string
+		secret    string
+		payload   []byte
+		signature string
+		wantErr   error
+	}{
+		{"valid", "whsec_test", payload, header(now, Sign(secret, payload, now)), nil},
+		{"spaces around parts", "whsec_test", payload, "t=" + strconv.FormatInt(now, 10) + ", v1=" + Sign(secret, payload, now), nil},
+		{"wrong secret", "whsec_test", payload, header(now, Sign([]byte("other"), payload, now)), ErrInvalidSignature},
+		{"tampered payload", "whsec_test", []byte(`{"type":"charge.succeeded","ref":"mock_ch_2"}`), header(now, Sign(secret, payload, now)), ErrInvalidSignature},
+		{"timestamp not signed", "whsec_test", payload, header(now+1, Sign(secret, payload, now)), ErrInvalidSignature},
+		{"too old", "whsec_test", payload, header(now-600, Sign(secret, payload, now-600)), ErrInvalidSignature},
+		{"too far ahead", "whsec_test", payload, header(now+600, Sign(secret, payload, now+600)), ErrInvalidSignature},
+		{"missing signature", "whsec_test", payload, "t=" + strconv.FormatInt(now, 10), ErrInvalidSignature},
+		{"missing timestamp", "whsec_test", payload, "v1=" + Sign(secret, payload, now), ErrInvalidSignature},
+		{"empty header", "whsec_test", payload, "", ErrInvalidSignature},
+		{"no secret configured", "", payload, header(now, Sign(nil, payload, now)), ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := NewMockProvider(tt.secret).ParseWebhook(tt.payload, tt.signature)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if event.Type != EventChargeSucceeded || event.Ref != "mock_ch_1" {
+				t.Fatalf("got event %+v", event)
+			}
+		})
+	}
+}
+
+func TestMockParseWebhookRejectsInvalidJSON(t *testing.T) {
+	secret := []byte("whsec_test")
+	payload := []byte(`not json`)
+	now := time.Now().Unix()
+
+	_, err := NewMockProvider("whsec_test").ParseWebhook(payload, "t="+strconv.FormatInt(now, 10)+",v1="+Sign(secret, payload, now))
+	if err == nil || errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("got %v, want a payload error", err)
+	}
+}
+
+func TestMockChargeTransitions(t *testing.T) {
+	ctx := context.Background()
+	m := NewMockProvider("whsec_test")
+
+	if _, err := m.CreateCharge(ctx, ChargeRequest{Amount: 0, Currency: "THB"}); err == nil {
+		t.Fatal("a zero amount charge was created")
+	}
+
+	charge, err := m.CreateCharge(ctx, ChargeRequest{Amount: 50000, Currency: "THB"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if charge.Status != ChargePending {
+		t.Fatalf("new charge is %s", charge.Status)
+	}
+
+	steps := []struct {
+		name    string
+		apply   func(context.Context, string) (*Charge, error)
+		want    string
+		wantErr bool
+	}{
+		{"refund before capture", m.Refund, "", true},
+		{"capture", m.Capture, ChargeSucceeded, false},
+		{"capture twice", m.Capture, "", true},
+		{"refund", m.Refund, ChargeRefunded, false},
+		{"refund twice", m.Refund, "", true},
+	}
+	for _, step := range steps {
+		got, err := step.apply(ctx, charge.Ref)
+		if step.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", step.name)
+			}
+			continue
+		}
+		if err != nil || got.Status != step.want {
+			t.Errorf("%s: got %v, %v; want %s", step.name, got, err, step.want)
+		}
+	}
+
+	if _, err := m.Capture(ctx, "mock_ch_unknown"); err == nil {
+		t.Error("captured an unknown charge")
+	}
+}
@@ -0,0 +1,95 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Charge statuses reported by providers
+const (
+	ChargePending   = "pending"
+	ChargeSucceeded = "succeeded"
+	ChargeFailed    = "failed"
+	ChargeRefunded  = "refunded"
+)
+
+// Webhook event types
+const (
+	EventChargeSucceeded = "charge.succeeded"
+	EventChargeFailed    = "charge.failed"
+	EventChargeRefunded  = "charge.refunded"
+)
+
+var ErrUnknownProvider = errors.New("unknown payment provider")
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// ChargeRequest describes the money we want to collect from a guest.
+type ChargeRequest struct {
+	Amount      int64
+	Currency    string
+	Reference   string
+	Description string
+}
+
+// Charge is the provider-side view of a payment.
+type Charge struct {
+	Ref      string
+	Status   string
+	Amount   int64
+	Currency string
+}
+
+// Event is a verified notification received from a provider webhook.
+type Event struct {
+	Type string `json:"type"`
+	Ref  string `json:"ref"`
+}
+
+// Provider is implemented by every payment gateway the API can charge through.
+type Provider interface {
+	// Name is the identifier used in routes and stored on payments.
+	Name() string
+	// CreateCharge opens a new pending charge.
+	CreateCharge(ctx context.Context, req ChargeRequest) (*Charge, error)
+	// Capture confirms a pending charge.
+	Capture(ctx context.Context, ref string) (*Charge, error)
+	// Refund returns the captured amount to the guest.
+	Refund(ctx context.Context, ref string) (*Charge, error)
+	// ParseWebhook verifies the signature of a webhook payload and decodes it.
+	ParseWebhook(payload []byte, signature string) (*Event, error)
+}
+
+var (
+	mu        sync.RWMutex
+	providers = map[string]Provider{}
+)
+
+// Register makes a provider available by its name.
+func Register(p Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+	providers[p.Name()] = p
+}
+
+// Get returns the provider registered under name.
+func Get(name string) (Provider, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	p, ok := providers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+	}
+	return p, nil
+}
+
+// Default returns the provider selected by PAYMENT_PROVIDER (mock if unset).
+func Default() (Provider, error) {
+	name := os.Getenv("PAYMENT_PROVIDER")
+	if name == "" {
+		name = "mock"
+	}
+	return Get(name)
+}
@@ -0,0 +1,185 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/JongSinister/WTFiber/config"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// Font is a TrueType font embedded in full into the documents that use it.
+// Text is written as glyph IDs, so every script the font covers renders.
+type Font struct {
+	name       string
+	data       []byte
+	sfnt       *sfnt.Font
+	mu         sync.Mutex // guards buf, which sfnt reuses between calls
+	buf        sfnt.Buffer
+	unitsPerEm int32
+	bbox       [4]int
+	ascent     int
+	descent    int
+	capHeight  int
+}
+
+// glyphID identifies a glyph in a font; with Identity-H it is also the
+// character code written in the content stream
+type glyphID = sfnt.GlyphIndex
+
+// Fonts new documents embed; nil means the standard Helvetica fonts
+var regularFont, boldFont *Font
+
+// Setup loads the fonts named by PDF_FONT and PDF_FONT_BOLD (paths to
+// TrueType files). Without them, documents fall back to Helvetica and text
+// outside Latin-1 is transliterated.
+func Setup() {
+	path := config.GetEnv("PDF_FONT", "")
+	if path == "" {
+		log.Println("PDF_FONT not set, PDFs will only render Latin text")
+		return
+	}
+
+	regular, err := LoadFont(path)
+	if err != nil {
+		log.Printf("Error loading PDF font %s: %v", path, err)
+		return
+	}
+	regularFont, boldFont = regular, regular
+
+	if path := config.GetEnv("PDF_FONT_BOLD", ""); path != "" {
+		bold, err := LoadFont(path)
+		if err != nil {
+			log.Printf("Error loading PDF bold font %s: %v", path, err)
+			return
+		}
+		boldFont = bold
+	}
+}
+
+// LoadFont reads a TrueType font file
+func LoadFont(path string) (*Font, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseFont(data)
+}
+
+// ParseFont parses a TrueType font. Fonts with CFF outlines are not
+// supported.
+func ParseFont(data []byte) (*Font, error) {
+	if len(data) < 4 || !(bytes.Equal(data[:4], []byte{0, 1, 0, 0}) || string(data[:4]) == "true") {
+		return nil, errors.New("not a TrueType font")
+	}
+
+	parsed, err := sfnt.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Font{data: data, sfnt: parsed, unitsPerEm: int32(parsed.UnitsPerEm())}
+	if f.unitsPerEm <= 0 {
+		return nil, errors.New("invalid units per em")
+	}
+
+	// Measuring at one pixel per font unit gives the metrics in font units
+	ppem := fixed.I(int(f.unitsPerEm))
+	bounds, err := parsed.Bounds(&f.buf, ppem, font.HintingNone)
+	if err != nil {
+		return nil, err
+	}
+	metrics, err := parsed.Metrics(&f.buf, ppem, font.HintingNone)
+	if err != nil {
+		return nil, err
+	}
+	f.bbox = [4]int{
+		f.scale(bounds.Min.X), f.scale(-bounds.Max.Y),
+		f.scale(bounds.Max.X), f.scale(-bounds.Min.Y),
+	}
+	f.ascent = f.scale(metrics.Ascent)
+	f.descent = -f.scale(metrics.Descent)
+	f.capHeight = f.scale(metrics.CapHeight)
+	if f.capHeight == 0 {
+		f.capHeight = f.ascent
+	}
+
+	name, _ := parsed.Name(&f.buf, sfnt.NameIDPostScript)
+	f.name = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' {
+			return r
+		}
+		return -1
+	}, name)
+	if f.name == "" {
+		f.name = "EmbeddedFont"
+	}
+	return f, nil
+}
+
+// glyph returns the glyph for r, or .notdef when the font lacks it
+func (f *Font) glyph(r rune) glyphID {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	gid, err := f.sfnt.GlyphIndex(&f.buf, r)
+	if err != nil {
+		return 0
+	}
+	return gid
+}
+
+// width returns the advance of a glyph in thousandths of an em
+func (f *Font) width(gid glyphID) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	advance, err := f.sfnt.GlyphAdvance(&f.buf, gid, fixed.I(int(f.unitsPerEm)), font.HintingNone)
+	if err != nil {
+		return 0
+	}
+	return f.scale(advance)
+}
+
+// scale converts a length measured at one pixel per font unit to
+// thousandths of an em
+func (f *Font) scale(v fixed.Int26_6) int {
+	return int(int64(v.Round()) * 1000 / int64(f.unitsPerEm))
+}
+
+// toUnicode builds the CMap that maps the used glyphs back to text, so the
+// PDF can be searched and copied from
+func toUnicode(used map[glyphID]rune, gids []glyphID) string {
+	var b strings.Builder
+	b.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+
+	// bfchar blocks hold at most 100 entries
+	for start := 0; start < len(gids); start += 100 {
+		end := min(start+100, len(gids))
+		fmt.Fprintf(&b, "%d beginbfchar\n", end-start)
+		for _, gid := range gids[start:end] {
+			fmt.Fprintf(&b, "<%04X> <%s>\n", gid, utf16Hex(used[gid]))
+		}
+		b.WriteString("endbfchar\n")
+	}
+
+	b.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend")
+	return b.String()
+}
+
+// utf16Hex encodes r as big-endian UTF-16 in hex
+func utf16Hex(r rune) string {
+	if r < 0x10000 {
+		return fmt.Sprintf("%04X", r)
+	}
+	r -= 0x10000
+	return fmt.Sprintf("%04X%04X", 0xD800+(r>>10), 0xDC00+(r&0x3FF))
+}
@@ -0,0 +1,238 @@
+// Package pdf is a small PDF 1.4 writer used for invoices and confirmations.
+// Text is set in a TrueType font embedded from PDF_FONT when one is
+// configured; otherwise the standard Helvetica fonts are used and text
+// outside Latin-1 is transliterated or replaced with '?'.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// A4 page size in points
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+type Document struct {
+	pages []*Page
+	// Regular and bold fonts; nil selects Helvetica
+	fonts [2]*Font
+	// Glyphs drawn with each embedded font and the text they stand for
+	used map[*Font]map[glyphID]rune
+}
+
+type Page struct {
+	doc     *Document
+	content bytes.Buffer
+}
+
+func New() *Document {
+	return &Document{
+		fonts: [2]*Font{regularFont, boldFont},
+		used:  map[*Font]map[glyphID]rune{},
+	}
+}
+
+// AddPage appends a blank A4 page and returns it for drawing.
+func (d *Document) AddPage() *Page {
+	page := &Page{doc: d}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// Text draws s with its baseline at (x, y), measured from the bottom-left corner.
+func (p *Page) Text(x, y, size float64, bold bool, s string) {
+	slot := 0
+	if bold {
+		slot = 1
+	}
+	fmt.Fprintf(&p.content, "BT /F%d %.2f Tf %.2f %.2f Td %s Tj ET\n", slot+1, size, x, y, p.doc.encode(slot, s))
+}
+
+// Line draws a straight line between two points.
+func (p *Page) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%.2f w %.2f %.2f m %.2f %.2f l S\n", width, x1, y1, x2, y2)
+}
+
+// encode turns s into a string operand for the font in the slot
+func (d *Document) encode(slot int, s string) string {
+	f := d.fonts[slot]
+	if f == nil {
+		return "(" + escape(s) + ")"
+	}
+
+	used := d.used[f]
+	if used == nil {
+		used = map[glyphID]rune{}
+		d.used[f] = used
+	}
+
+	var b strings.Builder
+	b.WriteByte('<')
+	for _, r := range s {
+		if unicode.IsControl(r) {
+			r = ' '
+		}
+		gid := f.glyph(r)
+		if _, ok := used[gid]; !ok {
+			used[gid] = r
+		}
+		fmt.Fprintf(&b, "%04X", gid)
+	}
+	b.WriteByte('>')
+	return b.String()
+}
+
+// Bytes serialises the document.
+func (d *Document) Bytes() []byte {
+	// Objects are numbered from 1: catalog, page tree, the objects of each
+	// distinct font, then a page object and a content stream for every page.
+	var bodies []string
+	reserve := func() int {
+		bodies = append(bodies, "")
+		return len(bodies)
+	}
+
+	catalog, tree := reserve(), reserve()
+
+	var slots [2]int
+	fontObjects := map[*Font]int{}
+	for i, f := range d.fonts {
+		switch {
+		case f == nil:
+			slots[i] = reserve()
+			base := "Helvetica"
+			if i == 1 {
+				base = "Helvetica-Bold"
+			}
+			bodies[slots[i]-1] = fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", base)
+		case fontObjects[f] != 0:
+			slots[i] = fontObjects[f]
+		default:
+			slots[i] = reserve()
+			for j := 0; j < 4; j++ {
+				reserve()
+			}
+			fontObjects[f] = slots[i]
+		}
+	}
+	for f, first := range fontObjects {
+		d.embedFont(f, first, bodies)
+	}
+
+	kids := make([]string, len(d.pages))
+	for i, page := range d.pages {
+		pageObject, contents := reserve(), reserve()
+		kids[i] = fmt.Sprintf("%d 0 R", pageObject)
+		bodies[pageObject-1] = fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.2f %.2f] "+
+			"/Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> /Contents %d 0 R >>",
+			tree, PageWidth, PageHeight, slots[0], slots[1], contents)
+		bodies[contents-1] = fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.content.Len(), page.content.String())
+	}
+
+	bodies[catalog-1] = fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", tree)
+	bodies[tree-1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages))
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	offsets := make([]int, len(bodies))
+	for i, body := range bodies {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, body)
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, catalog, xref)
+
+	return out.Bytes()
+}
+
+// embedFont writes the Type0 font, its CIDFont, descriptor, font file and
+// ToUnicode map into the five objects starting at first
+func (d *Document) embedFont(f *Font, first int, bodies []string) {
+	cidFont, descriptor, file, cmap := first+1, first+2, first+3, first+4
+
+	used := d.used[f]
+	gids := make([]glyphID, 0, len(used))
+	for gid := range used {
+		gids = append(gids, gid)
+	}
+	sort.Slice(gids, func(i, j int) bool { return gids[i] < gids[j] })
+
+	var widths strings.Builder
+	for _, gid := range gids {
+		fmt.Fprintf(&widths, "%d [%d] ", gid, f.width(gid))
+	}
+
+	bodies[first-1] = fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H "+
+		"/DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>", f.name, cidFont, cmap)
+	bodies[cidFont-1] = fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s "+
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> "+
+		"/FontDescriptor %d 0 R /DW 1000 /W [%s] /CIDToGIDMap /Identity >>",
+		f.name, descriptor, strings.TrimSpace(widths.String()))
+	bodies[descriptor-1] = fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 32 "+
+		"/FontBBox [%d %d %d %d] /ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+		f.name, f.bbox[0], f.bbox[1], f.bbox[2], f.bbox[3], f.ascent, f.descent, f.capHeight, file)
+	bodies[file-1] = fmt.Sprintf("<< /Length %d /Length1 %d >>\nstream\n%s\nendstream", len(f.data), len(f.data), f.data)
+
+	unicodeMap := toUnicode(used, gids)
+	bodies[cmap-1] = fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(unicodeMap), unicodeMap)
+}
+
+// escape converts s to a Latin-1 PDF string literal body. Accented letters
+// outside Latin-1 lose their accents; other characters become '?'.
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r > 0xff {
+			r = transliterate(r)
+		}
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n' || r == '\r' || r == '\t':
+			b.WriteByte(' ')
+		case r < 0x20 || r > 0xff || (r >= 0x7f && r < 0xa0):
+			b.WriteByte('?')
+		case r < 0x80:
+			b.WriteRune(r)
+		default:
+			fmt.Fprintf(&b, "\\%03o", r)
+		}
+	}
+	return b.String()
+}
+
+// transliterate maps a character outside Latin-1 to its closest Latin-1
+// character, or returns it unchanged when there is none
+func transliterate(r rune) rune {
+	switch r {
+	case '‘', '’', '‚', '′':
+		return '\''
+	case '“', '”', '„', '″':
+		return '"'
+	case '‐', '‑', '‒', '–', '—', '−':
+		return '-'
+	}
+
+	// Drop the combining marks of the decomposed character, so "ő" becomes "o"
+	for _, base := range norm.NFD.String(string(r)) {
+		if !unicode.Is(unicode.Mn, base) {
+			return base
+		}
+	}
+	return r
+}
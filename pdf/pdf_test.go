@@ -0,0 +1,122 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Hotel (Bangkok)", `Hotel \(Bangkok\)`},
+		{`back\slash`, `back\\slash`},
+		{"line\nbreak\ttab", "line break tab"},
+		{"Café", `Caf\351`},
+		{"Dvořák", `Dvor\341k`},
+		{"Łódź", `?\363dz`},
+		{"“quoted” – ‘single’", `"quoted" - 'single'`},
+		{"โรงแรม", "??????"},
+		{"bell\a", "bell?"},
+	}
+
+	for _, tt := range tests {
+		if got := escape(tt.in); got != tt.want {
+			t.Errorf("escape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestStandardFontDocument(t *testing.T) {
+	doc := &Document{used: map[*Font]map[glyphID]rune{}}
+	page := doc.AddPage()
+	page.Text(50, 800, 12, true, "Invoice")
+	page.Line(50, 790, 545, 790, 1)
+	doc.AddPage().Text(50, 800, 10, false, "Page two")
+
+	out := doc.Bytes()
+	checkXref(t, out)
+	for _, want := range []string{"/BaseFont /Helvetica ", "/BaseFont /Helvetica-Bold ", "/Count 2", "(Invoice) Tj"} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("document is missing %q", want)
+		}
+	}
+}
+
+func TestEmbeddedFontDocument(t *testing.T) {
+	const path = "/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf"
+	if _, err := os.Stat(path); err != nil {
+		t.Skip("DejaVu Sans is not installed")
+	}
+	f, err := LoadFont(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range "AПλ" {
+		if f.glyph(r) == 0 {
+			t.Errorf("no glyph for %q", r)
+		}
+	}
+
+	doc := &Document{fonts: [2]*Font{f, f}, used: map[*Font]map[glyphID]rune{}}
+	page := doc.AddPage()
+	page.Text(50, 800, 12, false, "Отель Αθήνα")
+	page.Text(50, 780, 12, true, "Total")
+
+	out := doc.Bytes()
+	checkXref(t, out)
+
+	// Both slots share one embedded copy of the font
+	if n := bytes.Count(out, []byte("/FontFile2")); n != 1 {
+		t.Errorf("font embedded %d times, want 1", n)
+	}
+	for _, want := range []string{"/Subtype /Type0", "/Encoding /Identity-H", "/BaseFont /DejaVuSans", "beginbfchar"} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("document is missing %q", want)
+		}
+	}
+	wantGlyph := fmt.Sprintf("<%04X> <041E>", f.glyph('О'))
+	if !bytes.Contains(out, []byte(wantGlyph)) {
+		t.Errorf("ToUnicode map is missing %q", wantGlyph)
+	}
+}
+
+func TestParseFontRejectsOtherFormats(t *testing.T) {
+	for _, data := range [][]byte{nil, []byte("OTTO...."), []byte("%PDF-1.4")} {
+		if _, err := ParseFont(data); err == nil {
+			t.Errorf("ParseFont(%q) succeeded", data)
+		}
+	}
+}
+
+// checkXref verifies that every offset in the cross-reference table points
+// at the object it lists
+func checkXref(t *testing.T, out []byte) {
+	t.Helper()
+
+	start := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+	if start == nil {
+		t.Fatal("no startxref")
+	}
+	xref, _ := strconv.Atoi(string(start[1]))
+	if !bytes.HasPrefix(out[xref:], []byte("xref\n")) {
+		t.Fatal("startxref does not point at the xref table")
+	}
+
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(out[xref:], -1)
+	if len(entries) == 0 {
+		t.Fatal("empty xref table")
+	}
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		want := fmt.Sprintf("%d 0 obj\n", i+1)
+		if !bytes.HasPrefix(out[offset:], []byte(want)) {
+			t.Errorf("xref entry %d points at %q", i+1, out[offset:min(offset+12, len(out))])
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Package rbac decides what an authenticated principal may do. Roles map to
+// permissions such as "hotels:update" or "appointments:read:own"; the
+// definitions live in Mongo and are cached in memory. Grants add a
+// permission for a single resource to a single user.
+package rbac
+
+import "strings"
+
+// Permissions known to the API. An ":own" permission only covers records
+// belonging to the user and a ":managed" one only covers hotels the user
+// manages (and their appointments); handlers check both.
+var Permissions = map[string]string{
+	"hotels:read":                  "View hotels",
+	"hotels:create":                "Create hotels",
+	"hotels:update":                "Update hotels",
+	"hotels:update:managed":        "Update managed hotels",
+	"hotels:delete":                "Delete hotels",
+	"hotels:managers":              "Assign hotel managers",
+	"hotels:wifi":                  "Manage hotel Wi-Fi",
+	"hotels:wifi:managed":          "Manage Wi-Fi of managed hotels",
+	"hotels:schedule":              "Manage hotel schedules",
+	"hotels:schedule:managed":      "Manage schedules of managed hotels",
+	"chains:manage":                "Create, edit and delete hotel chains",
+	"chains:reports":               "View chain booking reports",
+	"appointments:read":            "View every appointment",
+	"appointments:read:own":        "View own appointments",
+	"appointments:read:managed":    "View appointments at managed hotels",
+	"appointments:confirm":         "Confirm every appointment",
+	"appointments:confirm:managed": "Confirm appointments at managed hotels",
+	"appointments:create:own":      "Book appointments for oneself",
+	"appointments:update":          "Update every appointment",
+	"appointments:update:own":      "Update own appointments",
+	"appointments:delete":          "Delete every appointment",
+	"appointments:delete:own":      "Delete own appointments",
+	"payments:read":                "View every payment",
+	"payments:read:own":            "View own payments",
+	"payments:create":              "Take deposits for any appointment",
+	"payments:create:own":          "Pay deposits for own appointments",
+	"payments:confirm":             "Confirm any payment",
+	"payments:refund":              "Refund payments",
+	"users:read":                   "View user accounts",
+	"users:manage":                 "Change, disable and delete user accounts",
+	"users:invite":                 "Invite users with a role",
+	"users:export":                 "Export a user's personal data",
+	"users:impersonate":            "Act as another user for support",
+	"sessions:manage":              "View and end other users' sessions",
+	"apikeys:manage":               "Issue and revoke API keys",
+	"roles:manage":                 "Edit roles and grants",
+	"organizations:manage":         "Manage organizations and work across them",
+}
+
+// Permissions that were removed from the API. Seeding strips them from the
+// stored roles so they can still be edited; deposits are confirmed by staff
+// or the provider webhook, never by the guest who paid.
+var retiredPermissions = []string{"payments:confirm:own"}
+
+// Roles created on first start; admins can edit them later
+var defaultRoles = map[string][]string{
+	"admin": {"*"},
+	"user": {
+		"hotels:read",
+		"appointments:read:own",
+		"appointments:create:own",
+		"payments:read:own",
+		"payments:create:own",
+	},
+	"manager": {
+		"hotels:read",
+		"hotels:update:managed",
+		"hotels:wifi:managed",
+		"hotels:schedule:managed",
+		"appointments:read:own",
+		"appointments:read:managed",
+		"appointments:confirm:managed",
+		"appointments:create:own",
+		"payments:read:own",
+		"payments:create:own",
+	},
+}
+
+// API key scopes expand to these permissions. A key holds the permissions of
+// its role that also appear here for one of its scopes.
+var scopePermissions = map[string][]string{
+	"hotels:read":        {"hotels:read"},
+	"hotels:write":       {"hotels:create", "hotels:update"},
+	"appointments:read":  {"appointments:read"},
+	"appointments:write": {"appointments:update", "appointments:delete"},
+	"payments:read":      {"payments:read"},
+	"payments:write":     {"payments:create", "payments:confirm", "payments:refund"},
+}
+
+// Match reports whether a held permission covers the wanted one. "*" covers
+// everything, "hotels:*" covers every hotels permission, and a permission
+// covers its ":own" and ":managed" variants.
+func Match(held, wanted string) bool {
+	switch {
+	case held == "*", held == wanted, wanted == held+":own", wanted == held+":managed":
+		return true
+	case strings.HasSuffix(held, ":*"):
+		return strings.HasPrefix(wanted, strings.TrimSuffix(held, "*"))
+	}
+	return false
+}
+
+// ValidPermission reports whether p is a known permission or a wildcard
+// over known permissions
+func ValidPermission(p string) bool {
+	if p == "*" {
+		return true
+	}
+	if _, ok := Permissions[p]; ok {
+		return true
+	}
+	if strings.HasSuffix(p, ":*") {
+		for known := range Permissions {
+			if Match(p, known) {
+				return true
+			}
+		}
+	}
+	return false
+}
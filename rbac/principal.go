@@ -0,0 +1,95 @@
+package rbac
+
+import (
+	"context"
+
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Principal is whoever made a request: a user or an API key
+type Principal struct {
+	ID     string
+	Role   string
+	APIKey bool
+	Scopes []string
+}
+
+// FromClaims builds the principal from the claims Protect stored
+func FromClaims(claims jwt.MapClaims) Principal {
+	p := Principal{}
+	p.ID, _ = claims["sub"].(string)
+	p.Role, _ = claims["role"].(string)
+	_, p.APIKey = claims["apiKey"]
+
+	scopes, _ := claims["scopes"].([]interface{})
+	for _, s := range scopes {
+		if scope, ok := s.(string); ok {
+			p.Scopes = append(p.Scopes, scope)
+		}
+	}
+	return p
+}
+
+// Can reports whether the principal's role holds the permission. API keys
+// are further limited to the permissions their scopes expand to.
+func (p Principal) Can(perm string) bool {
+	role, ok := GetRole(p.Role)
+	if !ok || !holds(role.Permissions, perm) {
+		return false
+	}
+
+	if !p.APIKey {
+		return true
+	}
+	for _, scope := range p.Scopes {
+		if holds(scopePermissions[scope], perm) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanOn reports whether the principal holds the permission through its role
+// or through a grant on the given resource. Grants are never extended to API
+// keys.
+func (p Principal) CanOn(ctx context.Context, perm string, resource primitive.ObjectID) (bool, error) {
+	if p.Can(perm) {
+		return true, nil
+	}
+	if p.APIKey {
+		return false, nil
+	}
+
+	userID, err := primitive.ObjectIDFromHex(p.ID)
+	if err != nil {
+		return false, nil
+	}
+	return HasGrant(ctx, userID, perm, resource)
+}
+
+// CanAssign reports whether the principal may give the role to a user or an
+// API key: it must hold every permission of the role itself, so nobody can
+// hand out more than they have
+func (p Principal) CanAssign(name string) bool {
+	role, ok := GetRole(name)
+	if !ok {
+		return false
+	}
+	for _, perm := range role.Permissions {
+		if !p.Can(perm) {
+			return false
+		}
+	}
+	return true
+}
+
+// holds reports whether any of the held permissions covers perm
+func holds(held []string, perm string) bool {
+	for _, h := range held {
+		if Match(h, perm) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package rbac
+
+import (
+	"testing"
+
+	"github.com/JongSinister/WTFiber/models"
+)
+
+// withRoles replaces the role cache for the duration of a test
+func withRoles(t *testing.T, defs map[string][]string) {
+	t.Helper()
+	mu.Lock()
+	saved := roles
+	roles = map[string]models.Role{}
+	for name, perms := range defs {
+		roles[name] = models.Role{Name: name, Permissions: perms}
+	}
+	mu.Unlock()
+
+	t.Cleanup(func() {
+		mu.Lock()
+		roles = saved
+		mu.Unlock()
+	})
+}
+
+func TestCan(t *testing.T) {
+	withRoles(t, map[string][]string{
+		"admin":   {"*"},
+		"user":    {"hotels:read", "appointments:read:own"},
+		"support": {"hotels:*", "users:read"},
+	})
+
+	tests := []struct {
+		name      string
+		principal Principal
+		perm      string
+		want      bool
+	}{
+		{"admin holds everything", Principal{Role: "admin"}, "roles:manage", true},
+		{"exact permission", Principal{Role: "user"}, "hotels:read", true},
+		{"permission covers its own variant", Principal{Role: "support"}, "users:read:own", true},
+		{"own variant does not cover the whole", Principal{Role: "user"}, "appointments:read", false},
+		{"resource wildcard", Principal{Role: "support"}, "hotels:delete", true},
+		{"resource wildcard stays in its resource", Principal{Role: "support"}, "users:manage", false},
+		{"unknown role", Principal{Role: "ghost"}, "hotels:read", false},
+		{"API key limited to its scopes", Principal{Role: "admin", APIKey: true, Scopes: []string{"hotels:read"}}, "users:manage", false},
+		{"API key within its scopes", Principal{Role: "admin", APIKey: true, Scopes: []string{"payments:write"}}, "payments:refund", true},
+		{"API key scope beyond its role", Principal{Role: "user", APIKey: true, Scopes: []string{"hotels:write"}}, "hotels:create", false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.principal.Can(tt.perm); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCanAssign(t *testing.T) {
+	withRoles(t, map[string][]string{
+		"admin":   {"*"},
+		"user":    {"hotels:read", "appointments:read:own"},
+		"manager": {"hotels:read", "hotels:update:managed", "appointments:read:own"},
+		"support": {"users:read", "users:manage", "users:invite"},
+	})
+
+	tests := []struct {
+		name      string
+		principal Principal
+		role      string
+		want      bool
+	}{
+		{"admin assigns admin", Principal{Role: "admin"}, "admin", true},
+		{"admin assigns user", Principal{Role: "admin"}, "user", true},
+		{"support cannot assign admin", Principal{Role: "support"}, "admin", false},
+		{"support cannot assign user", Principal{Role: "support"}, "user", false},
+		{"manager assigns the subset role", Principal{Role: "manager"}, "user", true},
+		{"user cannot assign manager", Principal{Role: "user"}, "manager", false},
+		{"unknown role", Principal{Role: "admin"}, "ghost", false},
+		{"API key limited by its scopes", Principal{Role: "admin", APIKey: true, Scopes: []string{"hotels:read"}}, "manager", false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.principal.CanAssign(tt.role); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultRolesCannotConfirmPayments(t *testing.T) {
+	for name, perms := range defaultRoles {
+		if name == "admin" {
+			continue
+		}
+		withRoles(t, map[string][]string{name: perms})
+		if (Principal{Role: name}).Can("payments:confirm") {
+			t.Errorf("%s can confirm payments", name)
+		}
+		for _, p := range perms {
+			if !ValidPermission(p) {
+				t.Errorf("%s holds unknown permission %q", name, p)
+			}
+		}
+	}
+}
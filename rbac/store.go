@@ -0,0 +1,202 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/JongSinister/WTFiber/config"
+	"github.com/JongSinister/WTFiber/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	roleCollection  = "roles"
+	grantCollection = "grants"
+)
+
+var (
+	ErrRoleNotFound = errors.New("role not found")
+	ErrBuiltinRole  = errors.New("built-in roles cannot be deleted")
+	ErrRoleInUse    = errors.New("role is assigned to users")
+)
+
+var (
+	mu    sync.RWMutex
+	roles = map[string]models.Role{}
+)
+
+// StartSync creates the default roles if they are missing, loads the role
+// definitions and keeps reloading them so edits made on other instances are
+// picked up
+func StartSync(interval time.Duration) {
+	if err := seed(); err != nil {
+		log.Printf("Error creating default roles: %v", err)
+	}
+	if err := load(); err != nil {
+		log.Printf("Error loading roles: %v", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := load(); err != nil {
+				log.Printf("Error loading roles: %v", err)
+			}
+		}
+	}()
+}
+
+// Roles returns every role definition
+func Roles() []models.Role {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	result := make([]models.Role, 0, len(roles))
+	for _, role := range roles {
+		result = append(result, role)
+	}
+	return result
+}
+
+// GetRole returns the definition of a role
+func GetRole(name string) (models.Role, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	role, ok := roles[name]
+	return role, ok
+}
+
+// SaveRole creates or replaces a role definition
+func SaveRole(ctx context.Context, name, description string, permissions []string) (models.Role, error) {
+	now := primitive.NewDateTimeFromTime(time.Now())
+	role := models.Role{}
+	err := config.DB.Collection(roleCollection).FindOneAndUpdate(ctx,
+		bson.M{"name": name},
+		bson.M{
+			"$set":         bson.M{"description": description, "permissions": permissions, "updatedAt": now},
+			"$setOnInsert": bson.M{"builtin": false},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&role)
+	if err != nil {
+		return role, err
+	}
+
+	mu.Lock()
+	roles[name] = role
+	mu.Unlock()
+	return role, nil
+}
+
+// DeleteRole removes a custom role nobody holds
+func DeleteRole(ctx context.Context, name string) error {
+	role, ok := GetRole(name)
+	if !ok {
+		return ErrRoleNotFound
+	}
+	if role.Builtin {
+		return ErrBuiltinRole
+	}
+
+	count, err := config.DB.Collection("users").CountDocuments(ctx, bson.M{"role": name})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrRoleInUse
+	}
+
+	if _, err := config.DB.Collection(roleCollection).DeleteOne(ctx, bson.M{"name": name}); err != nil {
+		return err
+	}
+
+	mu.Lock()
+	delete(roles, name)
+	mu.Unlock()
+	return nil
+}
+
+// HasGrant reports whether the user was granted perm on the resource
+func HasGrant(ctx context.Context, userID primitive.ObjectID, perm string, resource primitive.ObjectID) (bool, error) {
+	cursor, err := config.DB.Collection(grantCollection).Find(ctx, bson.M{"user": userID, "resource": resource})
+	if err != nil {
+		return false, err
+	}
+	defer cursor.Close(ctx)
+
+	var grants []models.Grant
+	if err := cursor.All(ctx, &grants); err != nil {
+		return false, err
+	}
+
+	for _, grant := range grants {
+		if Match(grant.Permission, perm) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// seed inserts the default roles that do not exist yet and removes retired
+// permissions from every role
+func seed() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	for name, permissions := range defaultRoles {
+		_, err := config.DB.Collection(roleCollection).UpdateOne(ctx,
+			bson.M{"name": name},
+			bson.M{"$setOnInsert": bson.M{"permissions": permissions, "builtin": true, "updatedAt": now}},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := config.DB.Collection(roleCollection).UpdateMany(ctx,
+		bson.M{"permissions": bson.M{"$in": retiredPermissions}},
+		bson.M{"$pull": bson.M{"permissions": bson.M{"$in": retiredPermissions}}},
+	)
+	return err
+}
+
+// load replaces the cache with the roles in the database
+func load() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.DB.Collection(roleCollection).Find(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []models.Role
+	if err := cursor.All(ctx, &entries); err != nil {
+		return err
+	}
+
+	SetRoles(entries)
+	return nil
+}
+
+// SetRoles replaces the cached role definitions. The sync loop uses it after
+// each load; tests use it to run without a database.
+func SetRoles(entries []models.Role) {
+	fresh := make(map[string]models.Role, len(entries))
+	for _, role := range entries {
+		fresh[role.Name] = role
+	}
+
+	mu.Lock()
+	roles = fresh
+	mu.Unlock()
+}
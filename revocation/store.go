@@ -0,0 +1,133 @@
+// Package revocation keeps track of access tokens revoked before their expiry.
+// Revocations are stored in Mongo and mirrored in memory so that checking a
+// token on every request does not cost a database round trip.
+package revocation
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/JongSinister/WTFiber/config"
+	"github.com/JongSinister/WTFiber/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const collection = "revoked_tokens"
+
+var (
+	mu    sync.RWMutex
+	cache = map[string]models.Revocation{}
+)
+
+// Revoke records a revocation of the given kind and key, valid until expiresAt
+func Revoke(ctx context.Context, kind, key string, expiresAt time.Time) error {
+	now := time.Now()
+	entry := models.Revocation{
+		Kind:      kind,
+		Key:       key,
+		RevokedAt: primitive.NewDateTimeFromTime(now),
+		ExpiresAt: primitive.NewDateTimeFromTime(expiresAt),
+	}
+
+	_, err := config.DB.Collection(collection).UpdateOne(ctx,
+		bson.M{"kind": kind, "key": key},
+		bson.M{"$set": bson.M{"revokedAt": entry.RevokedAt, "expiresAt": entry.ExpiresAt}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	cache[cacheKey(kind, key)] = entry
+	mu.Unlock()
+	return nil
+}
+
+// RevokeUser revokes every access token issued to the user up to now
+func RevokeUser(ctx context.Context, userID string) error {
+	return Revoke(ctx, models.RevokeUser, userID, time.Now().Add(config.AccessTokenTTL()))
+}
+
+// RevokeSession revokes every access token issued for the session
+func RevokeSession(ctx context.Context, sessionID string) error {
+	return Revoke(ctx, models.RevokeSession, sessionID, time.Now().Add(config.AccessTokenTTL()))
+}
+
+// IsRevoked reports whether a token with the given jti, session, subject and
+// issue time has been revoked
+func IsRevoked(jti, sessionID, userID string, issuedAt time.Time) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if jti != "" {
+		if _, ok := cache[cacheKey(models.RevokeToken, jti)]; ok {
+			return true
+		}
+	}
+
+	if sessionID != "" {
+		if _, ok := cache[cacheKey(models.RevokeSession, sessionID)]; ok {
+			return true
+		}
+	}
+
+	if entry, ok := cache[cacheKey(models.RevokeUser, userID)]; ok {
+		return !issuedAt.After(entry.RevokedAt.Time())
+	}
+	return false
+}
+
+// StartSync loads the active revocations and keeps reloading them so that
+// revocations made by other instances are picked up
+func StartSync(interval time.Duration) {
+	if err := load(); err != nil {
+		log.Printf("Error loading revoked tokens: %v", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := load(); err != nil {
+				log.Printf("Error loading revoked tokens: %v", err)
+			}
+		}
+	}()
+}
+
+// load replaces the cache with the unexpired revocations from the database
+func load() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	cursor, err := config.DB.Collection(collection).Find(ctx, bson.M{"expiresAt": bson.M{"$gt": now}})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []models.Revocation
+	if err := cursor.All(ctx, &entries); err != nil {
+		return err
+	}
+
+	fresh := make(map[string]models.Revocation, len(entries))
+	for _, entry := range entries {
+		fresh[cacheKey(entry.Kind, entry.Key)] = entry
+	}
+
+	mu.Lock()
+	cache = fresh
+	mu.Unlock()
+	return nil
+}
+
+func cacheKey(kind, key string) string {
+	return kind + ":" + key
+}
@@ -0,0 +1,59 @@
+package revocation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/JongSinister/WTFiber/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestIsRevoked(t *testing.T) {
+	cutoff := time.Now().Truncate(time.Second)
+	entry := func(kind, key string) (string, models.Revocation) {
+		return cacheKey(kind, key), models.Revocation{
+			Kind:      kind,
+			Key:       key,
+			RevokedAt: primitive.NewDateTimeFromTime(cutoff),
+			ExpiresAt: primitive.NewDateTimeFromTime(cutoff.Add(time.Hour)),
+		}
+	}
+
+	mu.Lock()
+	cache = map[string]models.Revocation{}
+	for _, e := range [][2]string{
+		{models.RevokeToken, "jti-revoked"},
+		{models.RevokeSession, "sid-revoked"},
+		{models.RevokeUser, "user-revoked"},
+	} {
+		key, value := entry(e[0], e[1])
+		cache[key] = value
+	}
+	mu.Unlock()
+	t.Cleanup(func() {
+		mu.Lock()
+		cache = map[string]models.Revocation{}
+		mu.Unlock()
+	})
+
+	tests := []struct {
+		name               string
+		jti, session, user string
+		issuedAt           time.Time
+		want               bool
+	}{
+		{"nothing revoked", "jti", "sid", "user", cutoff, false},
+		{"token revoked", "jti-revoked", "sid", "user", cutoff, true},
+		{"session revoked", "jti", "sid-revoked", "user", cutoff, true},
+		{"token without session", "jti", "", "user", cutoff, false},
+		{"issued before the user cutoff", "jti", "sid", "user-revoked", cutoff.Add(-time.Minute), true},
+		{"issued at the user cutoff", "jti", "", "user-revoked", cutoff, true},
+		{"issued after the user cutoff", "jti", "sid", "user-revoked", cutoff.Add(time.Second), false},
+	}
+
+	for _, tt := range tests {
+		if got := IsRevoked(tt.jti, tt.session, tt.user, tt.issuedAt); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package routes
+
+import (
+	"github.com/JongSinister/WTFiber/controllers"
+	"github.com/JongSinister/WTFiber/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+func AdminRoutes(router fiber.Router) {
+	// Session management
+	router.Get("/users/:id/sessions", middleware.Protect, middleware.RequirePermission("sessions:manage"), controllers.GetUserSessions)
+	router.Delete("/users/:id/sessions", middleware.Protect, middleware.RequirePermission("sessions:manage"), controllers.DeleteUserSessions)
+	router.Delete("/sessions/:id", middleware.Protect, middleware.RequirePermission("sessions:manage"), controllers.DeleteSession)
+
+	// Login lockouts
+	router.Get("/lockouts", middleware.Protect, middleware.RequirePermission("users:manage"), controllers.GetLockouts)
+	router.Delete("/lockouts/ip/:ip", middleware.Protect, middleware.RequireSuperAdmin, middleware.RequirePermission("users:manage"), controllers.UnlockIP)
+	router.Delete("/users/:id/lockout", middleware.Protect, middleware.RequirePermission("users:manage"), controllers.UnlockUser)
+
+	// Impersonation for support
+	router.Post("/impersonate/:userId", middleware.Protect, middleware.RequirePermission("users:impersonate"), controllers.Impersonate)
+
+	// Personal data exports
+	router.Post("/users/:id/export", middleware.Protect, middleware.RequirePermission("users:export"), controllers.RequestUserDataExport)
+
+	// Invitations
+	router.Get("/invites", middleware.Protect, middleware.RequirePermission("users:invite"), controllers.GetInvites)
+	router.Post("/invites", middleware.Protect, middleware.RequirePermission("users:invite"), controllers.CreateInvite)
+	router.Delete("/invites/:id", middleware.Protect, middleware.RequirePermission("users:invite"), controllers.DeleteInvite)
+
+	// API keys
+	router.Get("/api-keys", middleware.Protect, middleware.RequirePermission("apikeys:manage"), controllers.GetAPIKeys)
+	router.Post("/api-keys", middleware.Protect, middleware.RequirePermission("apikeys:manage"), controllers.CreateAPIKey)
+	router.Delete("/api-keys/:id", middleware.Protect, middleware.RequirePermission("apikeys:manage"), controllers.RevokeAPIKey)
+
+	// Roles, permissions and resource grants
+	router.Get("/permissions", middleware.Protect, middleware.RequirePermission("roles:manage"), controllers.GetPermissions)
+	router.Get("/roles", middleware.Protect, middleware.RequirePermission("roles:manage"), controllers.GetRoles)
+	router.Get("/roles/:name", middleware.Protect, middleware.RequirePermission("roles:manage"), controllers.GetRole)
+	router.Put("/roles/:name", middleware.Protect, middleware.RequireSuperAdmin, middleware.RequirePermission("roles:manage"), controllers.PutRole)
+	router.Delete("/roles/:name", middleware.Protect, middleware.RequireSuperAdmin, middleware.RequirePermission("roles:manage"), controllers.DeleteRole)
+	router.Get("/users/:id/grants", middleware.Protect, middleware.RequirePermission("roles:manage"), controllers.GetUserGrants)
+	router.Post("/grants", middleware.Protect, middleware.RequirePermission("roles:manage"), controllers.CreateGrant)
+	router.Delete("/grants/:id", middleware.Protect, middleware.RequirePermission("roles:manage"), controllers.DeleteGrant)
+}
@@ -7,8 +7,16 @@ import (
 )
 
 func AppointmentRoutes(router fiber.Router) {
-	router.Get("/", middleware.Protect, middleware.Authorize("admin"), controllers.GetAppointments)
-	router.Get("/:id", middleware.Protect, middleware.Authorize("admin"), controllers.GetAppointment)
-	router.Put("/:id", middleware.Protect, middleware.Authorize("admin"), controllers.UpdateAppointment)
-	router.Delete("/:id", middleware.Protect, middleware.Authorize("admin"), controllers.DeleteAppointment)
+	router.Get("/", middleware.Protect, middleware.RequirePermission("appointments:read:own", "appointments:read:managed"), controllers.GetAppointments)
+	router.Get("/:id", middleware.Protect, middleware.RequirePermission("appointments:read:own", "appointments:read:managed"), controllers.GetAppointment)
+	router.Put("/:id/confirm", middleware.Protect, middleware.RequireMFA, controllers.ConfirmAppointment)
+	router.Put("/:id", middleware.Protect, middleware.RequirePermission("appointments:update:own"), controllers.UpdateAppointment)
+	router.Delete("/:id", middleware.Protect, middleware.RequirePermission("appointments:delete:own"), controllers.DeleteAppointment)
+
+	// Deposits for an appointment
+	router.Get("/:id/payments", middleware.Protect, middleware.RequirePermission("payments:read:own"), controllers.GetAppointmentPayments)
+	router.Post("/:id/payments", middleware.Protect, middleware.RequirePermission("payments:create:own"), controllers.CreatePayment)
+
+	// Invoice / booking confirmation
+	router.Get("/:id/invoice.pdf", middleware.Protect, middleware.RequirePermission("appointments:read:own"), controllers.GetInvoice)
 }
@@ -7,8 +7,37 @@ import (
 )
 
 func AuthRoutes(router fiber.Router) {
-	router.Post("/register", controllers.Register)
+	router.Post("/register", middleware.Tenant, controllers.Register)
 	router.Post("/login", controllers.Login)
-	router.Get("/me", middleware.Protect, controllers.Me)
-	router.Get("/logout", controllers.Logout)
+	router.Post("/login/mfa", controllers.LoginMFA)
+	router.Post("/refresh", controllers.Refresh)
+	router.Post("/forgot-password", controllers.ForgotPassword)
+	router.Put("/reset-password/:token", controllers.ResetPassword)
+
+	// Email verification
+	router.Get("/verify/:token", controllers.VerifyEmail)
+	router.Post("/verify/resend", middleware.Protect, middleware.RequireUser, controllers.ResendVerification)
+	router.Get("/me", middleware.Protect, middleware.RequireUser, controllers.Me)
+	router.Put("/me", middleware.Protect, middleware.RequireUser, controllers.UpdateMe)
+	router.Delete("/me", middleware.Protect, middleware.RequireUser, controllers.DeleteMe)
+	router.Put("/password", middleware.Protect, middleware.RequireUser, controllers.ChangePassword)
+	router.Post("/me/export", middleware.Protect, middleware.RequireUser, controllers.RequestMyDataExport)
+	router.Get("/me/exports", middleware.Protect, middleware.RequireUser, controllers.GetMyDataExports)
+	router.Post("/logout", middleware.Protect, middleware.RequireUser, controllers.Logout)
+	router.Post("/logout-all", middleware.Protect, middleware.RequireUser, controllers.LogoutAll)
+
+	// Sign in with an identity provider
+	router.Get("/oidc/:provider/login", controllers.OIDCLogin)
+	router.Get("/oidc/:provider/callback", controllers.OIDCCallback)
+
+	// Two-factor authentication
+	router.Post("/mfa/enroll", middleware.Protect, middleware.RequireUser, controllers.EnrollMFA)
+	router.Get("/mfa/qr.png", middleware.Protect, middleware.RequireUser, controllers.MFAQRCode)
+	router.Post("/mfa/verify", middleware.Protect, middleware.RequireUser, controllers.VerifyMFA)
+	router.Post("/mfa/recovery-codes", middleware.Protect, middleware.RequireUser, controllers.RegenerateRecoveryCodes)
+	router.Post("/mfa/disable", middleware.Protect, middleware.RequireUser, controllers.DisableMFA)
+
+	// Active sessions
+	router.Get("/sessions", middleware.Protect, middleware.RequireUser, controllers.GetMySessions)
+	router.Delete("/sessions/:id", middleware.Protect, middleware.RequireUser, controllers.DeleteMySession)
 }
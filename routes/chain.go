@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"github.com/JongSinister/WTFiber/controllers"
+	"github.com/JongSinister/WTFiber/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+func ChainRoutes(router fiber.Router) {
+	router.Get("/", middleware.Tenant, controllers.GetChains)
+	router.Get("/:id", middleware.Tenant, controllers.GetChain)
+	router.Post("/", middleware.Protect, middleware.RequirePermission("chains:manage"), controllers.CreateChain)
+	router.Put("/:id", middleware.Protect, middleware.RequirePermission("chains:manage"), controllers.UpdateChain)
+	router.Delete("/:id", middleware.Protect, middleware.RequirePermission("chains:manage"), controllers.DeleteChain)
+
+	// Reports built on the chain's bookings
+	router.Get("/:id/stats", middleware.Protect, middleware.RequirePermission("chains:reports"), controllers.GetChainStats)
+	router.Get("/:id/bookings", middleware.Protect, middleware.RequirePermission("chains:reports"), controllers.GetChainBookingsPerMonth)
+	router.Get("/:id/top-hotels", middleware.Protect, middleware.RequirePermission("chains:reports"), controllers.GetChainTopHotels)
+}
@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"github.com/JongSinister/WTFiber/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+func ExportRoutes(router fiber.Router) {
+	// The token in the link authenticates the download
+	router.Get("/:id/download", controllers.DownloadDataExport)
+}
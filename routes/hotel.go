@@ -7,13 +7,19 @@ import (
 )
 
 func HotelRoutes(router fiber.Router) {
-	router.Get("/", controllers.GetHotels)
-	router.Get("/:id", controllers.GetHotel)
-	router.Post("/", middleware.Protect, middleware.Authorize("admin"), controllers.CreateHotel)
-	router.Put("/:id", middleware.Protect, middleware.Authorize("admin"), controllers.UpdateHotel)
-	router.Delete("/:id", controllers.DeleteHotel)
+	router.Get("/", middleware.Tenant, controllers.GetHotels)
+	router.Get("/:id", middleware.Tenant, controllers.GetHotel)
+	router.Post("/", middleware.Protect, middleware.RequirePermission("hotels:create"), controllers.CreateHotel)
+	router.Put("/:id", middleware.Protect, middleware.RequireMFA, controllers.UpdateHotel)
+	router.Delete("/:id", middleware.Protect, middleware.RequirePermissionOn("hotels:delete", "id"), controllers.DeleteHotel)
+
+	// Managers and what they run
+	router.Put("/:id/managers", middleware.Protect, middleware.RequirePermission("hotels:managers"), controllers.SetHotelManagers)
+	router.Put("/:id/wifi", middleware.Protect, middleware.RequireMFA, controllers.UpdateHotelWifi)
+	router.Put("/:id/schedule", middleware.Protect, middleware.RequireMFA, controllers.UpdateHotelSchedule)
+	router.Get("/:id/appointments", middleware.Protect, middleware.RequireMFA, controllers.GetHotelAppointments)
 
 	// Create a appointment for a hotel
-	router.Post("/:hotelId/appointments", middleware.Protect, controllers.AddAppointment)
+	router.Post("/:hotelId/appointments", middleware.Protect, middleware.RequireUser, middleware.RequirePermission("appointments:create:own"), controllers.AddAppointment)
 
 }
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/JongSinister/WTFiber/controllers"
+	"github.com/JongSinister/WTFiber/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+func OrganizationRoutes(router fiber.Router) {
+	router.Get("/", middleware.Protect, middleware.RequireSuperAdmin, controllers.GetOrganizations)
+	router.Post("/", middleware.Protect, middleware.RequireSuperAdmin, controllers.CreateOrganization)
+	router.Get("/:id", middleware.Protect, middleware.RequireSuperAdmin, controllers.GetOrganization)
+	router.Put("/:id", middleware.Protect, middleware.RequireSuperAdmin, controllers.UpdateOrganization)
+}
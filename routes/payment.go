@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/JongSinister/WTFiber/controllers"
+	"github.com/JongSinister/WTFiber/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+func PaymentRoutes(router fiber.Router) {
+	router.Post("/webhook/:provider", controllers.PaymentWebhook)
+	router.Get("/:id", middleware.Protect, middleware.RequirePermission("payments:read:own"), controllers.GetPayment)
+	router.Post("/:id/confirm", middleware.Protect, middleware.RequirePermission("payments:confirm"), controllers.ConfirmPayment)
+	router.Post("/:id/refund", middleware.Protect, middleware.RequirePermission("payments:refund"), controllers.RefundPayment)
+}
@@ -1,19 +1,55 @@
 package routes
 
 import (
+	"log"
+
+	"github.com/JongSinister/WTFiber/config"
+	"github.com/JongSinister/WTFiber/controllers"
+	"github.com/JongSinister/WTFiber/oidc"
 	"github.com/gofiber/fiber/v2"
 )
 
 func Setup(app *fiber.App) {
+	// Public keys for verifying our tokens
+	app.Get("/.well-known/jwks.json", controllers.JWKS)
+
+	// Local OpenID provider for development (OIDC_MOCK_ENABLED=true)
+	if config.GetEnv("OIDC_MOCK_ENABLED", "false") == "true" {
+		mock, err := oidc.NewMockServer(config.AppURL() + "/mock-oidc")
+		if err != nil {
+			log.Fatalf("Error starting mock OIDC provider: %v", err)
+		}
+		mock.Register(app.Group("/mock-oidc"))
+		log.Println("Mock OIDC provider enabled at /mock-oidc")
+	}
+
 	api := app.Group("/api/v1")
 
 	// Hotel routes
 	HotelRoutes(api.Group("/hotels"))
 
+	// Hotel chain routes
+	ChainRoutes(api.Group("/chains"))
+
 	// Auth routes
 	AuthRoutes(api.Group("/auth"))
 
 	// Appointment routes
 	AppointmentRoutes(api.Group("/appointments"))
 
+	// Payment routes
+	PaymentRoutes(api.Group("/payments"))
+
+	// Data export downloads
+	ExportRoutes(api.Group("/exports"))
+
+	// Organization (hotel chain) routes
+	OrganizationRoutes(api.Group("/organizations"))
+
+	// User management routes
+	UserRoutes(api.Group("/users"))
+
+	// Admin routes
+	AdminRoutes(api.Group("/admin"))
+
 }
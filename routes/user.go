@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/JongSinister/WTFiber/controllers"
+	"github.com/JongSinister/WTFiber/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+func UserRoutes(router fiber.Router) {
+	router.Get("/", middleware.Protect, middleware.RequirePermission("users:read"), controllers.GetUsers)
+	router.Get("/:id", middleware.Protect, middleware.RequirePermission("users:read"), controllers.GetUser)
+	router.Put("/:id/role", middleware.Protect, middleware.RequirePermission("users:manage"), controllers.UpdateUserRole)
+	router.Put("/:id/disable", middleware.Protect, middleware.RequirePermission("users:manage"), controllers.DisableUser)
+	router.Put("/:id/enable", middleware.Protect, middleware.RequirePermission("users:manage"), controllers.EnableUser)
+	router.Delete("/:id", middleware.Protect, middleware.RequirePermission("users:manage"), controllers.DeleteUser)
+	router.Put("/:id/organization", middleware.Protect, middleware.RequireSuperAdmin, controllers.SetUserOrganization)
+}
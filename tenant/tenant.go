@@ -0,0 +1,51 @@
+// Package tenant keeps hotel chains apart. Every user, hotel and appointment
+// belongs to an organization; records without one form the default tenant.
+// Protect resolves the scope of each request and controllers add it to
+// their queries.
+package tenant
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Header lets super-admins, and public routes, pick the organization
+const Header = "X-Organization"
+
+// Scope is the organization a request acts for
+type Scope struct {
+	// Organization is the tenant; the zero ID is the default tenant
+	Organization primitive.ObjectID
+	// All is set when a super-admin has not picked an organization; queries
+	// then span every tenant
+	All bool
+	// SuperAdmin is set for principals who may cross tenants
+	SuperAdmin bool
+}
+
+// Default is the scope of records that belong to no organization
+var Default = Scope{}
+
+// Of scopes a request to a single organization
+func Of(organization primitive.ObjectID) Scope {
+	return Scope{Organization: organization}
+}
+
+// Filter adds the tenant to a query filter. The filter is modified and
+// returned so it can be used inline.
+func (s Scope) Filter(filter bson.M) bson.M {
+	if s.All {
+		return filter
+	}
+	if s.Organization.IsZero() {
+		filter["organization"] = bson.M{"$exists": false}
+	} else {
+		filter["organization"] = s.Organization
+	}
+	return filter
+}
+
+// Allows reports whether a record of the organization is visible in the scope
+func (s Scope) Allows(organization primitive.ObjectID) bool {
+	return s.All || s.Organization == organization
+}
@@ -0,0 +1,59 @@
+package tenant
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestScopeFilter(t *testing.T) {
+	org := primitive.NewObjectID()
+
+	tests := []struct {
+		name  string
+		scope Scope
+		want  bson.M
+	}{
+		{"organization", Of(org), bson.M{"status": "open", "organization": org}},
+		{"default tenant", Default, bson.M{"status": "open", "organization": bson.M{"$exists": false}}},
+		{"every tenant", Scope{All: true, SuperAdmin: true}, bson.M{"status": "open"}},
+		{"super-admin picking an organization", Scope{Organization: org, SuperAdmin: true}, bson.M{"status": "open", "organization": org}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.scope.Filter(bson.M{"status": "open"}); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScopeAllows(t *testing.T) {
+	org := primitive.NewObjectID()
+	other := primitive.NewObjectID()
+
+	tests := []struct {
+		name         string
+		scope        Scope
+		organization primitive.ObjectID
+		want         bool
+	}{
+		{"same organization", Of(org), org, true},
+		{"other organization", Of(org), other, false},
+		{"default tenant record from an organization", Of(org), primitive.NilObjectID, false},
+		{"organization record from the default tenant", Default, org, false},
+		{"default tenant", Default, primitive.NilObjectID, true},
+		{"every tenant", Scope{All: true}, other, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.scope.Allows(tt.organization); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}